@@ -7,23 +7,65 @@
 package main
 
 import (
+	"cares/internal/cluster"
 	"cares/internal/logging"
+	"cares/internal/tracing"
 	"cares/internal/ui"
+	"flag"
 	"fmt"
 	"os"
 )
 
 func main() {
+	config := logging.DefaultConfig(true)
+	levelName := flag.String("log-level", logging.LevelName(config.Level), "Minimum level logged: debug, info, warn or error")
+	flag.StringVar(&config.Format, "log-format", config.Format, "Log format: text or json")
+	flag.StringVar(&config.File, "log-file", config.File, "Log file, rotated by size and age")
+	maxSizeMB := flag.Int64("log-max-size", config.MaxSize>>20, "Megabytes written to the log file before it is rotated, 0 for no limit")
+	flag.DurationVar(&config.MaxAge, "log-max-age", config.MaxAge, "Time the log file is written to before it is rotated, 0 for no limit")
+	flag.IntVar(&config.MaxBackups, "log-max-backups", config.MaxBackups, "Rotated log files kept, 0 keeps all")
+	traceConfig := tracing.Config{ServiceName: tracing.DefaultServiceName}
+	flag.StringVar(&traceConfig.Endpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		"OTLP/HTTP endpoint spans are exported to, e.g. http://localhost:4318; spans are logged at debug level if empty")
+	flag.StringVar(&traceConfig.ServiceName, "otlp-service-name", traceConfig.ServiceName, "Service name spans are exported under")
+	tlsConfig := cluster.TLSConfigFromEnv()
+	flag.StringVar(&tlsConfig.CertFile, "tls-cert", tlsConfig.CertFile,
+		"PEM certificate of this node's address, securing the orchestrator-worker channel with TLS; plaintext if empty")
+	flag.StringVar(&tlsConfig.KeyFile, "tls-key", tlsConfig.KeyFile, "PEM key of the -tls-cert certificate")
+	flag.StringVar(&tlsConfig.CAFile, "tls-ca", tlsConfig.CAFile,
+		"PEM CAs peers' certificates are verified with, requiring workers to present one too; the system roots if empty")
+	flag.Parse()
+
+	level, err := logging.ParseLevel(*levelName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	config.Level = level
+	config.MaxSize = *maxSizeMB << 20
+
 	// Initialize logging system for TUI mode
-	if err := logging.InitLogger(true); err != nil {
+	if err := logging.Init(config); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logging: %v\n", err)
 		os.Exit(1)
 	}
 	defer logging.Close()
 
+	if err := tracing.Init(traceConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer tracing.Shutdown()
+
+	// Registry credentials are only sent to workers once this is secured
+	if err := cluster.ConfigureTLS(tlsConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	// Start the minimal TUI (blocks until exit)
 	if err := ui.Start(); err != nil {
 		fmt.Fprintln(os.Stderr, "TUI exited with error:", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
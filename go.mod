@@ -8,6 +8,8 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/guptarohit/asciigraph v0.7.3
 	github.com/shirou/gopsutil/v3 v3.24.5
+	github.com/tetratelabs/wazero v1.7.0
+	go.etcd.io/bbolt v1.4.3
 	google.golang.org/grpc v1.67.1
 	google.golang.org/protobuf v1.35.2
 )
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cares/internal/cluster"
+	"cares/internal/credentials"
+	"cares/internal/functions"
+	"cares/internal/logging"
+)
+
+// CredentialRequest represents the JSON payload for storing a registry credential
+type CredentialRequest struct {
+	Name     string `json:"name"`
+	Registry string `json:"registry"` // Registry host, e.g. "ghcr.io"
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CredentialResponse represents the JSON response for credential operations.
+// Passwords are never included.
+type CredentialResponse struct {
+	Status      string                   `json:"status"`
+	Message     string                   `json:"message,omitempty"`
+	Credential  *credentials.Credential  `json:"credential,omitempty"`
+	Credentials []credentials.Credential `json:"credentials,omitempty"`
+}
+
+// SetCredentials sets the store private registry credentials are kept in
+func (s *Server) SetCredentials(store *credentials.Store) {
+	s.credentials = store
+}
+
+// credentialExists reports whether a credential with the given name is stored
+func (s *Server) credentialExists(name string) bool {
+	if s.credentials == nil {
+		return false
+	}
+	_, err := s.credentials.Get(name)
+	return err == nil
+}
+
+// registryAuth returns the registry login a function's image is pulled with,
+// or nil if it pulls anonymously
+func (s *Server) registryAuth(function *functions.Function) (*cluster.RegistryAuth, error) {
+	name := function.Settings.RegistryCredential
+	if name == "" || function.Kind != functions.KindContainer {
+		return nil, nil
+	}
+	if s.credentials == nil {
+		return nil, fmt.Errorf("registry credential '%s' is unavailable: no credential store configured", name)
+	}
+
+	credential, err := s.credentials.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("registry credential '%s': %v", name, err)
+	}
+	return &cluster.RegistryAuth{
+		Server:   credential.Registry,
+		Username: credential.Username,
+		Password: credential.Password,
+	}, nil
+}
+
+// handleCredentials handles /credentials endpoint
+func (s *Server) handleCredentials(w http.ResponseWriter, r *http.Request) {
+	if s.credentials == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Credential store is not enabled")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CredentialResponse{Status: "success", Credentials: s.credentials.List()})
+	case "POST":
+		s.createCredential(w, r)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// createCredential handles POST /credentials
+func (s *Server) createCredential(w http.ResponseWriter, r *http.Request) {
+	var req CredentialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	credential, err := s.credentials.Add(credentials.Credential{
+		Name:     req.Name,
+		Registry: req.Registry,
+		Username: req.Username,
+		Password: req.Password,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, credentials.ErrDuplicateName):
+			s.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, credentials.ErrInvalid):
+			s.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Stored registry credential '%s' for '%s'", credential.Name, credential.Registry)
+
+	credential.Password = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CredentialResponse{
+		Status:     "success",
+		Message:    fmt.Sprintf("Credential '%s' stored", credential.Name),
+		Credential: credential,
+	})
+}
+
+// handleCredentialByName handles DELETE /credentials/{name}
+func (s *Server) handleCredentialByName(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.credentials == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Credential store is not enabled")
+		return
+	}
+
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/credentials/"), "/")
+
+	// Removing a credential in use would break the next pull of those functions
+	for _, function := range s.registry.GetAllFunctions() {
+		if function.Settings.RegistryCredential == name {
+			s.writeError(w, http.StatusConflict, fmt.Sprintf("Credential '%s' is used by function '%s'", name, function.Name))
+			return
+		}
+	}
+
+	if err := s.credentials.Remove(name); err != nil {
+		if errors.Is(err, credentials.ErrNotFound) {
+			s.writeError(w, http.StatusNotFound, "Credential not found")
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Removed registry credential '%s'", name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CredentialResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Credential '%s' removed", name),
+	})
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cares/internal/cluster"
+	"cares/internal/credentials"
+	"cares/internal/invocations"
+	"cares/internal/logging"
+)
+
+const testPassword = "s3cret-registry-password"
+
+// secureCluster secures the cluster channel with mutual TLS until the test
+// ends, every node presenting the same self-signed certificate for 127.0.0.1.
+func secureCluster(t *testing.T) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "cares-test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	config := cluster.TLSConfig{
+		CertFile: filepath.Join(dir, "node.pem"),
+		KeyFile:  filepath.Join(dir, "node-key.pem"),
+		CAFile:   filepath.Join(dir, "node.pem"),
+	}
+	os.WriteFile(config.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(config.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err := cluster.ConfigureTLS(config); err != nil {
+		t.Fatalf("configure TLS: %v", err)
+	}
+	t.Cleanup(func() { cluster.ConfigureTLS(cluster.TLSConfig{}) })
+}
+
+func TestInvokeWithRegistryCredential(t *testing.T) {
+	tests := []struct {
+		name       string
+		secure     bool
+		wantStatus string
+		wantPulls  int
+	}{
+		{name: "over TLS", secure: true, wantStatus: "success", wantPulls: 1},
+		{name: "refused in plaintext", wantStatus: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secure {
+				secureCluster(t)
+			}
+			logs := logging.NewBuffer(1000)
+			logging.Subscribe(logs)
+			defer logging.Unsubscribe(logs)
+			defer logging.SetLevel(logging.Level())
+			logging.SetLevel(slog.LevelDebug)
+
+			api, server, runtime := startCluster(t)
+			const image = "ghcr.io/acme/private:1"
+			runtime.RequireAuth(image, "acme", testPassword)
+
+			dir := t.TempDir()
+			store, err := credentials.OpenStore(filepath.Join(dir, "credentials.json"), make([]byte, 32))
+			if err != nil {
+				t.Fatalf("credential store: %v", err)
+			}
+			if _, err := store.Add(credentials.Credential{Name: "ghcr", Registry: "ghcr.io", Username: "acme", Password: testPassword}); err != nil {
+				t.Fatalf("add credential: %v", err)
+			}
+			server.SetCredentials(store)
+			historyPath := filepath.Join(dir, "history.jsonl")
+			history, err := invocations.OpenHistory(historyPath, invocations.DefaultRetention)
+			if err != nil {
+				t.Fatalf("history: %v", err)
+			}
+			defer history.Close()
+			server.SetHistory(history)
+
+			var registered FunctionResponse
+			if status := post(t, api.URL+"/functions", `{"name":"app","image":"`+image+`"}`, &registered); status != http.StatusCreated {
+				t.Fatalf("register: status %d: %s", status, registered.Message)
+			}
+			var updated FunctionResponse
+			if status := send(t, http.MethodPatch, api.URL+"/functions/"+registered.Function.ID, `{"settings":{"registry_credential":"ghcr"}}`, &updated); status != http.StatusOK {
+				t.Fatalf("set credential: status %d: %s", status, updated.Message)
+			}
+
+			var result InvokeResponse
+			post(t, api.URL+"/invoke/app", "", &result)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (%s)", result.Status, tt.wantStatus, result.Message)
+			}
+			if pulls := runtime.Pulls(); len(pulls) != tt.wantPulls {
+				t.Errorf("pulls = %v, want %d", pulls, tt.wantPulls)
+			}
+
+			history.Close()
+			stored, err := os.ReadFile(historyPath)
+			if err != nil {
+				t.Fatalf("read history: %v", err)
+			}
+			if len(stored) == 0 {
+				t.Error("invocation was not recorded")
+			}
+			if strings.Contains(string(stored), testPassword) {
+				t.Error("password stored in the invocation history")
+			}
+			if strings.Contains(result.Message+result.Output, testPassword) {
+				t.Error("password returned to the client")
+			}
+			for _, entry := range logs.Since(0) {
+				if strings.Contains(entry.String(), testPassword) {
+					t.Errorf("password logged: %s", entry)
+				}
+			}
+		})
+	}
+}
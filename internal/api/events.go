@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cares/internal/events"
+)
+
+// ActorHeader names the caller of a request in the audit log. There is no
+// authentication, so it is taken on trust; without it the caller is known by
+// its address.
+const ActorHeader = "X-Actor"
+
+// maxActorLength caps the length of an ActorHeader value
+const maxActorLength = 100
+
+// Pagination limits for GET /events
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+)
+
+// eventKeepAlive is how often an idle event stream sends a comment, so
+// proxies and clients don't give up on it
+const eventKeepAlive = 15 * time.Second
+
+// EventsResponse represents a page of the audit log
+type EventsResponse struct {
+	Status     string         `json:"status"`
+	Events     []events.Event `json:"events"`
+	Total      int            `json:"total"`                 // Matching events across all pages
+	NextOffset *int           `json:"next_offset,omitempty"` // Offset of the next page, if any
+}
+
+// actorMiddleware attributes the changes a request makes to its caller:
+// "api:" followed by the ActorHeader value, or by the client's address
+func (s *Server) actorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(events.WithActor(r.Context(), requestActor(r))))
+	})
+}
+
+// requestActor returns the actor of a request
+func requestActor(r *http.Request) string {
+	actor := strings.TrimSpace(r.Header.Get(ActorHeader))
+	if len(actor) > maxActorLength {
+		actor = actor[:maxActorLength]
+	}
+	if actor == "" {
+		actor = r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			actor = host
+		}
+	}
+	return "api:" + actor
+}
+
+// handleEvents handles GET /events: a page of the audit log, newest first,
+// or with ?stream=sse (or Accept: text/event-stream) a live stream of events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if streamMode(r) == streamSSE {
+		s.streamEvents(w, r, filter)
+		return
+	}
+
+	audit := events.Default().AuditLog()
+	if audit == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Audit log is not enabled")
+		return
+	}
+
+	matches, total := audit.Query(filter)
+	response := EventsResponse{
+		Status: "success",
+		Events: matches,
+		Total:  total,
+	}
+	if next := filter.Offset + len(matches); next < total {
+		response.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// streamEvents sends matching events as Server-Sent Events until the client
+// disconnects. Each event's id is its Seq: a client reconnecting with
+// Last-Event-ID (or ?after=) first receives the events it missed, as far as
+// the audit log remembers them.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, filter events.Filter) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, "Streaming not supported by connection")
+		return
+	}
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		seq, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Last-Event-ID must be an event seq")
+			return
+		}
+		filter.AfterSeq = seq
+	}
+
+	// Subscribe before replaying, so no event falls between the two
+	subscription := events.Default().Subscribe(filter)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent := filter.AfterSeq
+	if audit := events.Default().AuditLog(); audit != nil && filter.AfterSeq > 0 {
+		replay := filter
+		replay.Limit, replay.Offset = 0, 0
+		missed, _ := audit.Query(replay)
+		for i := len(missed) - 1; i >= 0; i-- {
+			writeEvent(w, missed[i])
+			sent = missed[i].Seq
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+			if event.Seq <= sent {
+				continue // Already replayed
+			}
+			writeEvent(w, event)
+			sent = event.Seq
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes an event as a Server-Sent Event named after its type
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+}
+
+// parseEventFilter builds an event filter from the query string. type is a
+// comma-separated list of event types or categories (e.g. "node,function.deleted");
+// since accepts an RFC 3339 timestamp or a duration relative to now (e.g. "15m");
+// after only keeps events with a greater seq.
+func parseEventFilter(r *http.Request) (events.Filter, error) {
+	query := r.URL.Query()
+	filter := events.Filter{
+		Actor:   query.Get("actor"),
+		Subject: query.Get("subject"),
+		Limit:   defaultEventLimit,
+	}
+
+	if types := query.Get("type"); types != "" {
+		for _, name := range strings.Split(types, ",") {
+			t := events.Type(strings.TrimSpace(name))
+			if !events.Known(t) {
+				return filter, fmt.Errorf("unknown event type '%s'", t)
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	if since := query.Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp or a positive duration")
+		}
+	}
+
+	if after := query.Get("after"); after != "" {
+		seq, err := strconv.ParseUint(after, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("after must be an event seq")
+		}
+		filter.AfterSeq = seq
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxEventLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxEventLimit)
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"cares/internal/events"
+	"cares/internal/invocations"
+)
+
+// failingThreshold is how many invocations of a function must fail in a row
+// before it is reported as failing.
+const failingThreshold = 5
+
+// failureTracker turns the outcome of every invocation into edge-triggered
+// events: FunctionFailing once a function's invocations keep failing, and
+// FunctionRecovered at its next success. Subscribers get one event per
+// outage instead of one per failed invocation.
+type failureTracker struct {
+	mu        sync.Mutex
+	functions map[string]*failureStreak // By function ID
+}
+
+// failureStreak counts the consecutive failed invocations of a function.
+type failureStreak struct {
+	failures int
+	failing  bool // FunctionFailing was published for this streak
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{functions: make(map[string]*failureStreak)}
+}
+
+// observe counts a finished invocation, publishing FunctionFailing or
+// FunctionRecovered if it changes whether the function is failing.
+func (t *failureTracker) observe(ctx context.Context, record *invocations.Record) {
+	if record.FunctionID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	streak := t.functions[record.FunctionID]
+	if record.Status == invocations.StatusSuccess {
+		delete(t.functions, record.FunctionID)
+		t.mu.Unlock()
+		if streak != nil && streak.failing {
+			t.publish(ctx, events.FunctionRecovered, record, map[string]string{
+				"failed_invocations": strconv.Itoa(streak.failures),
+			})
+		}
+		return
+	}
+
+	if streak == nil {
+		streak = &failureStreak{}
+		t.functions[record.FunctionID] = streak
+	}
+	streak.failures++
+	crossed := !streak.failing && streak.failures >= failingThreshold
+	if crossed {
+		streak.failing = true
+	}
+	failures := streak.failures
+	t.mu.Unlock()
+
+	if crossed {
+		data := map[string]string{
+			"consecutive_failures": strconv.Itoa(failures),
+			"error":                record.Error,
+		}
+		if record.FailureReason != "" {
+			data["failure_reason"] = record.FailureReason
+		}
+		t.publish(ctx, events.FunctionFailing, record, data)
+	}
+}
+
+// forget drops the streak of a deleted function.
+func (t *failureTracker) forget(functionID string) {
+	t.mu.Lock()
+	delete(t.functions, functionID)
+	t.mu.Unlock()
+}
+
+// publish publishes an event about the function of an invocation. The
+// function's state changed on its own rather than by anyone's request, so
+// the actor is the system whoever made the invocation.
+func (t *failureTracker) publish(ctx context.Context, eventType events.Type, record *invocations.Record, data map[string]string) {
+	events.Publish(events.WithActor(ctx, events.ActorSystem), events.Event{
+		Type:    eventType,
+		Subject: record.FunctionID,
+		Name:    record.FunctionName,
+		TraceID: record.TraceID,
+		Data:    data,
+	})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cares/internal/events"
+	"cares/internal/invocations"
+)
+
+func TestFailureTrackerIsEdgeTriggered(t *testing.T) {
+	subscription := events.Default().Subscribe(events.Filter{Types: []events.Type{events.FunctionFailing, events.FunctionRecovered}})
+	defer subscription.Close()
+
+	tracker := newFailureTracker()
+	observe := func(status string) {
+		tracker.observe(context.Background(), &invocations.Record{FunctionID: "fn-1", FunctionName: "app", Status: status, Error: "boom"})
+	}
+	for i := 0; i < failingThreshold-1; i++ {
+		observe(invocations.StatusFailed)
+	}
+	observe(invocations.StatusSuccess) // Resets the streak before the threshold
+	for i := 0; i < 2*failingThreshold; i++ {
+		observe(invocations.StatusFailed)
+	}
+	observe(invocations.StatusSuccess)
+	observe(invocations.StatusSuccess)
+
+	want := []struct {
+		eventType events.Type
+		key       string
+		value     string
+	}{
+		{events.FunctionFailing, "consecutive_failures", "5"},
+		{events.FunctionRecovered, "failed_invocations", "10"},
+	}
+	for _, w := range want {
+		select {
+		case event := <-subscription.C:
+			if event.Type != w.eventType || event.Subject != "fn-1" || event.Data[w.key] != w.value {
+				t.Errorf("event = %s %s %v, want %s fn-1 with %s=%s", event.Type, event.Subject, event.Data, w.eventType, w.key, w.value)
+			}
+			if event.Actor != events.ActorSystem {
+				t.Errorf("actor = %q, want %q", event.Actor, events.ActorSystem)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %s event", w.eventType)
+		}
+	}
+	select {
+	case event := <-subscription.C:
+		t.Errorf("unexpected %s event", event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
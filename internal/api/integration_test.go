@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cares/internal/cluster"
+	"cares/internal/executor"
+	"cares/internal/functions"
+	"cares/internal/registry"
+)
+
+// startCluster starts an API server whose functions run on one worker, which
+// executes them on a fake container runtime. The server can still be
+// configured until the first request.
+func startCluster(t *testing.T) (*httptest.Server, *Server, *executor.FakeRuntime) {
+	t.Helper()
+
+	runtime := executor.NewFakeRuntime()
+	executor.SetRuntime(runtime)
+	t.Cleanup(func() { executor.SetRuntime(nil) })
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	worker := cluster.NewServer()
+	worker.SetNodeID("worker-1")
+	go worker.Serve(lis)
+	t.Cleanup(func() { lis.Close() })
+
+	nodes := registry.NewNodeRegistry()
+	nodes.AddNode("worker-1", lis.Addr().String(), "worker")
+	nodes.UpdateMetrics("worker-1", 10, 10) // The first heartbeat makes a node active
+	nodes.UpdateRuntime("worker-1", registry.RuntimeStatus{Name: executor.RuntimeFake, Available: true})
+
+	functionRegistry, err := functions.NewRegistry(functions.NewFileStore(filepath.Join(t.TempDir(), "functions.json")))
+	if err != nil {
+		t.Fatalf("function registry: %v", err)
+	}
+	server := NewServer(functionRegistry)
+	server.SetNodeRegistry(nodes)
+
+	api := httptest.NewServer(server.Handler())
+	t.Cleanup(api.Close)
+	return api, server, runtime
+}
+
+// post sends a JSON body and decodes the JSON response into v
+func post(t *testing.T, url, body string, v interface{}) int {
+	t.Helper()
+	return send(t, http.MethodPost, url, body, v)
+}
+
+// send sends a request with a JSON body and decodes the JSON response into v
+func send(t *testing.T, method, url, body string, v interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s: decode response: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestInvokeRunsOnWorker(t *testing.T) {
+	tests := []struct {
+		name       string
+		behavior   *executor.FakeBehavior
+		wantStatus string
+		wantOutput string
+		wantExit   int
+	}{
+		{
+			name:       "greeting",
+			wantStatus: "success",
+			wantOutput: "Hello from registry.local:5000/acme/app:1\n",
+		},
+		{
+			name:       "scripted output",
+			behavior:   &executor.FakeBehavior{Stdout: "out\n", Stderr: "err\n"},
+			wantStatus: "success",
+			wantOutput: "out\nerr\n",
+		},
+		{
+			name:       "non-zero exit",
+			behavior:   &executor.FakeBehavior{Stderr: "boom\n", ExitCode: 3},
+			wantStatus: "error",
+			wantOutput: "boom\n",
+			wantExit:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, _, runtime := startCluster(t)
+			const image = "registry.local:5000/acme/app:1"
+			if tt.behavior != nil {
+				runtime.SetBehavior(image, *tt.behavior)
+			}
+
+			var registered FunctionResponse
+			if status := post(t, api.URL+"/functions", `{"name":"app","image":"`+image+`"}`, &registered); status != http.StatusCreated {
+				t.Fatalf("register: status %d: %s", status, registered.Message)
+			}
+
+			var result InvokeResponse
+			post(t, api.URL+"/invoke/app", "", &result)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q (%s)", result.Status, tt.wantStatus, result.Message)
+			}
+			if result.Output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", result.Output, tt.wantOutput)
+			}
+			if result.ExitCode != tt.wantExit {
+				t.Errorf("exit code = %d, want %d", result.ExitCode, tt.wantExit)
+			}
+			if result.Node != "worker-1" {
+				t.Errorf("node = %q, want worker-1", result.Node)
+			}
+			if pulls := runtime.Pulls(); len(pulls) != 1 || pulls[0] != image {
+				t.Errorf("pulls = %v, want [%s]", pulls, image)
+			}
+		})
+	}
+}
+
+func TestInvokeUnknownFunction(t *testing.T) {
+	api, _, _ := startCluster(t)
+
+	var result ErrorResponse
+	if status := post(t, api.URL+"/invoke/missing", "", &result); status != http.StatusNotFound {
+		t.Errorf("status = %d, want %d (%s)", status, http.StatusNotFound, result.Message)
+	}
+}
+
+func TestInvokeInputTooLarge(t *testing.T) {
+	api, _, runtime := startCluster(t)
+
+	var registered FunctionResponse
+	if status := post(t, api.URL+"/functions", `{"name":"app","image":"alpine"}`, &registered); status != http.StatusCreated {
+		t.Fatalf("register: status %d: %s", status, registered.Message)
+	}
+
+	var result ErrorResponse
+	input := strings.Repeat("x", MaxInvokeInputBytes+1)
+	if status := post(t, api.URL+"/invoke/app", input, &result); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d (%s)", status, http.StatusRequestEntityTooLarge, result.Message)
+	}
+	if pulls := runtime.Pulls(); len(pulls) != 0 {
+		t.Errorf("pulls = %v, want none", pulls)
+	}
+}
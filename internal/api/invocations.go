@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cares/internal/cluster"
+	"cares/internal/events"
+	"cares/internal/invocations"
+	"cares/internal/logging"
+)
+
+// Pagination limits for GET /invocations
+const (
+	defaultInvocationLimit = 50
+	maxInvocationLimit     = 500
+)
+
+// InvocationsResponse represents a page of invocation history
+type InvocationsResponse struct {
+	Status      string               `json:"status"`
+	Invocations []invocations.Record `json:"invocations"`
+	Total       int                  `json:"total"`                 // Matching records across all pages
+	NextOffset  *int                 `json:"next_offset,omitempty"` // Offset of the next page, if any
+}
+
+// InvocationResponse represents a single invocation record
+type InvocationResponse struct {
+	Status     string              `json:"status"`
+	Invocation *invocations.Record `json:"invocation"`
+}
+
+// recordInvocation finishes record, counts it in the metrics, publishes its
+// events and appends it to the history, if one is set
+func (s *Server) recordInvocation(ctx context.Context, record *invocations.Record, success bool, output, errMsg string) {
+	record.Finish(success, output, errMsg)
+	observeInvocation(record)
+	publishInvocation(ctx, record)
+	s.failures.observe(ctx, record)
+	if s.history == nil {
+		return
+	}
+
+	if err := s.history.Add(*record); err != nil {
+		logging.Warn("Failed to record invocation of '%s': %v", record.FunctionName, err)
+	}
+}
+
+// publishInvocation publishes the event of a finished invocation
+func publishInvocation(ctx context.Context, record *invocations.Record) {
+	event := events.Event{
+		Type:    events.InvocationSucceeded,
+		Subject: record.ID,
+		Name:    record.FunctionName,
+		TraceID: record.TraceID,
+		Data: map[string]string{
+			"function_id": record.FunctionID,
+			"version":     strconv.Itoa(record.Version),
+			"duration_ms": strconv.FormatInt(record.DurationMs, 10),
+		},
+	}
+	if record.NodeID != "" {
+		event.Data["node_id"] = record.NodeID
+	}
+	if record.Status != invocations.StatusSuccess {
+		event.Type = events.InvocationFailed
+		event.Data["error"] = record.Error
+		if record.FailureReason != "" {
+			event.Data["failure_reason"] = record.FailureReason
+		}
+	}
+	events.Publish(ctx, event)
+}
+
+// applyResult copies the execution details of a worker result into record
+func applyResult(record *invocations.Record, result *cluster.FunctionResult) {
+	if result.NodeId != "" {
+		record.NodeID = result.NodeId
+	}
+	if result.StartedAt != 0 {
+		exitCode := int(result.ExitCode)
+		record.ExitCode = &exitCode
+	}
+	record.ImageDigest = result.ImageDigest
+	record.FailureReason = result.FailureReason
+	record.OutputTruncated = record.OutputTruncated || result.OutputTruncated
+}
+
+// handleInvocations handles GET /invocations
+func (s *Server) handleInvocations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.history == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Invocation history is not enabled")
+		return
+	}
+
+	filter, err := parseInvocationFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, total := s.history.Query(filter)
+	response := InvocationsResponse{
+		Status:      "success",
+		Invocations: records,
+		Total:       total,
+	}
+	if next := filter.Offset + len(records); next < total {
+		response.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleInvocationByID handles GET /invocations/{id}
+func (s *Server) handleInvocationByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.history == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Invocation history is not enabled")
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/invocations/"), "/")
+	record, exists := s.history.Get(id)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Invocation not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(InvocationResponse{Status: "success", Invocation: record})
+}
+
+// parseInvocationFilter builds a history filter from the query string.
+// since accepts an RFC 3339 timestamp or a duration relative to now (e.g. "1h").
+func parseInvocationFilter(r *http.Request) (invocations.Filter, error) {
+	query := r.URL.Query()
+	filter := invocations.Filter{
+		Function: query.Get("function"),
+		Status:   query.Get("status"),
+		Limit:    defaultInvocationLimit,
+	}
+
+	if filter.Status != "" && filter.Status != invocations.StatusSuccess && filter.Status != invocations.StatusFailed {
+		return filter, fmt.Errorf("status must be '%s' or '%s'", invocations.StatusSuccess, invocations.StatusFailed)
+	}
+
+	if since := query.Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp or a positive duration")
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxInvocationLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxInvocationLimit)
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
+}
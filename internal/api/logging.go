@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"cares/internal/logging"
+)
+
+// LogLevelSetter changes the log level of workers: one worker, or all of them
+// if nodeID is empty. It is implemented by cluster.Server.
+type LogLevelSetter interface {
+	SetWorkerLogLevel(ctx context.Context, nodeID string, level slog.Level) int
+}
+
+// SetLogLevelSetter enables changing the log level of workers through the API
+func (s *Server) SetLogLevelSetter(setter LogLevelSetter) {
+	s.logLevelSetter = setter
+}
+
+// LogLevelRequest represents the JSON payload of PUT /log-level and
+// PUT /nodes/{id}/log-level
+type LogLevelRequest struct {
+	Level   string `json:"level"`             // "debug", "info", "warn" or "error"
+	Workers bool   `json:"workers,omitempty"` // PUT /log-level: also change every connected worker
+}
+
+// LogLevelResponse represents the JSON response of the log level endpoints
+type LogLevelResponse struct {
+	Status          string `json:"status"`
+	Level           string `json:"level"`
+	WorkersNotified int    `json:"workers_notified,omitempty"`
+}
+
+// handleLogLevel handles GET and PUT /log-level, the orchestrator's own level
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LogLevelResponse{Status: "success", Level: logging.LevelName(logging.Level())})
+	case "PUT":
+		level, req, ok := s.decodeLogLevel(w, r)
+		if !ok {
+			return
+		}
+		if req.Workers && s.logLevelSetter == nil {
+			s.writeError(w, http.StatusServiceUnavailable, "Worker log levels cannot be changed")
+			return
+		}
+
+		logging.SetLevel(level)
+		response := LogLevelResponse{Status: "success", Level: logging.LevelName(level)}
+		if req.Workers {
+			response.WorkersNotified = s.logLevelSetter.SetWorkerLogLevel(r.Context(), "", level)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleNodeLogLevel handles PUT /nodes/{id}/log-level, sending the new level
+// to the worker with its next heartbeat
+func (s *Server) handleNodeLogLevel(w http.ResponseWriter, r *http.Request, nodeID string) {
+	if s.logLevelSetter == nil || s.nodeRegistry == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Worker log levels cannot be changed")
+		return
+	}
+	if s.nodeRegistry.GetNode(nodeID) == nil {
+		s.writeError(w, http.StatusNotFound, "Node not found")
+		return
+	}
+	level, _, ok := s.decodeLogLevel(w, r)
+	if !ok {
+		return
+	}
+
+	if s.logLevelSetter.SetWorkerLogLevel(r.Context(), nodeID, level) == 0 {
+		s.writeError(w, http.StatusConflict, "Node is not connected or its command queue is full")
+		return
+	}
+	logging.With(logging.KeyNodeID, nodeID).Info("Asked worker to change its log level", "level", logging.LevelName(level))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LogLevelResponse{Status: "success", Level: logging.LevelName(level), WorkersNotified: 1})
+}
+
+// decodeLogLevel reads a LogLevelRequest, writing an error response and
+// returning false if it is invalid
+func (s *Server) decodeLogLevel(w http.ResponseWriter, r *http.Request) (slog.Level, LogLevelRequest, bool) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return 0, req, false
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return 0, req, false
+	}
+	return level, req, true
+}
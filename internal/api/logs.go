@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cares/internal/logging"
+	"cares/internal/logstore"
+)
+
+// Pagination limits for GET /logs
+const (
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+)
+
+// LogsResponse represents a page of worker log records
+type LogsResponse struct {
+	Status     string            `json:"status"`
+	Logs       []logstore.Record `json:"logs"`
+	Total      int               `json:"total"`                 // Matching records across all pages
+	NextOffset *int              `json:"next_offset,omitempty"` // Offset of the next page, if any
+}
+
+// SetLogStore sets the store of worker logs served by GET /logs
+func (s *Server) SetLogStore(store *logstore.Store) {
+	s.logStore = store
+}
+
+// handleLogs handles GET /logs, the log records shipped by workers
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.logStore == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Log aggregation is not enabled")
+		return
+	}
+
+	filter, err := parseLogFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, total, err := s.logStore.Query(filter)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response := LogsResponse{
+		Status: "success",
+		Logs:   records,
+		Total:  total,
+	}
+	if next := filter.Offset + len(records); next < total {
+		response.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseLogFilter builds a log store filter from the query string. level is
+// the minimum level; since accepts an RFC 3339 timestamp or a duration
+// relative to now (e.g. "15m").
+func parseLogFilter(r *http.Request) (logstore.Filter, error) {
+	query := r.URL.Query()
+	filter := logstore.Filter{
+		Node:         query.Get("node"),
+		Level:        query.Get("level"),
+		Function:     query.Get("function"),
+		InvocationID: query.Get("invocation"),
+		Limit:        defaultLogLimit,
+	}
+
+	if filter.Level != "" {
+		if _, err := logging.ParseLevel(filter.Level); err != nil {
+			return filter, err
+		}
+	}
+
+	if since := query.Get("since"); since != "" {
+		if t, err := time.Parse(time.RFC3339, since); err == nil {
+			filter.Since = t
+		} else if d, err := time.ParseDuration(since); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp or a positive duration")
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxLogLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxLogLimit)
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"cares/internal/invocations"
+	"cares/internal/telemetry"
+)
+
+// failureDispatch labels failed invocations that never produced a worker
+// result, e.g. because no worker was available or the gRPC call failed.
+const failureDispatch = "dispatch"
+
+var (
+	invocationsTotal = telemetry.NewCounter("cares_invocations_total",
+		"Finished invocations, by function, node and status.", "function", "node", "status")
+	invocationFailures = telemetry.NewCounter("cares_invocation_failures_total",
+		"Failed invocations, by function, node and failure reason.", "function", "node", "reason")
+	invocationDuration = telemetry.NewHistogram("cares_invocation_duration_seconds",
+		"Invocation latency as seen by the API, by function and node.", nil, "function", "node")
+	invocationsInFlight = telemetry.NewGauge("cares_invocations_in_flight",
+		"Invocations accepted and not yet finished, by function.", "function")
+)
+
+// AddMetricsCollector adds a collector whose metrics are served at /metrics
+// alongside the API's own
+func (s *Server) AddMetricsCollector(collector telemetry.Collector) {
+	s.metricsCollectors = append(s.metricsCollectors, collector)
+}
+
+// handleMetrics handles GET /metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	telemetry.Handler(s.metricsCollectors...).ServeHTTP(w, r)
+}
+
+// observeInvocation records a finished invocation in the metrics
+func observeInvocation(record *invocations.Record) {
+	invocationsTotal.Inc(record.FunctionName, record.NodeID, record.Status)
+	invocationDuration.Observe(record.FinishedAt.Sub(record.StartedAt).Seconds(), record.FunctionName, record.NodeID)
+	if record.Status != invocations.StatusSuccess {
+		reason := record.FailureReason
+		if reason == "" {
+			reason = failureDispatch
+		}
+		invocationFailures.Inc(record.FunctionName, record.NodeID, reason)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cares/internal/registry"
+)
+
+// defaultMetricsRange is the history returned by GET /nodes/{id}/metrics without ?range=
+const defaultMetricsRange = time.Hour
+
+// NodeMetricsResponse represents the JSON response of GET /nodes/{id}/metrics
+type NodeMetricsResponse struct {
+	Status     string                 `json:"status"`
+	NodeID     string                 `json:"node_id"`
+	Range      string                 `json:"range"`
+	Resolution string                 `json:"resolution"` // Interval each point averages over
+	Points     []registry.MetricPoint `json:"points"`
+}
+
+// handleNodeByID handles requests under /nodes/{id}
+func (s *Server) handleNodeByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/nodes/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		s.writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	switch {
+	case parts[1] == "metrics" && r.Method == "GET":
+		s.handleNodeMetrics(w, r, parts[0])
+	case parts[1] == "log-level" && r.Method == "PUT":
+		s.handleNodeLogLevel(w, r, parts[0])
+	case parts[1] == "metrics" || parts[1] == "log-level":
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	default:
+		s.writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleNodeMetrics handles GET /nodes/{id}/metrics?range=, returning the
+// node's metric history over range (a duration such as "15m", default 1h)
+func (s *Server) handleNodeMetrics(w http.ResponseWriter, r *http.Request, nodeID string) {
+	if s.nodeRegistry == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Node registry not available")
+		return
+	}
+
+	window := defaultMetricsRange
+	if value := r.URL.Query().Get("range"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			s.writeError(w, http.StatusBadRequest, "range must be a positive duration, e.g. '15m' or '6h'")
+			return
+		}
+		if limit := s.nodeRegistry.MaxHistoryRange(); d > limit {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("range must not exceed %s", limit))
+			return
+		}
+		window = d
+	}
+
+	points, resolution, exists := s.nodeRegistry.History(nodeID, window)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Node not found")
+		return
+	}
+	if points == nil {
+		points = []registry.MetricPoint{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NodeMetricsResponse{
+		Status:     "success",
+		NodeID:     nodeID,
+		Range:      window.String(),
+		Resolution: resolution.String(),
+		Points:     points,
+	})
+}
@@ -5,24 +5,67 @@
 // Available endpoints:
 //   - GET /functions - List all registered functions
 //   - POST /functions - Register a new function
-//   - GET /functions/{id} - Get function details by ID
-//   - POST /invoke/{name} - Execute a function by name
+//   - GET /functions/{id} - Get function details by ID (revision returned as ETag)
+//   - PATCH /functions/{id} - Update image, description or settings (If-Match for concurrency)
+//   - GET /functions/{id}/versions - List published versions with invocation stats
+//   - POST /functions/{id}/versions - Publish a new version of a function
+//   - GET /functions/{id}/traffic - Get the weighted traffic split between versions
+//   - PUT /functions/{id}/traffic - Set the weighted traffic split between versions
+//   - POST /functions/{id}/enable - Allow a function to be invoked
+//   - POST /functions/{id}/disable - Reject invocations of a function
+//   - GET /functions/{id}/schedule - Get scheduled enable/disable windows
+//   - PUT /functions/{id}/schedule - Replace scheduled enable/disable windows
+//   - POST /invoke/{name} - Execute a function by name; the body is passed to process and wasm
+//     functions on stdin (?stream=sse|chunked streams output live)
+//   - GET /invocations - Query invocation history (function, status, since, limit, offset)
+//   - GET /invocations/{id} - Get a single invocation record
+//   - GET /credentials - List private registry credentials (passwords are never returned)
+//   - POST /credentials - Store a private registry credential
+//   - DELETE /credentials/{name} - Remove a credential no function references
+//   - GET /nodes/{id}/metrics - Metric history of a worker node (?range=15m, up to 24h)
+//   - PUT /nodes/{id}/log-level - Change the log level of a worker
+//   - GET /log-level - Get the orchestrator's log level
+//   - PUT /log-level - Change the log level of the orchestrator, and of all workers with "workers": true
+//   - GET /logs - Query logs shipped by workers (node, level, since, function, invocation, limit, offset)
+//   - GET /events - Query the audit log of cluster events (type, actor, subject, since, after, limit, offset);
+//     ?stream=sse streams events live, resuming after Last-Event-ID
+//   - GET /webhooks - List webhook subscriptions (secrets are never returned)
+//   - POST /webhooks - Subscribe a URL to event types; returns the signing secret once
+//   - GET /webhooks/{id} - Get a webhook subscription
+//   - DELETE /webhooks/{id} - Remove a webhook subscription
+//   - POST /webhooks/{id}/test - Send a signed ping event to the webhook's URL
+//   - GET /webhooks/{id}/dead-letters - Deliveries given up on after retries (limit, offset)
+//   - GET /metrics - Prometheus metrics: nodes, invocations, latency, queues and gRPC errors
+//
+// Every request is traced; the trace ID is returned in the X-Trace-Id header,
+// and a traceparent header makes the request part of the caller's trace.
+// Changes are recorded in the audit log as made by "api:" and the X-Actor
+// header of the request, or the client's address if it has none.
 package api
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"strconv"
+	"strings"
+	"time"
 
 	"cares/internal/cluster"
+	"cares/internal/credentials"
 	"cares/internal/functions"
+	"cares/internal/invocations"
 	"cares/internal/logging"
+	"cares/internal/logstore"
 	"cares/internal/registry"
 	"cares/internal/scheduler"
+	"cares/internal/telemetry"
+	"cares/internal/tracing"
+	"cares/internal/webhooks"
 )
 
 // Server represents the REST API server for function management and execution.
@@ -33,10 +76,21 @@ import (
 // for all endpoints. It integrates with the function registry for persistence
 // and the node registry for worker node management.
 type Server struct {
-	registry     *functions.Registry  // Function registry for storage and retrieval
-	nodeRegistry *registry.NodeRegistry // Node registry for worker management
-	scheduler    *scheduler.Scheduler    // Scheduler for optimal node selection
-	server       *http.Server           // HTTP server instance
+	registry       *functions.Registry    // Function registry for storage and retrieval
+	nodeRegistry   *registry.NodeRegistry // Node registry for worker management
+	scheduler      *scheduler.Scheduler   // Scheduler for optimal node selection
+	history        *invocations.History   // Invocation history, nil if not recorded
+	maxOutputBytes int64                  // Output cap sent to workers (0 = worker default)
+	imagePuller    ImagePuller            // Pre-pulls images onto workers, nil to disable
+	credentials    *credentials.Store     // Private registry credentials, nil if not configured
+	digestResolver DigestResolver         // Pins published versions to digests, nil to disable
+	logLevelSetter LogLevelSetter         // Changes worker log levels, nil to disable
+	logStore       *logstore.Store        // Logs shipped by workers, nil if not aggregated
+	webhooks       *webhooks.Dispatcher   // Delivers events to webhook subscriptions, nil to disable
+	failures       *failureTracker        // Reports functions that start failing or recover
+	server         *http.Server           // HTTP server instance
+
+	metricsCollectors []telemetry.Collector // Served at /metrics with the API's own metrics
 }
 
 // NewServer creates a new REST API server with the provided function registry.
@@ -53,7 +107,8 @@ type Server struct {
 //
 // Example usage:
 //
-//	funcRegistry := functions.NewRegistry()
+//	store, _ := functions.OpenDefaultStore()
+//	funcRegistry, _ := functions.NewRegistry(store)
 //	apiServer := NewServer(funcRegistry)
 //	apiServer.SetNodeRegistry(nodeRegistry)
 //	err := apiServer.StartServer("8080")
@@ -61,6 +116,7 @@ func NewServer(registry *functions.Registry) *Server {
 	return &Server{
 		registry:  registry,
 		scheduler: scheduler.NewScheduler(),
+		failures:  newFailureTracker(),
 	}
 }
 
@@ -69,11 +125,56 @@ func (s *Server) SetNodeRegistry(nodeRegistry *registry.NodeRegistry) {
 	s.nodeRegistry = nodeRegistry
 }
 
+// SetHistory sets the invocation history every invocation is recorded to
+func (s *Server) SetHistory(history *invocations.History) {
+	s.history = history
+}
+
+// SetMaxOutputBytes caps how much output a single invocation may produce
+func (s *Server) SetMaxOutputBytes(limit int64) {
+	s.maxOutputBytes = limit
+}
+
+// ImagePuller asks workers to fetch an image ahead of its first invocation.
+// It is implemented by cluster.Server.
+type ImagePuller interface {
+	PrePullImage(ctx context.Context, image string) int
+}
+
+// SetImagePuller enables pre-pulling images onto workers when container
+// functions are registered or publish a new version
+func (s *Server) SetImagePuller(puller ImagePuller) {
+	s.imagePuller = puller
+}
+
+// prePull warms the worker image caches for a version of a container
+// function, by digest if it is pinned, unless its pull policy forbids pulling.
+// Images behind a registry credential are not pre-pulled: the credential is
+// only ever sent to the node selected to run an invocation, which pulls the
+// image then.
+func (s *Server) prePull(ctx context.Context, function *functions.Function, version *functions.FunctionVersion) {
+	if s.imagePuller == nil || version == nil || function.Kind != functions.KindContainer || function.Settings.PullPolicy == functions.PullNever {
+		return
+	}
+	image := version.PinnedImage()
+	if function.Settings.RegistryCredential != "" {
+		logging.Debug("Not pre-pulling private image '%s' for function '%s'", image, function.Name)
+		return
+	}
+	if queued := s.imagePuller.PrePullImage(ctx, image); queued > 0 {
+		logging.Info("Asked %d worker(s) to pre-pull image '%s' for function '%s'", queued, image, function.Name)
+	}
+}
+
+// MaxInvokeInputBytes caps the invocation input accepted by POST /invoke/{name}
+const MaxInvokeInputBytes = 1 << 20
+
 // FunctionRequest represents the JSON payload for function registration
 type FunctionRequest struct {
 	Name        string `json:"name"`
-	Image       string `json:"image"`
+	Image       string `json:"image"` // Container image, or executable path / wasm module for other kinds
 	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind,omitempty"` // "container" (default), "process" or "wasm"
 }
 
 // FunctionResponse represents the JSON response for function operations
@@ -83,38 +184,97 @@ type FunctionResponse struct {
 	Function   *functions.Function   `json:"function,omitempty"`
 	Functions  []*functions.Function `json:"functions,omitempty"`
 	InvokePath string                `json:"invoke_path,omitempty"`
+	PinError   string                `json:"pin_error,omitempty"` // Why the image could not be pinned to a digest
+}
+
+// InvokeResponse represents the JSON response of POST /invoke/{name}
+type InvokeResponse struct {
+	Status          string     `json:"status"`
+	Message         string     `json:"message,omitempty"` // Failure reason
+	Output          string     `json:"output"`            // Combined stdout and stderr
+	Stdout          string     `json:"stdout"`
+	Stderr          string     `json:"stderr"`
+	ExitCode        int        `json:"exit_code"` // -1 if the container did not exit normally
+	OutputTruncated bool       `json:"output_truncated,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	ImageDigest     string     `json:"image_digest,omitempty"`   // Digest of the image that actually ran
+	FailureReason   string     `json:"failure_reason,omitempty"` // Why the run failed, e.g. "digest_mismatch"
+	Node            string     `json:"node"`                     // Worker node that executed the function
+	Version         int        `json:"version"`
+	InvocationID    string     `json:"invocation_id"`
+	TraceID         string     `json:"trace_id,omitempty"` // Trace of the invocation, see X-Trace-Id
+}
+
+// FunctionPatchRequest represents the JSON payload for partial function updates.
+// Omitted fields are left unchanged; Revision may be used instead of If-Match.
+type FunctionPatchRequest struct {
+	Image       *string                `json:"image,omitempty"`
+	Description *string                `json:"description,omitempty"`
+	Settings    *FunctionSettingsPatch `json:"settings,omitempty"`
+	Revision    *int64                 `json:"revision,omitempty"`
+}
+
+// FunctionSettingsPatch represents the editable execution settings of a function
+type FunctionSettingsPatch struct {
+	TimeoutSeconds *int              `json:"timeout_seconds,omitempty"`
+	MemoryMB       *int              `json:"memory_mb,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Args           []string          `json:"args,omitempty"`
+	PullPolicy     *string           `json:"pull_policy,omitempty"`
+	// RegistryCredential names a credential from /credentials; "" removes it
+	RegistryCredential *string `json:"registry_credential,omitempty"`
+	VerifySignature    *bool   `json:"verify_signature,omitempty"`
 }
 
 // ErrorResponse represents error responses
 type ErrorResponse struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Errors  []functions.FieldError `json:"errors,omitempty"`
 }
 
 // StartServer starts the REST API server on the specified port
 func (s *Server) StartServer(port string) error {
-	mux := http.NewServeMux()
-
-	// Register routes
-	mux.HandleFunc("/functions", s.handleFunctions)
-	mux.HandleFunc("/functions/", s.handleFunctionByID)
-	mux.HandleFunc("/invoke/", s.handleInvokeFunction)
-
 	s.server = &http.Server{
 		Addr:    ":" + port,
-		Handler: s.corsMiddleware(mux),
+		Handler: s.Handler(),
 	}
 
 	logging.Info("REST API server starting on port %s", port)
 	return s.server.ListenAndServe()
 }
 
+// Handler returns the HTTP handler serving every API endpoint
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Register routes
+	mux.HandleFunc("/functions", s.handleFunctions)
+	mux.HandleFunc("/functions/", s.handleFunctionByID)
+	mux.HandleFunc("/invoke/", s.handleInvokeFunction)
+	mux.HandleFunc("/invocations", s.handleInvocations)
+	mux.HandleFunc("/invocations/", s.handleInvocationByID)
+	mux.HandleFunc("/credentials", s.handleCredentials)
+	mux.HandleFunc("/credentials/", s.handleCredentialByName)
+	mux.HandleFunc("/nodes/", s.handleNodeByID)
+	mux.HandleFunc("/log-level", s.handleLogLevel)
+	mux.HandleFunc("/logs", s.handleLogs)
+	mux.HandleFunc("/events", s.handleEvents)
+	mux.HandleFunc("/webhooks", s.handleWebhooks)
+	mux.HandleFunc("/webhooks/", s.handleWebhookByID)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	return s.corsMiddleware(s.tracingMiddleware(s.actorMiddleware(mux)))
+}
+
 // corsMiddleware adds CORS headers for browser compatibility
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match, Traceparent, X-Actor, Last-Event-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Trace-Id")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -137,13 +297,43 @@ func (s *Server) handleFunctions(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleFunctionByID handles /functions/{id} endpoint
+// handleFunctionByID handles /functions/{id} endpoint and its sub-resources
 func (s *Server) handleFunctionByID(w http.ResponseWriter, r *http.Request) {
+	id, resource := splitFunctionPath(r.URL.Path)
+	if id == "" {
+		s.writeError(w, http.StatusBadRequest, "Function ID required")
+		return
+	}
+	switch resource {
+	case "":
+		// Plain /functions/{id}, handled below
+	case "versions":
+		s.handleFunctionVersions(w, r, id)
+		return
+	case "traffic":
+		s.handleFunctionTraffic(w, r, id)
+		return
+	case "enable":
+		s.handleFunctionStatus(w, r, id, functions.StatusActive)
+		return
+	case "disable":
+		s.handleFunctionStatus(w, r, id, functions.StatusInactive)
+		return
+	case "schedule":
+		s.handleFunctionSchedule(w, r, id)
+		return
+	default:
+		s.writeError(w, http.StatusNotFound, "Unknown function resource")
+		return
+	}
+
 	switch r.Method {
 	case "GET":
-		s.getFunction(w, r)
+		s.getFunction(w, r, id)
+	case "PATCH":
+		s.patchFunction(w, r, id)
 	case "DELETE":
-		s.deleteFunction(w, r)
+		s.deleteFunction(w, r, id)
 	default:
 		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
@@ -167,19 +357,33 @@ func (s *Server) createFunction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Kind == "" {
+		req.Kind = functions.KindContainer
+	}
+
 	// Add function to registry
-	function, err := s.registry.AddFunction(req.Name, req.Image, req.Description)
+	function, err := s.registry.AddFunctionOfKind(r.Context(), req.Kind, req.Name, req.Image, req.Description)
 	if err != nil {
-		s.writeError(w, http.StatusConflict, err.Error())
+		var validationErr *functions.ValidationError
+		if errors.As(err, &validationErr) {
+			s.writeValidationError(w, validationErr)
+		} else if errors.Is(err, functions.ErrDuplicateName) {
+			s.writeError(w, http.StatusConflict, err.Error())
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
+	function, pinErr := s.PrepareLatestVersion(r.Context(), function)
+
 	// Success response
 	response := FunctionResponse{
 		Status:     "success",
 		Message:    fmt.Sprintf("Function '%s' registered successfully", req.Name),
 		Function:   function,
 		InvokePath: fmt.Sprintf("/invoke/%s", req.Name),
+		PinError:   pinErr,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -201,15 +405,7 @@ func (s *Server) listFunctions(w http.ResponseWriter, r *http.Request) {
 }
 
 // getFunction handles GET /functions/{id}
-func (s *Server) getFunction(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	path := r.URL.Path
-	if len(path) < 11 { // "/functions/" = 11 chars
-		s.writeError(w, http.StatusBadRequest, "Function ID required")
-		return
-	}
-	id := path[11:] // Get everything after "/functions/"
-
+func (s *Server) getFunction(w http.ResponseWriter, r *http.Request, id string) {
 	function, exists := s.registry.GetFunction(id)
 	if !exists {
 		s.writeError(w, http.StatusNotFound, "Function not found")
@@ -222,24 +418,114 @@ func (s *Server) getFunction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(function.Revision))
 	json.NewEncoder(w).Encode(response)
 }
 
-// deleteFunction handles DELETE /functions/{id}
-func (s *Server) deleteFunction(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	path := r.URL.Path
-	if len(path) < 11 { // "/functions/" = 11 chars
-		s.writeError(w, http.StatusBadRequest, "Function ID required")
+// patchFunction handles PATCH /functions/{id}
+func (s *Server) patchFunction(w http.ResponseWriter, r *http.Request, id string) {
+	var req FunctionPatchRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields() // Name, status etc. are not editable here
+	if err := decoder.Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON payload: %v", err))
 		return
 	}
-	id := path[11:] // Get everything after "/functions/"
 
-	if !s.registry.RemoveFunction(id) {
-		s.writeError(w, http.StatusNotFound, "Function not found")
+	// Optimistic concurrency: If-Match takes precedence over the body revision
+	var expectedRevision int64
+	if req.Revision != nil {
+		expectedRevision = *req.Revision
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != "*" {
+		revision, err := parseRevisionETag(ifMatch)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid If-Match header")
+			return
+		}
+		expectedRevision = revision
+	}
+
+	update := functions.FunctionUpdate{
+		Image:       req.Image,
+		Description: req.Description,
+	}
+	if req.Settings != nil {
+		update.TimeoutSeconds = req.Settings.TimeoutSeconds
+		update.MemoryMB = req.Settings.MemoryMB
+		update.Env = req.Settings.Env
+		update.Args = req.Settings.Args
+		update.PullPolicy = req.Settings.PullPolicy
+		update.RegistryCredential = req.Settings.RegistryCredential
+		update.VerifySignature = req.Settings.VerifySignature
+	}
+	if name := update.RegistryCredential; name != nil && *name != "" && !s.credentialExists(*name) {
+		s.writeValidationError(w, &functions.ValidationError{Fields: []functions.FieldError{{
+			Field:   "settings.registry_credential",
+			Message: fmt.Sprintf("no credential named '%s'", *name),
+		}}})
 		return
 	}
 
+	function, err := s.registry.UpdateFunction(r.Context(), id, expectedRevision, update)
+	if err != nil {
+		var validationErr *functions.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			s.writeValidationError(w, validationErr)
+		case errors.Is(err, functions.ErrFunctionNotFound):
+			s.writeError(w, http.StatusNotFound, "Function not found")
+		case errors.Is(err, functions.ErrRevisionConflict):
+			s.writeError(w, http.StatusPreconditionFailed, "Function has been modified, reload and retry")
+		default:
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Updated function '%s' to revision %d", function.Name, function.Revision)
+	var pinErr string
+	if update.Image != nil {
+		function, pinErr = s.PrepareLatestVersion(r.Context(), function)
+	} else if update.RegistryCredential != nil {
+		s.prePull(r.Context(), function, function.LatestVersion())
+	}
+
+	response := FunctionResponse{
+		Status:   "success",
+		Message:  "Function updated successfully",
+		Function: function,
+		PinError: pinErr,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", revisionETag(function.Revision))
+	json.NewEncoder(w).Encode(response)
+}
+
+// revisionETag formats a function revision as a strong ETag
+func revisionETag(revision int64) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// parseRevisionETag extracts the revision number from an ETag or If-Match value
+func parseRevisionETag(etag string) (int64, error) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	return strconv.ParseInt(strings.Trim(etag, "\""), 10, 64)
+}
+
+// deleteFunction handles DELETE /functions/{id}
+func (s *Server) deleteFunction(w http.ResponseWriter, r *http.Request, id string) {
+	if err := s.registry.RemoveFunction(r.Context(), id); err != nil {
+		if errors.Is(err, functions.ErrFunctionNotFound) {
+			s.writeError(w, http.StatusNotFound, "Function not found")
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.failures.forget(id)
+
 	response := FunctionResponse{
 		Status:  "success",
 		Message: "Function deleted successfully",
@@ -249,6 +535,14 @@ func (s *Server) deleteFunction(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// splitFunctionPath splits "/functions/{id}/{resource}" into its ID and
+// optional sub-resource name.
+func splitFunctionPath(path string) (string, string) {
+	rest := strings.Trim(strings.TrimPrefix(path, "/functions/"), "/")
+	id, resource, _ := strings.Cut(rest, "/")
+	return id, resource
+}
+
 // writeError writes an error response
 func (s *Server) writeError(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -262,6 +556,17 @@ func (s *Server) writeError(w http.ResponseWriter, statusCode int, message strin
 	json.NewEncoder(w).Encode(response)
 }
 
+// writeValidationError sends a 422 listing every rejected field
+func (s *Server) writeValidationError(w http.ResponseWriter, validationErr *functions.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Status:  "error",
+		Message: "Validation failed",
+		Errors:  validationErr.Fields,
+	})
+}
+
 // handleInvokeFunction handles POST /invoke/{function_name} endpoint
 func (s *Server) handleInvokeFunction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -277,55 +582,137 @@ func (s *Server) handleInvokeFunction(w http.ResponseWriter, r *http.Request) {
 	}
 	functionName := path[8:] // Get everything after "/invoke/"
 
-	// Step 1: Lookup function in registry
-	function, exists := s.registry.GetFunctionByName(functionName)
-	if !exists {
-		s.writeError(w, http.StatusNotFound, fmt.Sprintf("Function '%s' not found", functionName))
+	// Step 1: Lookup function in registry and pick a version from its traffic split
+	function, version, err := s.registry.ResolveVersion(functionName)
+	if err != nil {
+		if errors.Is(err, functions.ErrNoVersions) {
+			s.writeError(w, http.StatusConflict, fmt.Sprintf("Function '%s' has no published versions to invoke", functionName))
+		} else {
+			s.writeError(w, http.StatusNotFound, fmt.Sprintf("Function '%s' not found", functionName))
+		}
+		return
+	}
+
+	// Disabled functions (manually or by schedule) are never dispatched
+	if now := time.Now(); !function.IsInvocable(now) {
+		s.writeError(w, http.StatusForbidden, disabledMessage(function, now))
+		return
+	}
+
+	// The request body is the invocation input, handed to the function on stdin
+	input, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxInvokeInputBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Invocation input exceeds %d bytes", MaxInvokeInputBytes))
+		} else {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read invocation input: %v", err))
+		}
 		return
 	}
 
+	// Every attempt from here on is recorded in the invocation history
+	ctx := r.Context()
+	record := invocations.NewRecord(function.ID, function.Name, version.Version)
+	record.TraceID = tracing.TraceIDFromContext(ctx)
+	tracing.FromContext(ctx).SetAttributes(slog.String(logging.KeyFunction, function.Name),
+		slog.String(logging.KeyInvocationID, record.ID), slog.Int("version", version.Version))
+	invocationsInFlight.Add(1, function.Name)
+	defer invocationsInFlight.Add(-1, function.Name)
+
+	req, err := s.functionRequest(function, version, input)
+	if err != nil {
+		s.recordInvocation(ctx, &record, false, "", err.Error())
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	req.InvocationId = record.ID
+
 	// Step 2: Schedule execution (select optimal worker)
 	if s.nodeRegistry == nil {
+		s.recordInvocation(ctx, &record, false, "", "no worker nodes available")
 		s.writeError(w, http.StatusServiceUnavailable, "No worker nodes available")
 		return
 	}
 
-	selectedNode, err := s.scheduler.SelectNodeForExecution(s.nodeRegistry)
+	requirements := scheduler.Requirements{}
+	if function.Kind == functions.KindContainer {
+		requirements.ContainerRuntime = true
+		requirements.Image = version.Image
+	}
+	_, span := tracing.Start(ctx, "schedule")
+	selectedNode, err := s.scheduler.SelectNode(s.nodeRegistry, requirements)
+	if err == nil {
+		span.SetAttributes(slog.String(logging.KeyNodeID, selectedNode.ID))
+	}
+	span.RecordError(err)
+	span.End()
 	if err != nil {
+		s.recordInvocation(ctx, &record, false, "", fmt.Sprintf("failed to select worker: %v", err))
 		s.writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("Failed to select worker: %v", err))
 		return
 	}
+	record.NodeID = selectedNode.ID
 
-	logging.Info("Selected node '%s' for function '%s' execution", selectedNode.ID, functionName)
+	logging.With(logging.KeyFunction, functionName, logging.KeyInvocationID, record.ID, logging.KeyNodeID, selectedNode.ID).
+		InfoContext(ctx, "Selected node for execution", "version", version.Version)
 
-	// Step 3: Execute function on selected worker via gRPC
-	result, err := s.executeOnWorker(selectedNode, function)
-	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Execution failed: %v", err))
+	// Streaming clients receive output while the container runs
+	if mode := streamMode(r); mode != "" {
+		s.streamInvocation(w, r, mode, selectedNode, function, version, req, &record)
 		return
 	}
 
-	// Step 4: Return result
-	if !result.Success {
-		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Function execution failed: %s", result.Error))
+	// Step 3: Execute function on selected worker via gRPC. The run is not
+	// cancelled if the client disconnects, so its outcome is still recorded
+	started := time.Now()
+	result, err := s.executeOnWorker(context.WithoutCancel(ctx), selectedNode, function, version, req)
+	if recordErr := s.registry.RecordInvocation(function.ID, version.Version, err == nil && result.Success, time.Since(started)); recordErr != nil {
+		logging.Warn("Failed to record invocation stats for '%s': %v", functionName, recordErr)
+	}
+	if err != nil {
+		s.recordInvocation(ctx, &record, false, "", err.Error())
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Execution failed: %v", err))
 		return
 	}
+	applyResult(&record, result)
+	s.recordInvocation(ctx, &record, result.Success, result.Output, result.Error)
+
+	// Step 4: Return result, including the details of failed runs
+	response := InvokeResponse{
+		Status:          "success",
+		Output:          result.Output,
+		Stdout:          result.Stdout,
+		Stderr:          result.Stderr,
+		ExitCode:        int(result.ExitCode),
+		OutputTruncated: result.OutputTruncated,
+		StartedAt:       unixMilliTime(result.StartedAt),
+		FinishedAt:      unixMilliTime(result.FinishedAt),
+		ImageDigest:     result.ImageDigest,
+		FailureReason:   result.FailureReason,
+		Node:            result.NodeId,
+		Version:         version.Version,
+		InvocationID:    record.ID,
+		TraceID:         record.TraceID,
+	}
 
-	// Return successful result
-	response := map[string]interface{}{
-		"status": "success",
-		"output": result.Output,
-		"node":   selectedNode.ID,
+	if response.Node == "" {
+		response.Node = selectedNode.ID
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if !result.Success {
+		response.Status = "error"
+		response.Message = fmt.Sprintf("Function execution failed: %s", result.Error)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
-// executeOnWorker executes a function on a specific worker node via gRPC
-func (s *Server) executeOnWorker(node *registry.Node, function *functions.Function) (*cluster.FunctionResult, error) {
+// executeOnWorker executes a version of a function on a specific worker node via gRPC
+func (s *Server) executeOnWorker(ctx context.Context, node *registry.Node, function *functions.Function, version *functions.FunctionVersion, req *cluster.FunctionRequest) (*cluster.FunctionResult, error) {
 	// Connect to worker's gRPC server
-	conn, err := grpc.Dial(node.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := cluster.Dial(node.Address)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to worker %s: %v", node.ID, err)
 	}
@@ -334,15 +721,9 @@ func (s *Server) executeOnWorker(node *registry.Node, function *functions.Functi
 	// Create gRPC client
 	client := cluster.NewClusterServiceClient(conn)
 
-	// Call ExecuteFunction
-	ctx := context.Background()
-	req := &cluster.FunctionRequest{
-		DockerImage:  function.Image,
-		FunctionName: function.Name,
-	}
-
-	logging.Info("Executing function '%s' with image '%s' on worker '%s'", 
-		function.Name, function.Image, node.ID)
+	// Call ExecuteFunction; the trace in ctx continues on the worker
+	logging.With(logging.KeyFunction, function.Name, logging.KeyNodeID, node.ID).
+		InfoContext(ctx, "Executing function on worker", "version", version.Version, "image", version.Image)
 
 	result, err := client.ExecuteFunction(ctx, req)
 	if err != nil {
@@ -351,3 +732,41 @@ func (s *Server) executeOnWorker(node *registry.Node, function *functions.Functi
 
 	return result, nil
 }
+
+// functionRequest builds the gRPC execution request for a version of a
+// function, including the registry login its image is pulled with. Logins are
+// only sent to workers over TLS, never in plaintext
+func (s *Server) functionRequest(function *functions.Function, version *functions.FunctionVersion, input []byte) (*cluster.FunctionRequest, error) {
+	auth, err := s.registryAuth(function)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil && !cluster.Secure() {
+		return nil, fmt.Errorf("registry credential '%s' is only sent to workers over TLS: set %s and %s", function.Settings.RegistryCredential, cluster.TLSCertVariable, cluster.TLSKeyVariable)
+	}
+	return &cluster.FunctionRequest{
+		DockerImage:     version.Image,
+		FunctionName:    function.Name,
+		TimeoutSeconds:  int32(function.Settings.TimeoutSeconds),
+		MemoryMb:        int32(function.Settings.MemoryMB),
+		Env:             function.Settings.Env,
+		MaxOutputBytes:  s.maxOutputBytes,
+		Kind:            function.Kind,
+		Args:            function.Settings.Args,
+		Input:           input,
+		PullPolicy:      function.Settings.PullPolicy,
+		RegistryAuth:    auth,
+		ImageDigest:     version.Digest,
+		VerifySignature: function.Kind == functions.KindContainer && function.Settings.VerifySignature,
+	}, nil
+}
+
+// unixMilliTime converts a Unix millisecond timestamp from a FunctionResult,
+// returning nil for unset (zero) timestamps
+func unixMilliTime(ms int64) *time.Time {
+	if ms == 0 {
+		return nil
+	}
+	t := time.UnixMilli(ms)
+	return &t
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFunctionPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string // {id} is replaced by the function's ID
+		body       string
+		wantStatus int
+	}{
+		{"get", http.MethodGet, "/functions/{id}", "", http.StatusOK},
+		{"get with trailing slash", http.MethodGet, "/functions/{id}/", "", http.StatusOK},
+		{"patch with trailing slash", http.MethodPatch, "/functions/{id}/", `{"description":"edited"}`, http.StatusOK},
+		{"delete with trailing slash", http.MethodDelete, "/functions/{id}/", "", http.StatusOK},
+		{"no ID", http.MethodGet, "/functions/", "", http.StatusBadRequest},
+		{"unknown resource", http.MethodGet, "/functions/{id}/bogus", "", http.StatusNotFound},
+		{"unknown function", http.MethodDelete, "/functions/missing", "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, _, function := startFunctionAPI(t)
+
+			var response FunctionResponse
+			path := api.URL + strings.Replace(tt.path, "{id}", function.ID, 1)
+			if status := send(t, tt.method, path, tt.body, &response); status != tt.wantStatus {
+				t.Errorf("%s %s: status %d, want %d (%s)", tt.method, tt.path, status, tt.wantStatus, response.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cares/internal/functions"
+	"cares/internal/logging"
+)
+
+// ScheduleRequest represents the JSON payload for replacing status windows
+type ScheduleRequest struct {
+	Windows []functions.StatusWindow `json:"windows"`
+}
+
+// handleFunctionStatus handles POST /functions/{id}/enable and /functions/{id}/disable
+func (s *Server) handleFunctionStatus(w http.ResponseWriter, r *http.Request, id, status string) {
+	if r.Method != "POST" {
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	function, err := s.registry.UpdateFunctionStatus(r.Context(), id, status)
+	if err != nil {
+		s.writeMutationError(w, err)
+		return
+	}
+
+	logging.Info("Function '%s' set to %s", function.Name, status)
+
+	response := FunctionResponse{
+		Status:   "success",
+		Message:  fmt.Sprintf("Function '%s' is now %s", function.Name, status),
+		Function: function,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleFunctionSchedule handles /functions/{id}/schedule endpoint
+func (s *Server) handleFunctionSchedule(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case "GET":
+		function, exists := s.registry.GetFunction(id)
+		if !exists {
+			s.writeError(w, http.StatusNotFound, "Function not found")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FunctionResponse{Status: "success", Function: function})
+	case "PUT":
+		var req ScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+
+		function, err := s.registry.SetStatusWindows(r.Context(), id, req.Windows)
+		if err != nil {
+			s.writeMutationError(w, err)
+			return
+		}
+
+		logging.Info("Updated status schedule of function '%s' (%d window(s))", function.Name, len(function.StatusWindows))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FunctionResponse{
+			Status:   "success",
+			Message:  "Status schedule updated",
+			Function: function,
+		})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// writeMutationError answers a request whose change to a function failed: 404
+// if the function does not exist, 400 if the change is invalid and 500 if it
+// could not be saved
+func (s *Server) writeMutationError(w http.ResponseWriter, err error) {
+	var validationErr *functions.ValidationError
+	switch {
+	case errors.Is(err, functions.ErrFunctionNotFound):
+		s.writeError(w, http.StatusNotFound, "Function not found")
+	case errors.As(err, &validationErr):
+		s.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// disabledMessage explains why a function cannot currently be invoked
+func disabledMessage(function *functions.Function, now time.Time) string {
+	if window := function.ActiveWindow(now); window != nil {
+		return fmt.Sprintf("Function '%s' is disabled by schedule until %s",
+			function.Name, window.End.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("Function '%s' is disabled", function.Name)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"cares/internal/functions"
+)
+
+// failingStore is a function store whose writes fail while it is broken.
+type failingStore struct {
+	functions.Store
+	broken atomic.Bool
+}
+
+func (s *failingStore) Put(fn *functions.Function) error {
+	if s.broken.Load() {
+		return errors.New("disk full")
+	}
+	return s.Store.Put(fn)
+}
+
+// startFunctionAPI starts an API server with a single function, "app", whose
+// store can be broken.
+func startFunctionAPI(t *testing.T) (*httptest.Server, *failingStore, *functions.Function) {
+	t.Helper()
+
+	store := &failingStore{Store: functions.NewFileStore(filepath.Join(t.TempDir(), "functions.json"))}
+	functionRegistry, err := functions.NewRegistry(store)
+	if err != nil {
+		t.Fatalf("function registry: %v", err)
+	}
+	function, err := functionRegistry.AddFunction(context.Background(), "app", "ghcr.io/acme/app:1", "")
+	if err != nil {
+		t.Fatalf("add function: %v", err)
+	}
+	api := httptest.NewServer(NewServer(functionRegistry).Handler())
+	t.Cleanup(api.Close)
+	return api, store, function
+}
+
+func TestFunctionStatusErrors(t *testing.T) {
+	const window = `{"windows":[{"status":"inactive","start":"2030-01-01T00:00:00Z","end":"2030-01-02T00:00:00Z"}]}`
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string // Under /functions/{id}, or /functions/missing if unknown
+		unknown    bool
+		body       string
+		broken     bool
+		wantStatus int
+	}{
+		{name: "disable", method: http.MethodPost, path: "/disable", wantStatus: http.StatusOK},
+		{name: "disable unknown", method: http.MethodPost, path: "/disable", unknown: true, wantStatus: http.StatusNotFound},
+		{name: "disable unsaved", method: http.MethodPost, path: "/disable", broken: true, wantStatus: http.StatusInternalServerError},
+		{name: "schedule", method: http.MethodPut, path: "/schedule", body: window, wantStatus: http.StatusOK},
+		{name: "schedule unknown", method: http.MethodPut, path: "/schedule", unknown: true, body: window, wantStatus: http.StatusNotFound},
+		{name: "schedule unsaved", method: http.MethodPut, path: "/schedule", body: window, broken: true, wantStatus: http.StatusInternalServerError},
+		{
+			name:       "schedule ending before start",
+			method:     http.MethodPut,
+			path:       "/schedule",
+			body:       `{"windows":[{"status":"inactive","start":"2030-01-02T00:00:00Z","end":"2030-01-01T00:00:00Z"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, store, function := startFunctionAPI(t)
+			store.broken.Store(tt.broken)
+			id := function.ID
+			if tt.unknown {
+				id = "missing"
+			}
+
+			var response FunctionResponse
+			status := send(t, tt.method, api.URL+"/functions/"+id+tt.path, tt.body, &response)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", status, tt.wantStatus, response.Message)
+			}
+			if status == http.StatusOK && (response.Function == nil || response.Function.Revision != function.Revision+1) {
+				t.Errorf("function = %+v, want the updated function", response.Function)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cares/internal/cluster"
+	"cares/internal/functions"
+	"cares/internal/invocations"
+	"cares/internal/logging"
+	"cares/internal/registry"
+)
+
+// Streaming modes for POST /invoke/{name}
+const (
+	streamSSE     = "sse"     // Server-Sent Events (text/event-stream)
+	streamChunked = "chunked" // Newline-delimited JSON over chunked transfer encoding
+)
+
+// StreamEvent is a single newline-delimited JSON message of a chunked invocation stream
+type StreamEvent struct {
+	Type            string `json:"type"`                       // "output" or "result"
+	Stream          string `json:"stream,omitempty"`           // "stdout" or "stderr" for output events
+	Data            string `json:"data,omitempty"`             // Output text for output events
+	Status          string `json:"status,omitempty"`           // "success" or "error" for result events
+	Error           string `json:"error,omitempty"`            // Failure reason for result events
+	Node            string `json:"node,omitempty"`             // Worker that ran the function
+	Version         int    `json:"version,omitempty"`          // Function version that ran
+	InvocationID    string `json:"invocation_id,omitempty"`    // ID of the invocation history record
+	TraceID         string `json:"trace_id,omitempty"`         // Trace of the invocation, for result events
+	OutputTruncated bool   `json:"output_truncated,omitempty"` // Output exceeded the cap and was cut off
+
+	// Execution details of result events, unset if the container never started
+	ExitCode      *int       `json:"exit_code,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	ImageDigest   string     `json:"image_digest,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"` // Why the run failed, e.g. "digest_mismatch"
+}
+
+// streamMode returns the requested streaming mode, or "" for a buffered response.
+// ?stream=sse or an Accept: text/event-stream header selects Server-Sent Events;
+// ?stream=chunked (or true) selects newline-delimited JSON.
+func streamMode(r *http.Request) string {
+	switch strings.ToLower(r.URL.Query().Get("stream")) {
+	case streamSSE:
+		return streamSSE
+	case streamChunked, "true", "1":
+		return streamChunked
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return streamSSE
+	}
+	return ""
+}
+
+// streamInvocation executes a function on a worker and relays its output to the
+// client as it is produced, finishing with a result event.
+func (s *Server) streamInvocation(w http.ResponseWriter, r *http.Request, mode string, node *registry.Node, function *functions.Function, version *functions.FunctionVersion, req *cluster.FunctionRequest, record *invocations.Record) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.recordInvocation(r.Context(), record, false, "", "streaming not supported by connection")
+		s.writeError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	conn, err := cluster.Dial(node.Address)
+	if err != nil {
+		s.recordInvocation(r.Context(), record, false, "", fmt.Sprintf("failed to connect to worker %s: %v", node.ID, err))
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Execution failed: failed to connect to worker %s: %v", node.ID, err))
+		return
+	}
+	defer conn.Close()
+
+	logging.With(logging.KeyFunction, function.Name, logging.KeyInvocationID, record.ID, logging.KeyNodeID, node.ID).
+		InfoContext(r.Context(), "Streaming function from worker", "version", version.Version, "image", version.Image)
+
+	// The worker stops the container when the client goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	started := time.Now()
+	client := cluster.NewClusterServiceClient(conn)
+	stream, err := client.ExecuteFunctionStream(ctx, req)
+	if err != nil {
+		s.recordStreamStats(function, version, false, started)
+		s.recordInvocation(r.Context(), record, false, "", fmt.Sprintf("gRPC call failed: %v", err))
+		s.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Execution failed: gRPC call failed: %v", err))
+		return
+	}
+
+	// From here on the response is committed; failures are reported as events
+	events := newEventWriter(w, mode)
+	events.begin()
+	flusher.Flush()
+
+	var output strings.Builder // Kept for the history record, which stores a prefix only
+	var result *cluster.FunctionResult
+	var streamErr error
+	for result == nil && streamErr == nil {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			streamErr = fmt.Errorf("worker closed the stream without a result")
+			break
+		}
+		if err != nil {
+			streamErr = fmt.Errorf("stream failed: %v", err)
+			break
+		}
+
+		switch e := event.Event.(type) {
+		case *cluster.ExecutionEvent_Output:
+			if output.Len() <= invocations.MaxStoredOutput {
+				output.Write(e.Output.Data)
+			}
+			events.output(e.Output.Stream, e.Output.Data)
+			flusher.Flush()
+		case *cluster.ExecutionEvent_Result:
+			result = e.Result
+		}
+	}
+
+	final := StreamEvent{
+		Type:         "result",
+		Status:       "success",
+		Node:         node.ID,
+		Version:      version.Version,
+		InvocationID: record.ID,
+		TraceID:      record.TraceID,
+	}
+	switch {
+	case streamErr != nil:
+		final.Status = "error"
+		final.Error = fmt.Sprintf("Execution failed: %v", streamErr)
+		s.recordStreamStats(function, version, false, started)
+		s.recordInvocation(r.Context(), record, false, output.String(), streamErr.Error())
+	default:
+		if !result.Success {
+			final.Status = "error"
+			final.Error = fmt.Sprintf("Function execution failed: %s", result.Error)
+		}
+		applyResult(record, result)
+		final.Node = record.NodeID
+		final.OutputTruncated = result.OutputTruncated
+		final.ExitCode = record.ExitCode
+		final.StartedAt = unixMilliTime(result.StartedAt)
+		final.FinishedAt = unixMilliTime(result.FinishedAt)
+		final.ImageDigest = result.ImageDigest
+		final.FailureReason = result.FailureReason
+		s.recordStreamStats(function, version, result.Success, started)
+		s.recordInvocation(r.Context(), record, result.Success, output.String(), result.Error)
+	}
+
+	events.result(final)
+	flusher.Flush()
+}
+
+// recordStreamStats records per-version statistics for a streamed invocation
+func (s *Server) recordStreamStats(function *functions.Function, version *functions.FunctionVersion, success bool, started time.Time) {
+	if err := s.registry.RecordInvocation(function.ID, version.Version, success, time.Since(started)); err != nil {
+		logging.Warn("Failed to record invocation stats for '%s': %v", function.Name, err)
+	}
+}
+
+// eventWriter formats invocation stream events for the selected streaming mode
+type eventWriter struct {
+	w    http.ResponseWriter
+	mode string
+}
+
+// newEventWriter creates an event writer for the given streaming mode
+func newEventWriter(w http.ResponseWriter, mode string) *eventWriter {
+	return &eventWriter{w: w, mode: mode}
+}
+
+// begin writes the response headers
+func (e *eventWriter) begin() {
+	if e.mode == streamSSE {
+		e.w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		e.w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	e.w.Header().Set("Cache-Control", "no-cache")
+	e.w.Header().Set("X-Accel-Buffering", "no") // Keep reverse proxies from buffering the stream
+	e.w.WriteHeader(http.StatusOK)
+}
+
+// output writes a chunk of container output
+func (e *eventWriter) output(stream string, data []byte) {
+	if e.mode != streamSSE {
+		json.NewEncoder(e.w).Encode(StreamEvent{Type: "output", Stream: stream, Data: string(data)})
+		return
+	}
+
+	// SSE data may not contain line breaks; each line becomes its own data field
+	// and clients rejoin them with "\n"
+	text := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(data))
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", stream)
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	io.WriteString(e.w, b.String())
+}
+
+// result writes the final result event
+func (e *eventWriter) result(event StreamEvent) {
+	if e.mode != streamSSE {
+		json.NewEncoder(e.w).Encode(event)
+		return
+	}
+
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(e.w, "event: result\ndata: %s\n\n", data)
+}
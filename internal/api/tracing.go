@@ -0,0 +1,78 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"cares/internal/tracing"
+)
+
+// TraceIDHeader is the response header carrying the ID of the request's trace
+const TraceIDHeader = "X-Trace-Id"
+
+// tracingMiddleware opens a server span for every request, continuing the
+// caller's trace if the request carries a traceparent header, and returns the
+// trace ID in TraceIDHeader
+func (s *Server) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if parent, ok := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); ok {
+			ctx = tracing.WithRemote(ctx, parent)
+		}
+		ctx, span := tracing.StartKind(ctx, r.Method+" "+routeOf(r.URL.Path), tracing.KindServer,
+			slog.String("http.method", r.Method), slog.String("http.target", r.URL.Path))
+		defer span.End()
+
+		w.Header().Set(TraceIDHeader, span.TraceID())
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(slog.Int("http.status_code", recorder.status))
+		if recorder.status >= 500 {
+			span.SetError(http.StatusText(recorder.status))
+		}
+	})
+}
+
+// routeOf returns the route of a path for span names, keeping IDs and
+// function names out of them: "/invoke/hello" becomes "/invoke/{name}"
+func routeOf(path string) string {
+	first, rest, nested := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !nested || rest == "" {
+		return "/" + first
+	}
+	param := "{id}"
+	if first == "invoke" {
+		param = "{name}"
+	}
+	if _, resource, ok := strings.Cut(rest, "/"); ok && resource != "" {
+		return "/" + first + "/" + param + "/" + resource
+	}
+	return "/" + first + "/" + param
+}
+
+// statusRecorder remembers the status code written through it. It passes
+// Flush on, so streamed invocations still reach the client as they happen.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush sends buffered data to the client, if the connection supports it
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
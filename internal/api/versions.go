@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cares/internal/distribution"
+	"cares/internal/functions"
+	"cares/internal/imageref"
+	"cares/internal/logging"
+)
+
+// VersionRequest represents the JSON payload for publishing a function version
+type VersionRequest struct {
+	Image string `json:"image"`
+}
+
+// TrafficRequest represents the JSON payload for updating a traffic split.
+// Weights are percentages keyed by version number, e.g. {"1": 90, "2": 10}.
+type TrafficRequest struct {
+	Weights map[int]int `json:"weights"`
+}
+
+// VersionResponse represents the JSON response for version and traffic operations
+type VersionResponse struct {
+	Status       string                      `json:"status"`
+	Message      string                      `json:"message,omitempty"`
+	Version      *functions.FunctionVersion  `json:"version,omitempty"`
+	Versions     []functions.FunctionVersion `json:"versions,omitempty"`
+	TrafficSplit map[int]int                 `json:"traffic_split"`
+	PinError     string                      `json:"pin_error,omitempty"` // Why the version could not be pinned to a digest
+}
+
+// digestResolveTimeout bounds how long publishing waits for the registry
+const digestResolveTimeout = 15 * time.Second
+
+// DigestResolver resolves image tags to the manifest digests they currently
+// point at. It is implemented by distribution.Client.
+type DigestResolver interface {
+	ResolveDigest(ctx context.Context, image string, creds *distribution.Credentials) (string, error)
+}
+
+// SetDigestResolver enables pinning container function versions to the
+// digest their tag resolves to when they are published
+func (s *Server) SetDigestResolver(resolver DigestResolver) {
+	s.digestResolver = resolver
+}
+
+// PinVersion pins a version of a container function to the manifest digest
+// its image currently points at and returns the updated function. Versions
+// that need no pinning are returned as they are. If the digest can't be
+// resolved, e.g. because the registry is unreachable, the version stays
+// unpinned and the error says why; workers then run whatever its tag points
+// at. Resolving outlives the cancellation of ctx, so a pin that was started
+// is not lost when an API client disconnects.
+func (s *Server) PinVersion(ctx context.Context, function *functions.Function, version int) (*functions.Function, error) {
+	v := function.FindVersion(version)
+	if s.digestResolver == nil || function.Kind != functions.KindContainer || v == nil || v.Digest != "" {
+		return function, nil
+	}
+
+	ref, err := imageref.Parse(v.Image)
+	if err != nil {
+		return function, err
+	}
+	auth, err := s.registryAuth(function)
+	if err != nil {
+		return function, err
+	}
+	var creds *distribution.Credentials
+	if auth != nil && imageref.NormalizeRegistry(auth.Server) == ref.Registry {
+		creds = &distribution.Credentials{Username: auth.Username, Password: auth.Password}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), digestResolveTimeout)
+	defer cancel()
+	digest, err := s.digestResolver.ResolveDigest(ctx, v.Image, creds)
+	if err != nil {
+		logging.Warn("Could not pin function '%s' v%d to a digest, it will run whatever '%s' points at: %v", function.Name, version, v.Image, err)
+		return function, fmt.Errorf("failed to resolve the digest of '%s': %w", v.Image, err)
+	}
+
+	pinned, err := s.registry.PinVersion(function.ID, version, digest)
+	if err != nil {
+		logging.Warn("Failed to pin function '%s' v%d: %v", function.Name, version, err)
+		return function, err
+	}
+	logging.Info("Pinned function '%s' v%d to %s", function.Name, version, digest)
+	return pinned, nil
+}
+
+// PrepareLatestVersion pins the latest version of a function to its digest
+// and asks workers to pre-pull it, as done when a function is registered or
+// its image changed through the API. It returns the updated function and, if
+// pinning failed, why.
+func (s *Server) PrepareLatestVersion(ctx context.Context, function *functions.Function) (*functions.Function, string) {
+	function, pinErr := s.pinLatest(ctx, function)
+	s.prePull(ctx, function, function.LatestVersion())
+	return function, pinErr
+}
+
+// pinLatest pins the latest version of a function for a request, returning
+// the updated function and, if pinning failed, why.
+func (s *Server) pinLatest(ctx context.Context, function *functions.Function) (*functions.Function, string) {
+	latest := function.LatestVersion()
+	if latest == nil {
+		return function, ""
+	}
+	function, err := s.PinVersion(ctx, function, latest.Version)
+	if err != nil {
+		return function, err.Error()
+	}
+	return function, ""
+}
+
+// handleFunctionVersions handles /functions/{id}/versions endpoint
+func (s *Server) handleFunctionVersions(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case "GET":
+		s.listVersions(w, r, id)
+	case "POST":
+		s.publishVersion(w, r, id)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleFunctionTraffic handles /functions/{id}/traffic endpoint
+func (s *Server) handleFunctionTraffic(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case "GET":
+		s.getTraffic(w, r, id)
+	case "PUT":
+		s.setTraffic(w, r, id)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listVersions handles GET /functions/{id}/versions
+func (s *Server) listVersions(w http.ResponseWriter, r *http.Request, id string) {
+	function, exists := s.registry.GetFunction(id)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Function not found")
+		return
+	}
+
+	response := VersionResponse{
+		Status:       "success",
+		Versions:     function.Versions,
+		TrafficSplit: effectiveSplit(function),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// publishVersion handles POST /functions/{id}/versions
+func (s *Server) publishVersion(w http.ResponseWriter, r *http.Request, id string) {
+	var req VersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if req.Image == "" {
+		s.writeError(w, http.StatusBadRequest, "Docker image is required")
+		return
+	}
+
+	if _, exists := s.registry.GetFunction(id); !exists {
+		s.writeError(w, http.StatusNotFound, "Function not found")
+		return
+	}
+
+	version, err := s.registry.PublishVersion(r.Context(), id, req.Image)
+	if err != nil {
+		var validationErr *functions.ValidationError
+		if errors.As(err, &validationErr) {
+			s.writeValidationError(w, validationErr)
+		} else if errors.Is(err, functions.ErrFunctionNotFound) {
+			s.writeError(w, http.StatusNotFound, "Function not found")
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Published version %d of function '%s' with image '%s'", version.Version, id, version.Image)
+	var pinErr error
+	if function, exists := s.registry.GetFunction(id); exists {
+		function, pinErr = s.PinVersion(r.Context(), function, version.Version)
+		if pinned := function.FindVersion(version.Version); pinned != nil {
+			version = pinned
+		}
+		s.prePull(r.Context(), function, version)
+	}
+
+	response := VersionResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Version %d published", version.Version),
+		Version: version,
+	}
+	if pinErr != nil {
+		response.Message += ", but not pinned to a digest"
+		response.PinError = pinErr.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTraffic handles GET /functions/{id}/traffic
+func (s *Server) getTraffic(w http.ResponseWriter, r *http.Request, id string) {
+	function, exists := s.registry.GetFunction(id)
+	if !exists {
+		s.writeError(w, http.StatusNotFound, "Function not found")
+		return
+	}
+
+	response := VersionResponse{
+		Status:       "success",
+		TrafficSplit: effectiveSplit(function),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// setTraffic handles PUT /functions/{id}/traffic
+func (s *Server) setTraffic(w http.ResponseWriter, r *http.Request, id string) {
+	var req TrafficRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	function, err := s.registry.SetTrafficSplit(r.Context(), id, req.Weights)
+	if err != nil {
+		s.writeMutationError(w, err)
+		return
+	}
+
+	logging.Info("Updated traffic split of function '%s': %v", function.Name, effectiveSplit(function))
+
+	response := VersionResponse{
+		Status:       "success",
+		Message:      "Traffic split updated",
+		TrafficSplit: effectiveSplit(function),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// effectiveSplit returns the routing table actually in use, expanding the
+// implicit default of sending everything to the latest version.
+func effectiveSplit(function *functions.Function) map[int]int {
+	split := make(map[int]int)
+	for _, version := range function.Versions {
+		if weight := function.TrafficWeight(version.Version); weight > 0 {
+			split[version.Version] = weight
+		}
+	}
+	return split
+}
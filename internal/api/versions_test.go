@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"cares/internal/distribution"
+	"cares/internal/functions"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// fakeResolver resolves every image to testDigest, or fails with err.
+type fakeResolver struct {
+	err error
+}
+
+func (f fakeResolver) ResolveDigest(ctx context.Context, image string, creds *distribution.Credentials) (string, error) {
+	return testDigest, f.err
+}
+
+// fakePuller records the images it is asked to pre-pull.
+type fakePuller struct {
+	mu     sync.Mutex
+	images []string
+}
+
+func (f *fakePuller) PrePullImage(ctx context.Context, image string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.images = append(f.images, image)
+	return 1
+}
+
+func TestPublishVersionPinsDigest(t *testing.T) {
+	tests := []struct {
+		name         string
+		resolveErr   error
+		wantDigest   string
+		wantPinError bool
+		wantPull     string
+	}{
+		{
+			name:       "resolved",
+			wantDigest: testDigest,
+			wantPull:   "ghcr.io/acme/app@" + testDigest,
+		},
+		{
+			name:         "registry unreachable",
+			resolveErr:   errors.New("connection refused"),
+			wantPinError: true,
+			wantPull:     "ghcr.io/acme/app:2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			functionRegistry, err := functions.NewRegistry(functions.NewFileStore(filepath.Join(t.TempDir(), "functions.json")))
+			if err != nil {
+				t.Fatalf("function registry: %v", err)
+			}
+			function, err := functionRegistry.AddFunction(context.Background(), "app", "ghcr.io/acme/app:1", "")
+			if err != nil {
+				t.Fatalf("add function: %v", err)
+			}
+
+			puller := &fakePuller{}
+			server := NewServer(functionRegistry)
+			server.SetDigestResolver(fakeResolver{err: tt.resolveErr})
+			server.SetImagePuller(puller)
+			api := httptest.NewServer(server.Handler())
+			defer api.Close()
+
+			var response VersionResponse
+			if status := post(t, api.URL+"/functions/"+function.ID+"/versions", `{"image":"ghcr.io/acme/app:2"}`, &response); status != http.StatusCreated {
+				t.Fatalf("publish: status %d: %s", status, response.Message)
+			}
+			if response.Version == nil || response.Version.Digest != tt.wantDigest {
+				t.Errorf("version = %+v, want digest %q", response.Version, tt.wantDigest)
+			}
+			if (response.PinError != "") != tt.wantPinError {
+				t.Errorf("pin_error = %q, want error: %v", response.PinError, tt.wantPinError)
+			}
+			if len(puller.images) != 1 || puller.images[0] != tt.wantPull {
+				t.Errorf("pre-pulled %v, want [%s]", puller.images, tt.wantPull)
+			}
+		})
+	}
+}
+
+func TestPatchImagePinsDigest(t *testing.T) {
+	functionRegistry, err := functions.NewRegistry(functions.NewFileStore(filepath.Join(t.TempDir(), "functions.json")))
+	if err != nil {
+		t.Fatalf("function registry: %v", err)
+	}
+	function, err := functionRegistry.AddFunction(context.Background(), "app", "ghcr.io/acme/app:1", "")
+	if err != nil {
+		t.Fatalf("add function: %v", err)
+	}
+
+	puller := &fakePuller{}
+	server := NewServer(functionRegistry)
+	server.SetDigestResolver(fakeResolver{})
+	server.SetImagePuller(puller)
+	api := httptest.NewServer(server.Handler())
+	defer api.Close()
+
+	var response FunctionResponse
+	if status := send(t, http.MethodPatch, api.URL+"/functions/"+function.ID, `{"image":"ghcr.io/acme/app:2"}`, &response); status != http.StatusOK {
+		t.Fatalf("patch: status %d: %s", status, response.Message)
+	}
+	if latest := response.Function.LatestVersion(); latest.Version != 2 || latest.Digest != testDigest {
+		t.Errorf("latest version = %+v, want v2 pinned to %s", latest, testDigest)
+	}
+	if want := "ghcr.io/acme/app@" + testDigest; len(puller.images) != 1 || puller.images[0] != want {
+		t.Errorf("pre-pulled %v, want [%s]", puller.images, want)
+	}
+}
+
+func TestSetTrafficErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		unknown    bool
+		body       string
+		broken     bool
+		wantStatus int
+	}{
+		{name: "all to v1", body: `{"weights":{"1":100}}`, wantStatus: http.StatusOK},
+		{name: "unknown function", unknown: true, body: `{"weights":{"1":100}}`, wantStatus: http.StatusNotFound},
+		{name: "unknown version", body: `{"weights":{"7":100}}`, wantStatus: http.StatusBadRequest},
+		{name: "not adding up", body: `{"weights":{"1":60}}`, wantStatus: http.StatusBadRequest},
+		{name: "unsaved", body: `{"weights":{"1":100}}`, broken: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, store, function := startFunctionAPI(t)
+			store.broken.Store(tt.broken)
+			id := function.ID
+			if tt.unknown {
+				id = "missing"
+			}
+
+			var response VersionResponse
+			if status := send(t, http.MethodPut, api.URL+"/functions/"+id+"/traffic", tt.body, &response); status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", status, tt.wantStatus, response.Message)
+			}
+			if tt.wantStatus == http.StatusOK && response.TrafficSplit[1] != 100 {
+				t.Errorf("traffic split = %v, want all to v1", response.TrafficSplit)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cares/internal/events"
+	"cares/internal/logging"
+	"cares/internal/webhooks"
+)
+
+// Pagination limits for GET /webhooks/{id}/dead-letters
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+// WebhookRequest represents the JSON payload for creating a webhook subscription
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Types       []string `json:"types"`            // Event types or categories, e.g. "node.disconnected" or "invocation"
+	Secret      string   `json:"secret,omitempty"` // Signing secret; generated if omitted
+	Description string   `json:"description,omitempty"`
+}
+
+// WebhookResponse represents the JSON response for webhook operations.
+// Secrets are only returned once, when the subscription is created.
+type WebhookResponse struct {
+	Status   string                  `json:"status"`
+	Message  string                  `json:"message,omitempty"`
+	Webhook  *webhooks.Subscription  `json:"webhook,omitempty"`
+	Webhooks []webhooks.Subscription `json:"webhooks,omitempty"`
+	Secret   string                  `json:"secret,omitempty"`
+}
+
+// WebhookTestResponse represents the JSON response of POST /webhooks/{id}/test
+type WebhookTestResponse struct {
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	StatusCode int    `json:"status_code,omitempty"` // Answer of the endpoint, unset if it was unreachable
+}
+
+// DeadLettersResponse represents a page of failed webhook deliveries
+type DeadLettersResponse struct {
+	Status      string                `json:"status"`
+	DeadLetters []webhooks.DeadLetter `json:"dead_letters"`
+	Total       int                   `json:"total"`                 // Matching dead letters across all pages
+	NextOffset  *int                  `json:"next_offset,omitempty"` // Offset of the next page, if any
+}
+
+// SetWebhooks sets the dispatcher whose subscriptions are managed at /webhooks
+func (s *Server) SetWebhooks(dispatcher *webhooks.Dispatcher) {
+	s.webhooks = dispatcher
+}
+
+// handleWebhooks handles /webhooks endpoint
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Webhooks are not enabled")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(WebhookResponse{Status: "success", Webhooks: s.webhooks.Store().List()})
+	case "POST":
+		s.createWebhook(w, r)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// createWebhook handles POST /webhooks. Alerting subscriptions should ask for
+// "function.failing" and "function.recovered" rather than "invocation.failed".
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	types := make([]events.Type, len(req.Types))
+	for i, t := range req.Types {
+		types[i] = events.Type(strings.TrimSpace(t))
+	}
+	webhook, err := s.webhooks.Store().Add(webhooks.Subscription{
+		URL:         req.URL,
+		Types:       types,
+		Secret:      req.Secret,
+		Description: req.Description,
+	})
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalid) {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Added webhook %s to '%s' for %v", webhook.ID, webhook.URL, webhook.Types)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(WebhookResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Webhook to '%s' created", webhook.URL),
+		Webhook: webhook,
+		Secret:  webhook.Secret,
+	})
+}
+
+// handleWebhookByID handles requests under /webhooks/{id}
+func (s *Server) handleWebhookByID(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Webhooks are not enabled")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks/"), "/"), "/")
+	if parts[0] == "" || len(parts) > 2 {
+		s.writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	id := parts[0]
+
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		s.getWebhook(w, id)
+	case len(parts) == 1 && r.Method == "DELETE":
+		s.deleteWebhook(w, id)
+	case len(parts) == 2 && parts[1] == "test" && r.Method == "POST":
+		s.testWebhook(w, r, id)
+	case len(parts) == 2 && parts[1] == "dead-letters" && r.Method == "GET":
+		s.listDeadLetters(w, r, id)
+	case len(parts) == 1 || parts[1] == "test" || parts[1] == "dead-letters":
+		s.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	default:
+		s.writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// getWebhook handles GET /webhooks/{id}
+func (s *Server) getWebhook(w http.ResponseWriter, id string) {
+	webhook, err := s.webhooks.Store().Get(id)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	webhook.Secret = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookResponse{Status: "success", Webhook: webhook})
+}
+
+// deleteWebhook handles DELETE /webhooks/{id}. Its dead letters are kept.
+func (s *Server) deleteWebhook(w http.ResponseWriter, id string) {
+	if err := s.webhooks.Store().Remove(id); err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			s.writeError(w, http.StatusNotFound, "Webhook not found")
+		} else {
+			s.writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	logging.Info("Removed webhook %s", id)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Webhook %s removed", id),
+	})
+}
+
+// testWebhook handles POST /webhooks/{id}/test, sending a signed ping event
+// to the webhook's URL once and reporting how the endpoint answered
+func (s *Server) testWebhook(w http.ResponseWriter, r *http.Request, id string) {
+	webhook, err := s.webhooks.Store().Get(id)
+	if err != nil {
+		s.writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	status, err := s.webhooks.Ping(r.Context(), *webhook)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(WebhookTestResponse{
+			Status:     "error",
+			Message:    fmt.Sprintf("Test delivery failed: %v", err),
+			StatusCode: status,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookTestResponse{
+		Status:     "success",
+		Message:    fmt.Sprintf("Test event delivered to '%s'", webhook.URL),
+		StatusCode: status,
+	})
+}
+
+// listDeadLetters handles GET /webhooks/{id}/dead-letters?limit=&offset=,
+// the deliveries to the webhook that were given up on, newest first
+func (s *Server) listDeadLetters(w http.ResponseWriter, r *http.Request, id string) {
+	deadLetters := s.webhooks.DeadLetters()
+	if deadLetters == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "Dead letters are not recorded")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultDeadLetterLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxDeadLetterLimit {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxDeadLetterLimit))
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			s.writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	letters, total := deadLetters.Query(id, limit, offset)
+	response := DeadLettersResponse{
+		Status:      "success",
+		DeadLetters: letters,
+		Total:       total,
+	}
+	if next := offset + len(letters); next < total {
+		response.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
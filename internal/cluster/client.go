@@ -3,13 +3,16 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 
+	"cares/internal/executor"
+	"cares/internal/logging"
 	"cares/internal/metrics"
 )
 
@@ -21,6 +24,8 @@ type Client struct {
 	address     string
 	hostname    string
 	isConnected bool
+	loggedSeq   uint64      // Last log entry relayed to the orchestrator over heartbeats
+	logsShipped atomic.Bool // Logs are sent with ShipLogs, so heartbeats don't relay them
 }
 
 // NewClient creates a new gRPC client instance.
@@ -34,7 +39,7 @@ func NewClient(hostname string) *Client {
 // Connect establishes a connection to the orchestrator at the given address.
 func (c *Client) Connect(orchestratorAddr string) error {
 	// Establish gRPC connection
-	conn, err := grpc.Dial(orchestratorAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := Dial(orchestratorAddr)
 	if err != nil {
 		return fmt.Errorf("failed to connect to orchestrator: %v", err)
 	}
@@ -89,12 +94,12 @@ func (c *Client) StartHeartbeat(ctx context.Context) error {
 				return
 			}
 			
-			// TODO: Handle commands in Phase 03
-			_ = cmd // Suppress unused variable warning
+			c.handleCommand(ctx, cmd)
 		}
 	}()
 
 	// Send heartbeat messages every 2 seconds
+	sampler := metrics.Default()
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
@@ -103,28 +108,89 @@ func (c *Client) StartHeartbeat(ctx context.Context) error {
 		case <-ctx.Done():
 			return stream.CloseSend()
 		case <-ticker.C:
-			// Collect current metrics
-			cpu, err1 := metrics.GetCPUUsage()
-			memory, err2 := metrics.GetMemoryUsage()
+			// Runtime health comes from the background monitor, never a live probe
+			health := executor.RuntimeHealthStatus()
+			sampler.SetDiskPath(health.RootDir)
 
+			// Metrics come from the shared sampler, so the heartbeat never
+			// waits for a measurement
+			sample, ok := sampler.Latest()
 			status := "active"
-			if err1 != nil || err2 != nil {
+			if !ok || sample.Err != nil {
 				status = "error"
 			}
+			stats := sample.Node
 
 			// Send metrics to orchestrator
 			metricsMsg := &NodeMetrics{
 				NodeId:      c.nodeID,
-				CpuUsage:    cpu,
-				MemoryUsage: memory,
+				CpuUsage:    sample.CPUUsage,
+				MemoryUsage: sample.MemoryUsage,
 				Timestamp:   time.Now().Unix(),
 				Status:      status,
+				Runtime: &RuntimeStatus{
+					Name:      health.Runtime,
+					Version:   health.Version,
+					Available: health.Available,
+					Error:     health.Error,
+				},
+				Images: executor.CachedImages(),
+				Resources: &NodeResources{
+					CpuCores:          int32(stats.CPUCores),
+					MemoryTotal:       stats.MemoryTotal,
+					MemoryAvailable:   stats.MemoryAvailable,
+					DiskPath:          stats.DiskPath,
+					DiskTotal:         stats.DiskTotal,
+					DiskFree:          stats.DiskFree,
+					NetworkRxRate:     stats.NetworkRxRate,
+					NetworkTxRate:     stats.NetworkTxRate,
+					Load1:             stats.Load1,
+					Load5:             stats.Load5,
+					Load15:            stats.Load15,
+					RunningContainers: int32(health.RunningContainers),
+					UptimeSeconds:     int64(stats.Uptime.Seconds()),
+				},
+			}
+
+			// Relay what was logged since the previous heartbeat, unless it is shipped
+			loggedSeq := c.loggedSeq
+			if !c.logsShipped.Load() {
+				metricsMsg.Logs, loggedSeq = pendingLogs(c.loggedSeq)
 			}
 
 			if err := stream.Send(metricsMsg); err != nil {
+				countGRPCError(sideClient, ClusterService_Heartbeat_FullMethodName, err)
 				return err
 			}
+			c.loggedSeq = loggedSeq
+		}
+	}
+}
+
+// handleCommand executes a command sent by the orchestrator. Long-running
+// commands run in the background so heartbeats are never held up.
+func (c *Client) handleCommand(ctx context.Context, cmd *OrchestratorCommand) {
+	switch cmd.CommandType {
+	case CommandPullImage:
+		var payload PullImagePayload
+		if err := json.Unmarshal([]byte(cmd.Payload), &payload); err != nil {
+			logging.Warn("Ignoring malformed pull command: %v", err)
+			return
+		}
+		go func() {
+			if err := executor.PrePullImage(ctx, payload.Image, nil); err != nil {
+				logging.Warn("%v", err)
+			}
+		}()
+	case CommandSetLogLevel:
+		level, err := logging.ParseLevel(cmd.Payload)
+		if err != nil {
+			logging.Warn("Ignoring log level command: %v", err)
+			return
 		}
+		logging.SetLevel(level)
+	default:
+		logging.Warn("Ignoring unknown orchestrator command '%s'", cmd.CommandType)
 	}
 }
 
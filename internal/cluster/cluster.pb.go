@@ -98,6 +98,10 @@ type NodeMetrics struct {
 	MemoryUsage   float64                `protobuf:"fixed64,3,opt,name=memory_usage,json=memoryUsage,proto3" json:"memory_usage,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Runtime       *RuntimeStatus         `protobuf:"bytes,6,opt,name=runtime,proto3" json:"runtime,omitempty"`     // Container runtime health
+	Images        []string               `protobuf:"bytes,7,rep,name=images,proto3" json:"images,omitempty"`       // Images cached on the worker, as "repository:tag"
+	Resources     *NodeResources         `protobuf:"bytes,8,opt,name=resources,proto3" json:"resources,omitempty"` // Capacity and load of the worker machine
+	Logs          []*LogEntry            `protobuf:"bytes,9,rep,name=logs,proto3" json:"logs,omitempty"`           // Entries logged since the previous heartbeat, sent while ShipLogs isn't in use
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -167,6 +171,481 @@ func (x *NodeMetrics) GetStatus() string {
 	return ""
 }
 
+func (x *NodeMetrics) GetRuntime() *RuntimeStatus {
+	if x != nil {
+		return x.Runtime
+	}
+	return nil
+}
+
+func (x *NodeMetrics) GetImages() []string {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *NodeMetrics) GetResources() *NodeResources {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *NodeMetrics) GetLogs() []*LogEntry {
+	if x != nil {
+		return x.Logs
+	}
+	return nil
+}
+
+// LogEntry is a structured log record of a worker.
+type LogEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          int64                  `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`  // Unix time in nanoseconds
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"` // "debug", "info", "warn" or "error"
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"` // file:line of the logging call
+	Fields        []*LogField            `protobuf:"bytes,5,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogEntry) Reset() {
+	*x = LogEntry{}
+	mi := &file_cluster_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogEntry) ProtoMessage() {}
+
+func (x *LogEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogEntry.ProtoReflect.Descriptor instead.
+func (*LogEntry) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LogEntry) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *LogEntry) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogEntry) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *LogEntry) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *LogEntry) GetFields() []*LogField {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// LogField is a key and value of a LogEntry.
+type LogField struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogField) Reset() {
+	*x = LogField{}
+	mi := &file_cluster_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogField) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogField) ProtoMessage() {}
+
+func (x *LogField) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogField.ProtoReflect.Descriptor instead.
+func (*LogField) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LogField) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *LogField) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// LogBatch is a batch of log records shipped by a worker.
+type LogBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Entries       []*LogEntry            `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	Dropped       int64                  `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"` // Entries discarded since the previous batch because the worker's queue was full
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogBatch) Reset() {
+	*x = LogBatch{}
+	mi := &file_cluster_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogBatch) ProtoMessage() {}
+
+func (x *LogBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogBatch.ProtoReflect.Descriptor instead.
+func (*LogBatch) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LogBatch) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *LogBatch) GetEntries() []*LogEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *LogBatch) GetDropped() int64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+// LogAck closes a ShipLogs stream.
+type LogAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Received      int64                  `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"` // Entries received over the stream
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogAck) Reset() {
+	*x = LogAck{}
+	mi := &file_cluster_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogAck) ProtoMessage() {}
+
+func (x *LogAck) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogAck.ProtoReflect.Descriptor instead.
+func (*LogAck) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *LogAck) GetReceived() int64 {
+	if x != nil {
+		return x.Received
+	}
+	return 0
+}
+
+// NodeResources reports the capacity and load of a worker machine.
+// Sizes are in bytes, rates in bytes per second.
+type NodeResources struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CpuCores          int32                  `protobuf:"varint,1,opt,name=cpu_cores,json=cpuCores,proto3" json:"cpu_cores,omitempty"`
+	MemoryTotal       uint64                 `protobuf:"varint,2,opt,name=memory_total,json=memoryTotal,proto3" json:"memory_total,omitempty"`
+	MemoryAvailable   uint64                 `protobuf:"varint,3,opt,name=memory_available,json=memoryAvailable,proto3" json:"memory_available,omitempty"`
+	DiskPath          string                 `protobuf:"bytes,4,opt,name=disk_path,json=diskPath,proto3" json:"disk_path,omitempty"` // Filesystem measured: the container engine's data root if local
+	DiskTotal         uint64                 `protobuf:"varint,5,opt,name=disk_total,json=diskTotal,proto3" json:"disk_total,omitempty"`
+	DiskFree          uint64                 `protobuf:"varint,6,opt,name=disk_free,json=diskFree,proto3" json:"disk_free,omitempty"`
+	NetworkRxRate     float64                `protobuf:"fixed64,7,opt,name=network_rx_rate,json=networkRxRate,proto3" json:"network_rx_rate,omitempty"`
+	NetworkTxRate     float64                `protobuf:"fixed64,8,opt,name=network_tx_rate,json=networkTxRate,proto3" json:"network_tx_rate,omitempty"`
+	Load1             float64                `protobuf:"fixed64,9,opt,name=load1,proto3" json:"load1,omitempty"` // Load averages over 1, 5 and 15 minutes
+	Load5             float64                `protobuf:"fixed64,10,opt,name=load5,proto3" json:"load5,omitempty"`
+	Load15            float64                `protobuf:"fixed64,11,opt,name=load15,proto3" json:"load15,omitempty"`
+	RunningContainers int32                  `protobuf:"varint,12,opt,name=running_containers,json=runningContainers,proto3" json:"running_containers,omitempty"`
+	UptimeSeconds     int64                  `protobuf:"varint,13,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *NodeResources) Reset() {
+	*x = NodeResources{}
+	mi := &file_cluster_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeResources) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeResources) ProtoMessage() {}
+
+func (x *NodeResources) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeResources.ProtoReflect.Descriptor instead.
+func (*NodeResources) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *NodeResources) GetCpuCores() int32 {
+	if x != nil {
+		return x.CpuCores
+	}
+	return 0
+}
+
+func (x *NodeResources) GetMemoryTotal() uint64 {
+	if x != nil {
+		return x.MemoryTotal
+	}
+	return 0
+}
+
+func (x *NodeResources) GetMemoryAvailable() uint64 {
+	if x != nil {
+		return x.MemoryAvailable
+	}
+	return 0
+}
+
+func (x *NodeResources) GetDiskPath() string {
+	if x != nil {
+		return x.DiskPath
+	}
+	return ""
+}
+
+func (x *NodeResources) GetDiskTotal() uint64 {
+	if x != nil {
+		return x.DiskTotal
+	}
+	return 0
+}
+
+func (x *NodeResources) GetDiskFree() uint64 {
+	if x != nil {
+		return x.DiskFree
+	}
+	return 0
+}
+
+func (x *NodeResources) GetNetworkRxRate() float64 {
+	if x != nil {
+		return x.NetworkRxRate
+	}
+	return 0
+}
+
+func (x *NodeResources) GetNetworkTxRate() float64 {
+	if x != nil {
+		return x.NetworkTxRate
+	}
+	return 0
+}
+
+func (x *NodeResources) GetLoad1() float64 {
+	if x != nil {
+		return x.Load1
+	}
+	return 0
+}
+
+func (x *NodeResources) GetLoad5() float64 {
+	if x != nil {
+		return x.Load5
+	}
+	return 0
+}
+
+func (x *NodeResources) GetLoad15() float64 {
+	if x != nil {
+		return x.Load15
+	}
+	return 0
+}
+
+func (x *NodeResources) GetRunningContainers() int32 {
+	if x != nil {
+		return x.RunningContainers
+	}
+	return 0
+}
+
+func (x *NodeResources) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+// RuntimeStatus reports the health of a worker's container runtime
+type RuntimeStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Available     bool                   `protobuf:"varint,3,opt,name=available,proto3" json:"available,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuntimeStatus) Reset() {
+	*x = RuntimeStatus{}
+	mi := &file_cluster_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuntimeStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuntimeStatus) ProtoMessage() {}
+
+func (x *RuntimeStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuntimeStatus.ProtoReflect.Descriptor instead.
+func (*RuntimeStatus) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RuntimeStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RuntimeStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RuntimeStatus) GetAvailable() bool {
+	if x != nil {
+		return x.Available
+	}
+	return false
+}
+
+func (x *RuntimeStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 // Acknowledgement confirms successful operations
 type Acknowledgement struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -179,7 +658,7 @@ type Acknowledgement struct {
 
 func (x *Acknowledgement) Reset() {
 	*x = Acknowledgement{}
-	mi := &file_cluster_proto_msgTypes[2]
+	mi := &file_cluster_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -191,7 +670,7 @@ func (x *Acknowledgement) String() string {
 func (*Acknowledgement) ProtoMessage() {}
 
 func (x *Acknowledgement) ProtoReflect() protoreflect.Message {
-	mi := &file_cluster_proto_msgTypes[2]
+	mi := &file_cluster_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,7 +683,7 @@ func (x *Acknowledgement) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Acknowledgement.ProtoReflect.Descriptor instead.
 func (*Acknowledgement) Descriptor() ([]byte, []int) {
-	return file_cluster_proto_rawDescGZIP(), []int{2}
+	return file_cluster_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *Acknowledgement) GetSuccess() bool {
@@ -228,7 +707,8 @@ func (x *Acknowledgement) GetClusterId() string {
 	return ""
 }
 
-// OrchestratorCommand represents commands sent from orchestrator to workers
+// OrchestratorCommand represents commands sent from orchestrator to workers.
+// "pull_image" asks a worker to pre-pull the image named in the payload.
 type OrchestratorCommand struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CommandType   string                 `protobuf:"bytes,1,opt,name=command_type,json=commandType,proto3" json:"command_type,omitempty"`
@@ -240,7 +720,7 @@ type OrchestratorCommand struct {
 
 func (x *OrchestratorCommand) Reset() {
 	*x = OrchestratorCommand{}
-	mi := &file_cluster_proto_msgTypes[3]
+	mi := &file_cluster_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -252,7 +732,7 @@ func (x *OrchestratorCommand) String() string {
 func (*OrchestratorCommand) ProtoMessage() {}
 
 func (x *OrchestratorCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_cluster_proto_msgTypes[3]
+	mi := &file_cluster_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -265,7 +745,7 @@ func (x *OrchestratorCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OrchestratorCommand.ProtoReflect.Descriptor instead.
 func (*OrchestratorCommand) Descriptor() ([]byte, []int) {
-	return file_cluster_proto_rawDescGZIP(), []int{3}
+	return file_cluster_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *OrchestratorCommand) GetCommandType() string {
@@ -289,18 +769,30 @@ func (x *OrchestratorCommand) GetTimestamp() int64 {
 	return 0
 }
 
-// FunctionRequest contains the Docker image to execute on a worker
+// FunctionRequest contains the Docker image or executable to run on a worker
 type FunctionRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	DockerImage   string                 `protobuf:"bytes,1,opt,name=docker_image,json=dockerImage,proto3" json:"docker_image,omitempty"`
-	FunctionName  string                 `protobuf:"bytes,2,opt,name=function_name,json=functionName,proto3" json:"function_name,omitempty"` // For logging purposes
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DockerImage     string                 `protobuf:"bytes,1,opt,name=docker_image,json=dockerImage,proto3" json:"docker_image,omitempty"`           // Executable path or wasm module for other kinds
+	FunctionName    string                 `protobuf:"bytes,2,opt,name=function_name,json=functionName,proto3" json:"function_name,omitempty"`        // For logging purposes
+	TimeoutSeconds  int32                  `protobuf:"varint,3,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"` // 0 uses the worker default
+	MemoryMb        int32                  `protobuf:"varint,4,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`                   // 0 means no memory limit
+	Env             map[string]string      `protobuf:"bytes,5,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	MaxOutputBytes  int64                  `protobuf:"varint,6,opt,name=max_output_bytes,json=maxOutputBytes,proto3" json:"max_output_bytes,omitempty"`   // 0 uses the worker default
+	Kind            string                 `protobuf:"bytes,7,opt,name=kind,proto3" json:"kind,omitempty"`                                                // "container" (default if empty), "process" or "wasm"
+	Args            []string               `protobuf:"bytes,8,rep,name=args,proto3" json:"args,omitempty"`                                                // Command-line arguments of process and wasm functions
+	Input           []byte                 `protobuf:"bytes,9,opt,name=input,proto3" json:"input,omitempty"`                                              // Invocation input, passed on stdin (process and wasm functions)
+	PullPolicy      string                 `protobuf:"bytes,10,opt,name=pull_policy,json=pullPolicy,proto3" json:"pull_policy,omitempty"`                 // "Always", "IfNotPresent" (default if empty) or "Never"
+	RegistryAuth    *RegistryAuth          `protobuf:"bytes,11,opt,name=registry_auth,json=registryAuth,proto3" json:"registry_auth,omitempty"`           // Login for a private registry, used only for the pull
+	ImageDigest     string                 `protobuf:"bytes,12,opt,name=image_digest,json=imageDigest,proto3" json:"image_digest,omitempty"`              // Manifest digest the image is pinned to; workers refuse other content
+	VerifySignature bool                   `protobuf:"varint,13,opt,name=verify_signature,json=verifySignature,proto3" json:"verify_signature,omitempty"` // Require a signature by a key the worker trusts
+	InvocationId    string                 `protobuf:"bytes,14,opt,name=invocation_id,json=invocationId,proto3" json:"invocation_id,omitempty"`           // Invocation the run belongs to, tagged on the worker's logs
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *FunctionRequest) Reset() {
 	*x = FunctionRequest{}
-	mi := &file_cluster_proto_msgTypes[4]
+	mi := &file_cluster_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -312,7 +804,7 @@ func (x *FunctionRequest) String() string {
 func (*FunctionRequest) ProtoMessage() {}
 
 func (x *FunctionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_cluster_proto_msgTypes[4]
+	mi := &file_cluster_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -325,7 +817,7 @@ func (x *FunctionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FunctionRequest.ProtoReflect.Descriptor instead.
 func (*FunctionRequest) Descriptor() ([]byte, []int) {
-	return file_cluster_proto_rawDescGZIP(), []int{4}
+	return file_cluster_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *FunctionRequest) GetDockerImage() string {
@@ -342,19 +834,176 @@ func (x *FunctionRequest) GetFunctionName() string {
 	return ""
 }
 
-// FunctionResult contains the result of function execution
-type FunctionResult struct {
+func (x *FunctionRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *FunctionRequest) GetMemoryMb() int32 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *FunctionRequest) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *FunctionRequest) GetMaxOutputBytes() int64 {
+	if x != nil {
+		return x.MaxOutputBytes
+	}
+	return 0
+}
+
+func (x *FunctionRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *FunctionRequest) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *FunctionRequest) GetInput() []byte {
+	if x != nil {
+		return x.Input
+	}
+	return nil
+}
+
+func (x *FunctionRequest) GetPullPolicy() string {
+	if x != nil {
+		return x.PullPolicy
+	}
+	return ""
+}
+
+func (x *FunctionRequest) GetRegistryAuth() *RegistryAuth {
+	if x != nil {
+		return x.RegistryAuth
+	}
+	return nil
+}
+
+func (x *FunctionRequest) GetImageDigest() string {
+	if x != nil {
+		return x.ImageDigest
+	}
+	return ""
+}
+
+func (x *FunctionRequest) GetVerifySignature() bool {
+	if x != nil {
+		return x.VerifySignature
+	}
+	return false
+}
+
+func (x *FunctionRequest) GetInvocationId() string {
+	if x != nil {
+		return x.InvocationId
+	}
+	return ""
+}
+
+// RegistryAuth carries private registry credentials to the worker that pulls
+// an image. They are only sent in the execution request to the node the
+// scheduler selected, never in heartbeat commands, and workers never log or
+// store them.
+type RegistryAuth struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Output        string                 `protobuf:"bytes,1,opt,name=output,proto3" json:"output,omitempty"`
-	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Server        string                 `protobuf:"bytes,1,opt,name=server,proto3" json:"server,omitempty"` // Registry host the credentials belong to
+	Username      string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Password      string                 `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
+func (x *RegistryAuth) Reset() {
+	*x = RegistryAuth{}
+	mi := &file_cluster_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistryAuth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistryAuth) ProtoMessage() {}
+
+func (x *RegistryAuth) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistryAuth.ProtoReflect.Descriptor instead.
+func (*RegistryAuth) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RegistryAuth) GetServer() string {
+	if x != nil {
+		return x.Server
+	}
+	return ""
+}
+
+func (x *RegistryAuth) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *RegistryAuth) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+// FunctionResult contains the result of function execution
+type FunctionResult struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Output          string                 `protobuf:"bytes,1,opt,name=output,proto3" json:"output,omitempty"` // Combined stdout and stderr
+	Success         bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Error           string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	OutputTruncated bool                   `protobuf:"varint,4,opt,name=output_truncated,json=outputTruncated,proto3" json:"output_truncated,omitempty"` // Output exceeded max_output_bytes and was cut off
+	Stdout          string                 `protobuf:"bytes,5,opt,name=stdout,proto3" json:"stdout,omitempty"`
+	Stderr          string                 `protobuf:"bytes,6,opt,name=stderr,proto3" json:"stderr,omitempty"`
+	ExitCode        int32                  `protobuf:"varint,7,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`                // -1 if the container did not exit normally or never started
+	StartedAt       int64                  `protobuf:"varint,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`             // Unix time in milliseconds, 0 if never started
+	FinishedAt      int64                  `protobuf:"varint,9,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`          // Unix time in milliseconds, 0 if never started
+	ImageDigest     string                 `protobuf:"bytes,10,opt,name=image_digest,json=imageDigest,proto3" json:"image_digest,omitempty"`       // Digest of the image that actually ran
+	NodeId          string                 `protobuf:"bytes,11,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`                      // Worker node that executed the function
+	FailureReason   string                 `protobuf:"bytes,12,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"` // Why a failed run failed, see the Failure* constants
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
 func (x *FunctionResult) Reset() {
 	*x = FunctionResult{}
-	mi := &file_cluster_proto_msgTypes[5]
+	mi := &file_cluster_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -366,7 +1015,7 @@ func (x *FunctionResult) String() string {
 func (*FunctionResult) ProtoMessage() {}
 
 func (x *FunctionResult) ProtoReflect() protoreflect.Message {
-	mi := &file_cluster_proto_msgTypes[5]
+	mi := &file_cluster_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -379,7 +1028,7 @@ func (x *FunctionResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FunctionResult.ProtoReflect.Descriptor instead.
 func (*FunctionResult) Descriptor() ([]byte, []int) {
-	return file_cluster_proto_rawDescGZIP(), []int{5}
+	return file_cluster_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *FunctionResult) GetOutput() string {
@@ -403,6 +1052,206 @@ func (x *FunctionResult) GetError() string {
 	return ""
 }
 
+func (x *FunctionResult) GetOutputTruncated() bool {
+	if x != nil {
+		return x.OutputTruncated
+	}
+	return false
+}
+
+func (x *FunctionResult) GetStdout() string {
+	if x != nil {
+		return x.Stdout
+	}
+	return ""
+}
+
+func (x *FunctionResult) GetStderr() string {
+	if x != nil {
+		return x.Stderr
+	}
+	return ""
+}
+
+func (x *FunctionResult) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *FunctionResult) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *FunctionResult) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+func (x *FunctionResult) GetImageDigest() string {
+	if x != nil {
+		return x.ImageDigest
+	}
+	return ""
+}
+
+func (x *FunctionResult) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *FunctionResult) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
+// OutputChunk is a piece of container output
+type OutputChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stream        string                 `protobuf:"bytes,1,opt,name=stream,proto3" json:"stream,omitempty"` // "stdout" or "stderr"
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutputChunk) Reset() {
+	*x = OutputChunk{}
+	mi := &file_cluster_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutputChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputChunk) ProtoMessage() {}
+
+func (x *OutputChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputChunk.ProtoReflect.Descriptor instead.
+func (*OutputChunk) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *OutputChunk) GetStream() string {
+	if x != nil {
+		return x.Stream
+	}
+	return ""
+}
+
+func (x *OutputChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ExecutionEvent is a message of the ExecuteFunctionStream response stream:
+// zero or more output chunks followed by exactly one result
+type ExecutionEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ExecutionEvent_Output
+	//	*ExecutionEvent_Result
+	Event         isExecutionEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecutionEvent) Reset() {
+	*x = ExecutionEvent{}
+	mi := &file_cluster_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecutionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecutionEvent) ProtoMessage() {}
+
+func (x *ExecutionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecutionEvent.ProtoReflect.Descriptor instead.
+func (*ExecutionEvent) Descriptor() ([]byte, []int) {
+	return file_cluster_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ExecutionEvent) GetEvent() isExecutionEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ExecutionEvent) GetOutput() *OutputChunk {
+	if x != nil {
+		if x, ok := x.Event.(*ExecutionEvent_Output); ok {
+			return x.Output
+		}
+	}
+	return nil
+}
+
+func (x *ExecutionEvent) GetResult() *FunctionResult {
+	if x != nil {
+		if x, ok := x.Event.(*ExecutionEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isExecutionEvent_Event interface {
+	isExecutionEvent_Event()
+}
+
+type ExecutionEvent_Output struct {
+	Output *OutputChunk `protobuf:"bytes,1,opt,name=output,proto3,oneof"`
+}
+
+type ExecutionEvent_Result struct {
+	Result *FunctionResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*ExecutionEvent_Output) isExecutionEvent_Event() {}
+
+func (*ExecutionEvent_Result) isExecutionEvent_Event() {}
+
 var File_cluster_proto protoreflect.FileDescriptor
 
 const file_cluster_proto_rawDesc = "" +
@@ -412,13 +1261,53 @@ const file_cluster_proto_rawDesc = "" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
 	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x1a\n" +
 	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x1c\n" +
-	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"\x9c\x01\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\"\xc3\x02\n" +
 	"\vNodeMetrics\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x1b\n" +
 	"\tcpu_usage\x18\x02 \x01(\x01R\bcpuUsage\x12!\n" +
 	"\fmemory_usage\x18\x03 \x01(\x01R\vmemoryUsage\x12\x1c\n" +
 	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12\x16\n" +
-	"\x06status\x18\x05 \x01(\tR\x06status\"d\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x120\n" +
+	"\aruntime\x18\x06 \x01(\v2\x16.cluster.RuntimeStatusR\aruntime\x12\x16\n" +
+	"\x06images\x18\a \x03(\tR\x06images\x124\n" +
+	"\tresources\x18\b \x01(\v2\x16.cluster.NodeResourcesR\tresources\x12%\n" +
+	"\x04logs\x18\t \x03(\v2\x11.cluster.LogEntryR\x04logs\"\x91\x01\n" +
+	"\bLogEntry\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\x03R\x04time\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12)\n" +
+	"\x06fields\x18\x05 \x03(\v2\x11.cluster.LogFieldR\x06fields\"2\n" +
+	"\bLogField\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"j\n" +
+	"\bLogBatch\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12+\n" +
+	"\aentries\x18\x02 \x03(\v2\x11.cluster.LogEntryR\aentries\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x03R\adropped\"$\n" +
+	"\x06LogAck\x12\x1a\n" +
+	"\breceived\x18\x01 \x01(\x03R\breceived\"\xbd\x03\n" +
+	"\rNodeResources\x12\x1b\n" +
+	"\tcpu_cores\x18\x01 \x01(\x05R\bcpuCores\x12!\n" +
+	"\fmemory_total\x18\x02 \x01(\x04R\vmemoryTotal\x12)\n" +
+	"\x10memory_available\x18\x03 \x01(\x04R\x0fmemoryAvailable\x12\x1b\n" +
+	"\tdisk_path\x18\x04 \x01(\tR\bdiskPath\x12\x1d\n" +
+	"\n" +
+	"disk_total\x18\x05 \x01(\x04R\tdiskTotal\x12\x1b\n" +
+	"\tdisk_free\x18\x06 \x01(\x04R\bdiskFree\x12&\n" +
+	"\x0fnetwork_rx_rate\x18\a \x01(\x01R\rnetworkRxRate\x12&\n" +
+	"\x0fnetwork_tx_rate\x18\b \x01(\x01R\rnetworkTxRate\x12\x14\n" +
+	"\x05load1\x18\t \x01(\x01R\x05load1\x12\x14\n" +
+	"\x05load5\x18\n" +
+	" \x01(\x01R\x05load5\x12\x16\n" +
+	"\x06load15\x18\v \x01(\x01R\x06load15\x12-\n" +
+	"\x12running_containers\x18\f \x01(\x05R\x11runningContainers\x12%\n" +
+	"\x0euptime_seconds\x18\r \x01(\x03R\ruptimeSeconds\"q\n" +
+	"\rRuntimeStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1c\n" +
+	"\tavailable\x18\x03 \x01(\bR\tavailable\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"d\n" +
 	"\x0fAcknowledgement\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
@@ -427,18 +1316,60 @@ const file_cluster_proto_rawDesc = "" +
 	"\x13OrchestratorCommand\x12!\n" +
 	"\fcommand_type\x18\x01 \x01(\tR\vcommandType\x12\x18\n" +
 	"\apayload\x18\x02 \x01(\tR\apayload\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"Y\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"\xc4\x04\n" +
 	"\x0fFunctionRequest\x12!\n" +
 	"\fdocker_image\x18\x01 \x01(\tR\vdockerImage\x12#\n" +
-	"\rfunction_name\x18\x02 \x01(\tR\ffunctionName\"X\n" +
+	"\rfunction_name\x18\x02 \x01(\tR\ffunctionName\x12'\n" +
+	"\x0ftimeout_seconds\x18\x03 \x01(\x05R\x0etimeoutSeconds\x12\x1b\n" +
+	"\tmemory_mb\x18\x04 \x01(\x05R\bmemoryMb\x123\n" +
+	"\x03env\x18\x05 \x03(\v2!.cluster.FunctionRequest.EnvEntryR\x03env\x12(\n" +
+	"\x10max_output_bytes\x18\x06 \x01(\x03R\x0emaxOutputBytes\x12\x12\n" +
+	"\x04kind\x18\a \x01(\tR\x04kind\x12\x12\n" +
+	"\x04args\x18\b \x03(\tR\x04args\x12\x14\n" +
+	"\x05input\x18\t \x01(\fR\x05input\x12\x1f\n" +
+	"\vpull_policy\x18\n" +
+	" \x01(\tR\n" +
+	"pullPolicy\x12:\n" +
+	"\rregistry_auth\x18\v \x01(\v2\x15.cluster.RegistryAuthR\fregistryAuth\x12!\n" +
+	"\fimage_digest\x18\f \x01(\tR\vimageDigest\x12)\n" +
+	"\x10verify_signature\x18\r \x01(\bR\x0fverifySignature\x12#\n" +
+	"\rinvocation_id\x18\x0e \x01(\tR\finvocationId\x1a6\n" +
+	"\bEnvEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"^\n" +
+	"\fRegistryAuth\x12\x16\n" +
+	"\x06server\x18\x01 \x01(\tR\x06server\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x03 \x01(\tR\bpassword\"\xf3\x02\n" +
 	"\x0eFunctionResult\x12\x16\n" +
 	"\x06output\x18\x01 \x01(\tR\x06output\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error2\xd7\x01\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12)\n" +
+	"\x10output_truncated\x18\x04 \x01(\bR\x0foutputTruncated\x12\x16\n" +
+	"\x06stdout\x18\x05 \x01(\tR\x06stdout\x12\x16\n" +
+	"\x06stderr\x18\x06 \x01(\tR\x06stderr\x12\x1b\n" +
+	"\texit_code\x18\a \x01(\x05R\bexitCode\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\b \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\t \x01(\x03R\n" +
+	"finishedAt\x12!\n" +
+	"\fimage_digest\x18\n" +
+	" \x01(\tR\vimageDigest\x12\x17\n" +
+	"\anode_id\x18\v \x01(\tR\x06nodeId\x12%\n" +
+	"\x0efailure_reason\x18\f \x01(\tR\rfailureReason\"9\n" +
+	"\vOutputChunk\x12\x16\n" +
+	"\x06stream\x18\x01 \x01(\tR\x06stream\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"|\n" +
+	"\x0eExecutionEvent\x12.\n" +
+	"\x06output\x18\x01 \x01(\v2\x14.cluster.OutputChunkH\x00R\x06output\x121\n" +
+	"\x06result\x18\x02 \x01(\v2\x17.cluster.FunctionResultH\x00R\x06resultB\a\n" +
+	"\x05event2\xd7\x02\n" +
 	"\x0eClusterService\x12:\n" +
 	"\vJoinCluster\x12\x11.cluster.NodeInfo\x1a\x18.cluster.Acknowledgement\x12C\n" +
 	"\tHeartbeat\x12\x14.cluster.NodeMetrics\x1a\x1c.cluster.OrchestratorCommand(\x010\x01\x12D\n" +
-	"\x0fExecuteFunction\x12\x18.cluster.FunctionRequest\x1a\x17.cluster.FunctionResultB\x18Z\x16cares/internal/clusterb\x06proto3"
+	"\x0fExecuteFunction\x12\x18.cluster.FunctionRequest\x1a\x17.cluster.FunctionResult\x12L\n" +
+	"\x15ExecuteFunctionStream\x12\x18.cluster.FunctionRequest\x1a\x17.cluster.ExecutionEvent0\x01\x120\n" +
+	"\bShipLogs\x12\x11.cluster.LogBatch\x1a\x0f.cluster.LogAck(\x01B\x18Z\x16cares/internal/clusterb\x06proto3"
 
 var (
 	file_cluster_proto_rawDescOnce sync.Once
@@ -452,27 +1383,50 @@ func file_cluster_proto_rawDescGZIP() []byte {
 	return file_cluster_proto_rawDescData
 }
 
-var file_cluster_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_cluster_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
 var file_cluster_proto_goTypes = []any{
 	(*NodeInfo)(nil),            // 0: cluster.NodeInfo
 	(*NodeMetrics)(nil),         // 1: cluster.NodeMetrics
-	(*Acknowledgement)(nil),     // 2: cluster.Acknowledgement
-	(*OrchestratorCommand)(nil), // 3: cluster.OrchestratorCommand
-	(*FunctionRequest)(nil),     // 4: cluster.FunctionRequest
-	(*FunctionResult)(nil),      // 5: cluster.FunctionResult
+	(*LogEntry)(nil),            // 2: cluster.LogEntry
+	(*LogField)(nil),            // 3: cluster.LogField
+	(*LogBatch)(nil),            // 4: cluster.LogBatch
+	(*LogAck)(nil),              // 5: cluster.LogAck
+	(*NodeResources)(nil),       // 6: cluster.NodeResources
+	(*RuntimeStatus)(nil),       // 7: cluster.RuntimeStatus
+	(*Acknowledgement)(nil),     // 8: cluster.Acknowledgement
+	(*OrchestratorCommand)(nil), // 9: cluster.OrchestratorCommand
+	(*FunctionRequest)(nil),     // 10: cluster.FunctionRequest
+	(*RegistryAuth)(nil),        // 11: cluster.RegistryAuth
+	(*FunctionResult)(nil),      // 12: cluster.FunctionResult
+	(*OutputChunk)(nil),         // 13: cluster.OutputChunk
+	(*ExecutionEvent)(nil),      // 14: cluster.ExecutionEvent
+	nil,                         // 15: cluster.FunctionRequest.EnvEntry
 }
 var file_cluster_proto_depIdxs = []int32{
-	0, // 0: cluster.ClusterService.JoinCluster:input_type -> cluster.NodeInfo
-	1, // 1: cluster.ClusterService.Heartbeat:input_type -> cluster.NodeMetrics
-	4, // 2: cluster.ClusterService.ExecuteFunction:input_type -> cluster.FunctionRequest
-	2, // 3: cluster.ClusterService.JoinCluster:output_type -> cluster.Acknowledgement
-	3, // 4: cluster.ClusterService.Heartbeat:output_type -> cluster.OrchestratorCommand
-	5, // 5: cluster.ClusterService.ExecuteFunction:output_type -> cluster.FunctionResult
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: cluster.NodeMetrics.runtime:type_name -> cluster.RuntimeStatus
+	6,  // 1: cluster.NodeMetrics.resources:type_name -> cluster.NodeResources
+	2,  // 2: cluster.NodeMetrics.logs:type_name -> cluster.LogEntry
+	3,  // 3: cluster.LogEntry.fields:type_name -> cluster.LogField
+	2,  // 4: cluster.LogBatch.entries:type_name -> cluster.LogEntry
+	15, // 5: cluster.FunctionRequest.env:type_name -> cluster.FunctionRequest.EnvEntry
+	11, // 6: cluster.FunctionRequest.registry_auth:type_name -> cluster.RegistryAuth
+	13, // 7: cluster.ExecutionEvent.output:type_name -> cluster.OutputChunk
+	12, // 8: cluster.ExecutionEvent.result:type_name -> cluster.FunctionResult
+	0,  // 9: cluster.ClusterService.JoinCluster:input_type -> cluster.NodeInfo
+	1,  // 10: cluster.ClusterService.Heartbeat:input_type -> cluster.NodeMetrics
+	10, // 11: cluster.ClusterService.ExecuteFunction:input_type -> cluster.FunctionRequest
+	10, // 12: cluster.ClusterService.ExecuteFunctionStream:input_type -> cluster.FunctionRequest
+	4,  // 13: cluster.ClusterService.ShipLogs:input_type -> cluster.LogBatch
+	8,  // 14: cluster.ClusterService.JoinCluster:output_type -> cluster.Acknowledgement
+	9,  // 15: cluster.ClusterService.Heartbeat:output_type -> cluster.OrchestratorCommand
+	12, // 16: cluster.ClusterService.ExecuteFunction:output_type -> cluster.FunctionResult
+	14, // 17: cluster.ClusterService.ExecuteFunctionStream:output_type -> cluster.ExecutionEvent
+	5,  // 18: cluster.ClusterService.ShipLogs:output_type -> cluster.LogAck
+	14, // [14:19] is the sub-list for method output_type
+	9,  // [9:14] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
 }
 
 func init() { file_cluster_proto_init() }
@@ -480,13 +1434,17 @@ func file_cluster_proto_init() {
 	if File_cluster_proto != nil {
 		return
 	}
+	file_cluster_proto_msgTypes[14].OneofWrappers = []any{
+		(*ExecutionEvent_Output)(nil),
+		(*ExecutionEvent_Result)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_cluster_proto_rawDesc), len(file_cluster_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   16,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,11 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ClusterService_JoinCluster_FullMethodName     = "/cluster.ClusterService/JoinCluster"
-	ClusterService_Heartbeat_FullMethodName       = "/cluster.ClusterService/Heartbeat"
-	ClusterService_ExecuteFunction_FullMethodName = "/cluster.ClusterService/ExecuteFunction"
+	ClusterService_JoinCluster_FullMethodName           = "/cluster.ClusterService/JoinCluster"
+	ClusterService_Heartbeat_FullMethodName             = "/cluster.ClusterService/Heartbeat"
+	ClusterService_ExecuteFunction_FullMethodName       = "/cluster.ClusterService/ExecuteFunction"
+	ClusterService_ExecuteFunctionStream_FullMethodName = "/cluster.ClusterService/ExecuteFunctionStream"
+	ClusterService_ShipLogs_FullMethodName              = "/cluster.ClusterService/ShipLogs"
 )
 
 // ClusterServiceClient is the client API for ClusterService service.
@@ -34,8 +36,13 @@ type ClusterServiceClient interface {
 	JoinCluster(ctx context.Context, in *NodeInfo, opts ...grpc.CallOption) (*Acknowledgement, error)
 	// Heartbeat establishes a bidirectional stream for metrics and commands
 	Heartbeat(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[NodeMetrics, OrchestratorCommand], error)
-	// ExecuteFunction executes a Docker container on the worker node
+	// ExecuteFunction executes a function (container or process) on the worker node
 	ExecuteFunction(ctx context.Context, in *FunctionRequest, opts ...grpc.CallOption) (*FunctionResult, error)
+	// ExecuteFunctionStream executes a function and streams its output
+	// as it is produced, finishing with the FunctionResult
+	ExecuteFunctionStream(ctx context.Context, in *FunctionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecutionEvent], error)
+	// ShipLogs streams a worker's log records to the orchestrator in batches
+	ShipLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LogBatch, LogAck], error)
 }
 
 type clusterServiceClient struct {
@@ -79,6 +86,38 @@ func (c *clusterServiceClient) ExecuteFunction(ctx context.Context, in *Function
 	return out, nil
 }
 
+func (c *clusterServiceClient) ExecuteFunctionStream(ctx context.Context, in *FunctionRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecutionEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClusterService_ServiceDesc.Streams[1], ClusterService_ExecuteFunctionStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[FunctionRequest, ExecutionEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClusterService_ExecuteFunctionStreamClient = grpc.ServerStreamingClient[ExecutionEvent]
+
+func (c *clusterServiceClient) ShipLogs(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[LogBatch, LogAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClusterService_ServiceDesc.Streams[2], ClusterService_ShipLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LogBatch, LogAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClusterService_ShipLogsClient = grpc.ClientStreamingClient[LogBatch, LogAck]
+
 // ClusterServiceServer is the server API for ClusterService service.
 // All implementations must embed UnimplementedClusterServiceServer
 // for forward compatibility.
@@ -89,8 +128,13 @@ type ClusterServiceServer interface {
 	JoinCluster(context.Context, *NodeInfo) (*Acknowledgement, error)
 	// Heartbeat establishes a bidirectional stream for metrics and commands
 	Heartbeat(grpc.BidiStreamingServer[NodeMetrics, OrchestratorCommand]) error
-	// ExecuteFunction executes a Docker container on the worker node
+	// ExecuteFunction executes a function (container or process) on the worker node
 	ExecuteFunction(context.Context, *FunctionRequest) (*FunctionResult, error)
+	// ExecuteFunctionStream executes a function and streams its output
+	// as it is produced, finishing with the FunctionResult
+	ExecuteFunctionStream(*FunctionRequest, grpc.ServerStreamingServer[ExecutionEvent]) error
+	// ShipLogs streams a worker's log records to the orchestrator in batches
+	ShipLogs(grpc.ClientStreamingServer[LogBatch, LogAck]) error
 	mustEmbedUnimplementedClusterServiceServer()
 }
 
@@ -110,6 +154,12 @@ func (UnimplementedClusterServiceServer) Heartbeat(grpc.BidiStreamingServer[Node
 func (UnimplementedClusterServiceServer) ExecuteFunction(context.Context, *FunctionRequest) (*FunctionResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecuteFunction not implemented")
 }
+func (UnimplementedClusterServiceServer) ExecuteFunctionStream(*FunctionRequest, grpc.ServerStreamingServer[ExecutionEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method ExecuteFunctionStream not implemented")
+}
+func (UnimplementedClusterServiceServer) ShipLogs(grpc.ClientStreamingServer[LogBatch, LogAck]) error {
+	return status.Errorf(codes.Unimplemented, "method ShipLogs not implemented")
+}
 func (UnimplementedClusterServiceServer) mustEmbedUnimplementedClusterServiceServer() {}
 func (UnimplementedClusterServiceServer) testEmbeddedByValue()                        {}
 
@@ -174,6 +224,24 @@ func _ClusterService_ExecuteFunction_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ClusterService_ExecuteFunctionStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FunctionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClusterServiceServer).ExecuteFunctionStream(m, &grpc.GenericServerStream[FunctionRequest, ExecutionEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClusterService_ExecuteFunctionStreamServer = grpc.ServerStreamingServer[ExecutionEvent]
+
+func _ClusterService_ShipLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClusterServiceServer).ShipLogs(&grpc.GenericServerStream[LogBatch, LogAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClusterService_ShipLogsServer = grpc.ClientStreamingServer[LogBatch, LogAck]
+
 // ClusterService_ServiceDesc is the grpc.ServiceDesc for ClusterService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -197,6 +265,16 @@ var ClusterService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ExecuteFunctionStream",
+			Handler:       _ClusterService_ExecuteFunctionStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ShipLogs",
+			Handler:       _ClusterService_ShipLogs_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "cluster.proto",
 }
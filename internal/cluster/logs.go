@@ -0,0 +1,330 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cares/internal/logging"
+	"cares/internal/logstore"
+	"cares/internal/tracing"
+)
+
+// Log shipping limits
+const (
+	logQueueSize       = 5000            // Entries a worker queues for shipping before dropping new ones
+	logBatchSize       = 100             // Entries sent in one batch at most
+	logFlushInterval   = time.Second     // Longest an entry waits for its batch to fill up
+	logRetryInterval   = 5 * time.Second // Wait before reopening a failed log stream
+	maxShippedOutput   = 1000            // Output lines of one execution shipped at most
+	maxRelayedLogs     = 200             // Entries a heartbeat relays at most
+	shipOutputVariable = "CARES_SHIP_FUNCTION_OUTPUT"
+)
+
+// logShipper queues the entries a worker logs until they are shipped.
+// Publish never blocks logging: once the queue is full, new entries are
+// dropped and counted, and the count is reported with the next batch.
+type logShipper struct {
+	queue   chan logging.Entry
+	dropped atomic.Int64
+}
+
+// Publish queues an entry for shipping. Entries relayed from other nodes are
+// left out so they never travel back.
+func (s *logShipper) Publish(entry logging.Entry) {
+	if entry.NodeID != "" {
+		return
+	}
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+		workerLogsDropped.Inc()
+	}
+}
+
+// next waits for queued entries and returns them as a batch of up to
+// logBatchSize entries, sent once full or logFlushInterval after its first
+// entry. It returns nil when ctx is done.
+func (s *logShipper) next(ctx context.Context, nodeID string) *LogBatch {
+	batch := &LogBatch{NodeId: nodeID}
+	select {
+	case entry := <-s.queue:
+		batch.Entries = append(batch.Entries, logEntryProto(entry))
+	case <-ctx.Done():
+		return nil
+	}
+
+	flush := time.NewTimer(logFlushInterval)
+	defer flush.Stop()
+	for len(batch.Entries) < logBatchSize {
+		select {
+		case entry := <-s.queue:
+			batch.Entries = append(batch.Entries, logEntryProto(entry))
+		case <-flush.C:
+			batch.Dropped = s.dropped.Swap(0)
+			return batch
+		case <-ctx.Done():
+			batch.Dropped = s.dropped.Swap(0)
+			return batch
+		}
+	}
+	batch.Dropped = s.dropped.Swap(0)
+	return batch
+}
+
+// StartLogShipping ships everything this worker logs to the orchestrator
+// until ctx is done or the client disconnects. A stream that fails is
+// reopened after logRetryInterval, resending the batch that failed; entries
+// logged meanwhile wait in the queue. Heartbeats relay the logs instead while
+// logs aren't shipped, including to orchestrators that predate ShipLogs.
+func (c *Client) StartLogShipping(ctx context.Context) error {
+	if !c.isConnected {
+		return fmt.Errorf("not connected to orchestrator")
+	}
+
+	shipper := &logShipper{queue: make(chan logging.Entry, logQueueSize)}
+	logging.Subscribe(shipper)
+	defer logging.Unsubscribe(shipper)
+	c.logsShipped.Store(true)
+	defer c.logsShipped.Store(false)
+
+	var pending *LogBatch
+	failing := false
+	for {
+		sent, err := c.shipLogs(ctx, shipper, &pending)
+		if ctx.Err() != nil || !c.isConnected {
+			return nil
+		}
+		if status.Code(err) == codes.Unimplemented {
+			logging.Info("Orchestrator does not accept shipped logs, relaying them over heartbeats")
+			return nil
+		}
+		// Only the first failure of an outage is worth a warning
+		if sent > 0 {
+			failing = false
+		}
+		if !failing {
+			logging.Warn("Log shipping interrupted, retrying every %s: %v", logRetryInterval, err)
+			failing = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logRetryInterval):
+		}
+	}
+}
+
+// shipLogs sends batches over one ShipLogs stream until it fails or ctx is
+// done, returning the number of batches sent. A batch that could not be sent
+// is left in pending.
+func (c *Client) shipLogs(ctx context.Context, shipper *logShipper, pending **LogBatch) (int, error) {
+	stream, err := c.client.ShipLogs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for {
+		if *pending == nil {
+			*pending = shipper.next(ctx, c.nodeID)
+			if *pending == nil {
+				_, err := stream.CloseAndRecv()
+				return sent, err
+			}
+		}
+
+		// Send blocks while the orchestrator falls behind (gRPC flow control), so
+		// the queue fills up and entries are dropped rather than logging blocked
+		if err := stream.Send(*pending); err != nil {
+			if err == io.EOF {
+				_, err = stream.CloseAndRecv()
+			}
+			countGRPCError(sideClient, ClusterService_ShipLogs_FullMethodName, err)
+			return sent, err
+		}
+		*pending = nil
+		sent++
+	}
+}
+
+// SetLogStore sets the store the log records shipped by workers are kept in.
+// Without one, shipped records are only shown in the TUI.
+func (s *Server) SetLogStore(store *logstore.Store) {
+	s.logStore = store
+}
+
+// ShipLogs receives the log batches of a worker, storing them and handing
+// them to the local subscribers tagged with the worker's node ID. Workers
+// that don't ship logs relay them with their heartbeats instead.
+func (s *Server) ShipLogs(stream grpc.ClientStreamingServer[LogBatch, LogAck]) error {
+	var received int64
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&LogAck{Received: received})
+		}
+		if err != nil {
+			countGRPCError(sideServer, ClusterService_ShipLogs_FullMethodName, err)
+			return err
+		}
+
+		received += int64(len(batch.Entries))
+		s.receiveLogs(batch)
+	}
+}
+
+// receiveLogs stores and publishes a batch of worker logs, shipped or relayed
+// with a heartbeat.
+func (s *Server) receiveLogs(batch *LogBatch) {
+	log := logging.With(logging.KeyNodeID, batch.NodeId)
+	if batch.Dropped > 0 {
+		log.Warn("Worker dropped log entries, shipping fell behind", "dropped", batch.Dropped)
+		logsDropped.Add(float64(batch.Dropped), batch.NodeId)
+	}
+	if len(batch.Entries) == 0 {
+		return
+	}
+	logsReceived.Add(float64(len(batch.Entries)), batch.NodeId)
+
+	entries := workerLogEntries(batch.NodeId, batch.Entries)
+	if s.logStore != nil {
+		records := make([]logstore.Record, len(entries))
+		for i, entry := range entries {
+			records[i] = logRecord(entry)
+		}
+		if err := s.logStore.Add(records...); err != nil {
+			log.Error("Failed to store worker logs", "error", err)
+		}
+	}
+	for _, entry := range entries {
+		logging.Publish(entry)
+	}
+}
+
+// shipFunctionOutput logs the output of an execution line by line, so it is
+// shipped to the orchestrator along with the worker's own logs. It is only
+// done when CARES_SHIP_FUNCTION_OUTPUT is set to true, since output can be
+// large and may hold data that should not end up in logs.
+func shipFunctionOutput(ctx context.Context, req *FunctionRequest, stdout, stderr string) {
+	if os.Getenv(shipOutputVariable) != "true" {
+		return
+	}
+	fields := []logging.Field{
+		{Key: logging.KeyFunction, Value: req.FunctionName},
+		{Key: logging.KeyInvocationID, Value: req.InvocationId},
+	}
+	if traceID := tracing.TraceIDFromContext(ctx); traceID != "" {
+		fields = append(fields, logging.Field{Key: logging.KeyTraceID, Value: traceID})
+	}
+
+	shipped := 0
+	for _, output := range []struct{ stream, text string }{{"stdout", stdout}, {"stderr", stderr}} {
+		for _, line := range strings.Split(strings.TrimRight(output.text, "\n"), "\n") {
+			if line == "" {
+				continue
+			}
+			if shipped == maxShippedOutput {
+				return
+			}
+			shipped++
+			logging.Publish(logging.Entry{
+				Time:    time.Now(),
+				Level:   slog.LevelInfo,
+				Message: line,
+				Fields:  append(fields[:len(fields):len(fields)], logging.Field{Key: "stream", Value: output.stream}),
+			})
+		}
+	}
+}
+
+// pendingLogs returns the entries this process logged after seq, converted
+// for a heartbeat, and the seq to continue from. Entries relayed from other
+// nodes are left out so they never travel back.
+func pendingLogs(seq uint64) ([]*LogEntry, uint64) {
+	entries := logging.Recent().Since(seq)
+	if len(entries) == 0 {
+		return nil, seq
+	}
+	last := entries[len(entries)-1].Seq
+
+	var logs []*LogEntry
+	for _, entry := range entries {
+		if entry.NodeID == "" {
+			logs = append(logs, logEntryProto(entry))
+		}
+	}
+	if len(logs) > maxRelayedLogs {
+		logs = logs[len(logs)-maxRelayedLogs:]
+	}
+	return logs, last
+}
+
+// logEntryProto converts a logged entry for shipping.
+func logEntryProto(entry logging.Entry) *LogEntry {
+	fields := make([]*LogField, len(entry.Fields))
+	for i, field := range entry.Fields {
+		fields[i] = &LogField{Key: field.Key, Value: field.Value}
+	}
+	return &LogEntry{
+		Time:    entry.Time.UnixNano(),
+		Level:   logging.LevelName(entry.Level),
+		Message: entry.Message,
+		Source:  entry.Source,
+		Fields:  fields,
+	}
+}
+
+// workerLogEntries converts log entries shipped by a worker, tagging them
+// with its node ID. Entries with an unknown level are skipped.
+func workerLogEntries(nodeID string, logs []*LogEntry) []logging.Entry {
+	entries := make([]logging.Entry, 0, len(logs))
+	for _, log := range logs {
+		level, err := logging.ParseLevel(log.Level)
+		if err != nil {
+			continue
+		}
+		fields := make([]logging.Field, len(log.Fields))
+		for i, field := range log.Fields {
+			fields[i] = logging.Field{Key: field.Key, Value: field.Value}
+		}
+		entries = append(entries, logging.Entry{
+			Time:    time.Unix(0, log.Time),
+			Level:   level,
+			Message: log.Message,
+			Source:  log.Source,
+			Fields:  fields,
+			NodeID:  nodeID,
+		})
+	}
+	return entries
+}
+
+// logRecord converts a worker's log entry for the log store.
+func logRecord(entry logging.Entry) logstore.Record {
+	record := logstore.Record{
+		Time:    entry.Time,
+		NodeID:  entry.NodeID,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Source:  entry.Source,
+	}
+	if len(entry.Fields) > 0 {
+		record.Fields = make(map[string]string, len(entry.Fields))
+		for _, field := range entry.Fields {
+			record.Fields[field.Key] = field.Value
+		}
+	}
+	return record
+}
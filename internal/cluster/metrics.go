@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"cares/internal/executor"
+	"cares/internal/metrics"
+	"cares/internal/registry"
+	"cares/internal/telemetry"
+)
+
+// Sides of a gRPC call, as labelled in the gRPC metrics
+const (
+	sideClient = "client"
+	sideServer = "server"
+)
+
+var (
+	grpcRequests = telemetry.NewCounter("cares_grpc_requests_total",
+		"gRPC calls made (client) and served (server), by method.", "side", "method")
+	grpcErrors = telemetry.NewCounter("cares_grpc_errors_total",
+		"gRPC calls and streams that failed, by method and status code.", "side", "method", "code")
+
+	workerExecutions = telemetry.NewCounter("cares_worker_executions_total",
+		"Functions executed on this worker, by function and status.", "function", "status")
+	workerExecutionFailures = telemetry.NewCounter("cares_worker_execution_failures_total",
+		"Failed executions on this worker, by function and failure reason.", "function", "reason")
+	workerExecutionDuration = telemetry.NewHistogram("cares_worker_execution_duration_seconds",
+		"Time from receiving an execution request to its result, by function.", nil, "function")
+	workerExecutionsRunning = telemetry.NewGauge("cares_worker_executions_running",
+		"Functions currently executing on this worker.")
+	workerLogsDropped = telemetry.NewCounter("cares_worker_logs_dropped_total",
+		"Log entries this worker dropped because shipping them fell behind.")
+
+	logsReceived = telemetry.NewCounter("cares_logs_received_total",
+		"Log entries shipped to the orchestrator, by node.", "node")
+	logsDropped = telemetry.NewCounter("cares_logs_dropped_total",
+		"Log entries workers reported dropping before shipping, by node.", "node")
+)
+
+// Dial connects to the cluster service at address. Calls made over the
+// connection are counted in the gRPC metrics and traced if their context
+// carries a span. The connection is secured with TLS once ConfigureTLS is.
+func Dial(address string) (*grpc.ClientConn, error) {
+	return grpc.Dial(address,
+		grpc.WithTransportCredentials(clientCredentials()),
+		grpc.WithChainUnaryInterceptor(countUnaryClient, traceUnaryClient),
+		grpc.WithChainStreamInterceptor(countStreamClient, traceStreamClient),
+	)
+}
+
+// countGRPCCall records a gRPC call and, if err is not nil, its failure.
+func countGRPCCall(side, method string, err error) {
+	grpcRequests.Inc(side, method)
+	countGRPCError(side, method, err)
+}
+
+// countGRPCError records a failure of a gRPC call or stream, if err is not nil.
+func countGRPCError(side, method string, err error) {
+	if err != nil {
+		grpcErrors.Inc(side, method, status.Code(err).String())
+	}
+}
+
+// countUnaryClient is a client interceptor counting unary calls.
+func countUnaryClient(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	countGRPCCall(sideClient, method, err)
+	return err
+}
+
+// countStreamClient is a client interceptor counting streams. Only failures
+// to open a stream are seen here; errors on open streams are counted where
+// they are received.
+func countStreamClient(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	countGRPCCall(sideClient, method, err)
+	return stream, err
+}
+
+// countUnaryServer is a server interceptor counting unary calls.
+func countUnaryServer(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	countGRPCCall(sideServer, info.FullMethod, err)
+	return resp, err
+}
+
+// countStreamServer is a server interceptor counting streams.
+func countStreamServer(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, stream)
+	countGRPCCall(sideServer, info.FullMethod, err)
+	return err
+}
+
+// recordExecution records a finished execution in the worker metrics.
+func recordExecution(function string, started time.Time, result *FunctionResult) {
+	status := "success"
+	if !result.Success {
+		status = "failed"
+		workerExecutionFailures.Inc(function, result.FailureReason)
+	}
+	workerExecutions.Inc(function, status)
+	workerExecutionDuration.Observe(time.Since(started).Seconds(), function)
+}
+
+// CollectMetrics writes the state of the cluster as seen by the orchestrator:
+// nodes by status, the resources each node last reported, how long ago that
+// was, and the commands queued for it.
+func (s *Server) CollectMetrics(w *telemetry.Writer) {
+	nodes := s.registry.GetAllNodes()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	counts := map[registry.NodeStatus]int{
+		registry.NodeStatusActive:       0,
+		registry.NodeStatusJoining:      0,
+		registry.NodeStatusDisconnected: 0,
+	}
+	for _, node := range nodes {
+		counts[node.Status]++
+	}
+	w.Declare("cares_nodes", telemetry.TypeGauge, "Worker nodes known to the orchestrator, by status.")
+	for _, status := range []registry.NodeStatus{registry.NodeStatusActive, registry.NodeStatusJoining, registry.NodeStatusDisconnected} {
+		w.Sample("cares_nodes", float64(counts[status]), "status", strings.ToLower(string(status)))
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(node *registry.Node) float64
+	}{
+		{"cares_node_cpu_usage_percent", "CPU usage last reported by a node.",
+			func(node *registry.Node) float64 { return node.CPUUsage }},
+		{"cares_node_memory_usage_percent", "Memory usage last reported by a node.",
+			func(node *registry.Node) float64 { return node.MemoryUsage }},
+		{"cares_node_memory_available_bytes", "Memory available on a node.",
+			func(node *registry.Node) float64 { return float64(node.Resources.MemoryAvailable) }},
+		{"cares_node_disk_free_bytes", "Free space on the filesystem holding a node's container data.",
+			func(node *registry.Node) float64 { return float64(node.Resources.DiskFree) }},
+		{"cares_node_load1", "One-minute load average of a node.",
+			func(node *registry.Node) float64 { return node.Resources.Load1 }},
+		{"cares_node_running_containers", "Containers running on a node's container engine.",
+			func(node *registry.Node) float64 { return float64(node.Resources.RunningContainers) }},
+		{"cares_node_runtime_available", "Whether a node's container runtime is available (1) or not (0).",
+			func(node *registry.Node) float64 { return boolValue(node.Runtime.Available) }},
+		{"cares_node_heartbeat_lag_seconds", "Time since a node's last heartbeat.",
+			func(node *registry.Node) float64 { return time.Since(node.LastSeen).Seconds() }},
+	}
+	for _, gauge := range gauges {
+		w.Declare(gauge.name, telemetry.TypeGauge, gauge.help)
+		for _, node := range nodes {
+			w.Sample(gauge.name, gauge.value(node), "node", node.ID)
+		}
+	}
+
+	s.mu.RLock()
+	depths := make(map[string]int, len(s.listeners))
+	for nodeID, commands := range s.listeners {
+		depths[nodeID] = len(commands)
+	}
+	s.mu.RUnlock()
+
+	w.Declare("cares_command_queue_depth", telemetry.TypeGauge, "Commands queued for a connected node, waiting for its next heartbeat.")
+	for _, node := range nodes {
+		if depth, connected := depths[node.ID]; connected {
+			w.Sample("cares_command_queue_depth", float64(depth), "node", node.ID)
+		}
+	}
+}
+
+// CollectMetrics writes the worker's own resource usage, as sampled for its
+// heartbeats, and the health of its container runtime.
+func (c *Client) CollectMetrics(w *telemetry.Writer) {
+	if sample, ok := metrics.Default().Latest(); ok {
+		w.Declare("cares_worker_cpu_usage_percent", telemetry.TypeGauge, "CPU usage of the worker machine.")
+		w.Sample("cares_worker_cpu_usage_percent", sample.CPUUsage)
+		w.Declare("cares_worker_memory_usage_percent", telemetry.TypeGauge, "Memory usage of the worker machine.")
+		w.Sample("cares_worker_memory_usage_percent", sample.MemoryUsage)
+		w.Declare("cares_worker_load1", telemetry.TypeGauge, "One-minute load average of the worker machine.")
+		w.Sample("cares_worker_load1", sample.Node.Load1)
+	}
+
+	health := executor.RuntimeHealthStatus()
+	w.Declare("cares_worker_runtime_available", telemetry.TypeGauge, "Whether the container runtime is available (1) or not (0).")
+	w.Sample("cares_worker_runtime_available", boolValue(health.Available))
+	w.Declare("cares_worker_running_containers", telemetry.TypeGauge, "Containers running on the container engine.")
+	w.Sample("cares_worker_running_containers", float64(health.RunningContainers))
+
+	w.Declare("cares_worker_connected", telemetry.TypeGauge, "Whether the worker is connected to an orchestrator (1) or not (0).")
+	w.Sample("cares_worker_connected", boolValue(c.IsConnected()))
+}
+
+// boolValue returns 1 for true and 0 for false.
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -5,16 +5,22 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 
 	"cares/internal/logging"
 
+	"cares/internal/events"
 	"cares/internal/executor"
+	"cares/internal/logstore"
 	"cares/internal/registry"
 )
 
@@ -24,6 +30,8 @@ type Server struct {
 	registry *registry.NodeRegistry
 	listeners map[string]chan *OrchestratorCommand // nodeID -> command channel
 	mu       sync.RWMutex
+	nodeID   string // ID of this node when running as a worker, reported in results
+	logStore *logstore.Store // Logs shipped by workers, nil to keep none
 }
 
 // NewServer creates a new gRPC server instance with an empty node registry.
@@ -39,6 +47,12 @@ func (s *Server) GetRegistry() *registry.NodeRegistry {
 	return s.registry
 }
 
+// SetNodeID sets the node ID this server reports in function results when it
+// runs on a worker.
+func (s *Server) SetNodeID(nodeID string) {
+	s.nodeID = nodeID
+}
+
 // JoinCluster handles worker node registration requests.
 func (s *Server) JoinCluster(ctx context.Context, nodeInfo *NodeInfo) (*Acknowledgement, error) {
 	// Add node to registry
@@ -55,6 +69,97 @@ func (s *Server) JoinCluster(ctx context.Context, nodeInfo *NodeInfo) (*Acknowle
 	}, nil
 }
 
+// Orchestrator command types
+const (
+	CommandPullImage   = "pull_image"    // Payload: PullImagePayload as JSON
+	CommandSetLogLevel = "set_log_level" // Payload: level name, e.g. "debug"
+)
+
+// Failure reasons reported in FunctionResult.FailureReason
+const (
+	FailureExecution      = "execution"         // The function failed or could not be started
+	FailureImagePull      = "image_pull"        // The image could not be pulled
+	FailureDigestMismatch = "digest_mismatch"   // The image is not the manifest the version is pinned to
+	FailureSignature      = "signature_invalid" // The image has no signature by a trusted key
+)
+
+// PullImagePayload is the payload of a CommandPullImage command. It carries no
+// registry credentials: those are only sent with an execution request to the
+// node that runs it, so private images are not pre-pulled.
+type PullImagePayload struct {
+	Image string `json:"image"`
+}
+
+// PrePullImage queues an anonymous pull of image on every connected worker and
+// returns how many workers were asked. Commands ride on the heartbeat stream,
+// so workers receive them within one heartbeat; a worker whose queue is full
+// is skipped. Every queued command is published as an event attributed to the
+// actor of ctx.
+func (s *Server) PrePullImage(ctx context.Context, image string) int {
+	payload, err := json.Marshal(PullImagePayload{Image: image})
+	if err != nil {
+		logging.Error("Failed to encode pre-pull of '%s': %v", image, err)
+		return 0
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queued := 0
+	for nodeID, commands := range s.listeners {
+		cmd := &OrchestratorCommand{
+			CommandType: CommandPullImage,
+			Payload:     string(payload),
+			Timestamp:   time.Now().Unix(),
+		}
+		select {
+		case commands <- cmd:
+			queued++
+			publishCommand(ctx, nodeID, CommandPullImage, image)
+		default:
+			logging.Warn("Command queue of node '%s' is full, not pre-pulling '%s'", nodeID, image)
+		}
+	}
+	return queued
+}
+
+// SetWorkerLogLevel queues a change of the log level on the worker nodeID, or
+// on every connected worker if nodeID is empty, and returns how many workers
+// were asked. Like pre-pulls, the change arrives with the next heartbeat.
+func (s *Server) SetWorkerLogLevel(ctx context.Context, nodeID string, level slog.Level) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queued := 0
+	for id, commands := range s.listeners {
+		if nodeID != "" && id != nodeID {
+			continue
+		}
+		cmd := &OrchestratorCommand{
+			CommandType: CommandSetLogLevel,
+			Payload:     logging.LevelName(level),
+			Timestamp:   time.Now().Unix(),
+		}
+		select {
+		case commands <- cmd:
+			queued++
+			publishCommand(ctx, id, CommandSetLogLevel, cmd.Payload)
+		default:
+			logging.Warn("Command queue of node '%s' is full, not changing its log level", id)
+		}
+	}
+	return queued
+}
+
+// publishCommand publishes the event of a command queued for a node.
+func publishCommand(ctx context.Context, nodeID, command, argument string) {
+	events.Publish(ctx, events.Event{
+		Type:    events.NodeCommandQueued,
+		Subject: nodeID,
+		Data:    map[string]string{"command": command, "argument": argument},
+	})
+}
+
 // Heartbeat handles bidirectional streaming for worker heartbeats.
 func (s *Server) Heartbeat(stream grpc.BidiStreamingServer[NodeMetrics, OrchestratorCommand]) error {
 	var nodeID string
@@ -76,6 +181,7 @@ func (s *Server) Heartbeat(stream grpc.BidiStreamingServer[NodeMetrics, Orchestr
 			break
 		}
 		if err != nil {
+			countGRPCError(sideServer, ClusterService_Heartbeat_FullMethodName, err)
 			break
 		}
 
@@ -83,6 +189,34 @@ func (s *Server) Heartbeat(stream grpc.BidiStreamingServer[NodeMetrics, Orchestr
 
 		// Update node metrics in registry
 		s.registry.UpdateMetrics(nodeID, float64(metrics.CpuUsage), float64(metrics.MemoryUsage))
+		if runtime := metrics.Runtime; runtime != nil {
+			s.registry.UpdateRuntime(nodeID, registry.RuntimeStatus{
+				Name:      runtime.Name,
+				Version:   runtime.Version,
+				Available: runtime.Available,
+				Error:     runtime.Error,
+			})
+		}
+		s.registry.UpdateImages(nodeID, metrics.Images)
+		if resources := metrics.Resources; resources != nil {
+			s.registry.UpdateResources(nodeID, registry.NodeResources{
+				CPUCores:          int(resources.CpuCores),
+				MemoryTotal:       resources.MemoryTotal,
+				MemoryAvailable:   resources.MemoryAvailable,
+				DiskPath:          resources.DiskPath,
+				DiskTotal:         resources.DiskTotal,
+				DiskFree:          resources.DiskFree,
+				NetworkRxRate:     resources.NetworkRxRate,
+				NetworkTxRate:     resources.NetworkTxRate,
+				Load1:             resources.Load1,
+				Load5:             resources.Load5,
+				Load15:            resources.Load15,
+				RunningContainers: int(resources.RunningContainers),
+				UptimeSeconds:     resources.UptimeSeconds,
+			})
+		}
+		s.registry.RecordHistory(nodeID)
+		s.receiveLogs(&LogBatch{NodeId: nodeID, Entries: metrics.Logs})
 
 		// Send commands to worker (if any)
 		s.mu.RLock()
@@ -123,36 +257,162 @@ func (s *Server) StartServer(port string) error {
 	if err != nil {
 		return fmt.Errorf("failed to listen on port %s: %v", port, err)
 	}
+	return s.Serve(lis)
+}
 
-	grpcServer := grpc.NewServer()
+// Serve serves the cluster service on lis until it fails, with TLS once
+// ConfigureTLS is.
+func (s *Server) Serve(lis net.Listener) error {
+	grpcServer := grpc.NewServer(
+		grpc.Creds(serverCredentials()),
+		grpc.ChainUnaryInterceptor(countUnaryServer, traceUnaryServer),
+		grpc.ChainStreamInterceptor(countStreamServer, traceStreamServer),
+	)
 	RegisterClusterServiceServer(grpcServer, s)
 
 	return grpcServer.Serve(lis)
 }
 
-// ExecuteFunction executes a Docker container on this worker node
+// ExecuteFunction executes a function (container or process) on this worker node
 func (s *Server) ExecuteFunction(ctx context.Context, req *FunctionRequest) (*FunctionResult, error) {
 	// Log the execution request
-	logging.Info("Received execution request for image '%s' (function: %s)", 
-		req.DockerImage, req.FunctionName)
+	log := logging.With(logging.KeyFunction, req.FunctionName, logging.KeyInvocationID, req.InvocationId)
+	log.InfoContext(ctx, "Received execution request", "image", req.DockerImage)
 	
-	// Execute the Docker container
-	output, err := executor.RunContainer(req.DockerImage)
+	started := time.Now()
+	workerExecutionsRunning.Add(1)
+	defer workerExecutionsRunning.Add(-1)
+	
+	// Run the container or process with the function's settings
+	result, err := executor.RunFunctionStream(ctx, req.Kind, req.DockerImage, runOptions(req), nil)
 	
 	if err != nil {
-		logging.Error("Container execution failed: %v", err)
-		return &FunctionResult{
-			Output:  "",
-			Success: false,
-			Error:   fmt.Sprintf("Container execution failed: %v", err),
-		}, nil
+		log.ErrorContext(ctx, "Function execution failed", "error", err)
+	} else {
+		log.InfoContext(ctx, "Function finished successfully", "output_bytes", len(result.Output))
+	}
+	if result != nil {
+		shipFunctionOutput(ctx, req, result.Stdout, result.Stderr)
 	}
 	
-	logging.Info("Container finished successfully. Output length: %d bytes", len(output))
-	
-	return &FunctionResult{
-		Output:  output,
-		Success: true,
-		Error:   "",
-	}, nil
+	functionResult := s.functionResult(result, err)
+	recordExecution(req.FunctionName, started, functionResult)
+	return functionResult, nil
+}
+
+// ExecuteFunctionStream executes a function on this worker node and
+// streams its stdout and stderr back while it runs. The final event carries the
+// FunctionResult; its output fields are left empty since they were already streamed.
+func (s *Server) ExecuteFunctionStream(req *FunctionRequest, stream grpc.ServerStreamingServer[ExecutionEvent]) error {
+	ctx := stream.Context()
+	log := logging.With(logging.KeyFunction, req.FunctionName, logging.KeyInvocationID, req.InvocationId)
+	log.InfoContext(ctx, "Received streaming execution request", "image", req.DockerImage)
+
+	started := time.Now()
+	workerExecutionsRunning.Add(1)
+	defer workerExecutionsRunning.Add(-1)
+
+	// Chunks are sent synchronously, so a slow client applies backpressure to the
+	// container through its output pipes instead of buffering here
+	var sendErr error
+	onOutput := func(name string, chunk []byte) {
+		if sendErr != nil {
+			return
+		}
+		sendErr = stream.Send(&ExecutionEvent{
+			Event: &ExecutionEvent_Output{Output: &OutputChunk{Stream: name, Data: chunk}},
+		})
+	}
+
+	result, err := executor.RunFunctionStream(ctx, req.Kind, req.DockerImage, runOptions(req), onOutput)
+	if sendErr != nil {
+		log.WarnContext(ctx, "Stopped streaming output", "error", sendErr)
+		return sendErr
+	}
+
+	if err != nil {
+		log.ErrorContext(ctx, "Function execution failed", "error", err)
+	} else {
+		log.InfoContext(ctx, "Function finished successfully", "output_bytes", len(result.Output))
+	}
+	if result != nil {
+		shipFunctionOutput(ctx, req, result.Stdout, result.Stderr)
+	}
+
+	// The output itself has already been streamed
+	final := s.functionResult(result, err)
+	final.Output, final.Stdout, final.Stderr = "", "", ""
+	recordExecution(req.FunctionName, started, final)
+
+	return stream.Send(&ExecutionEvent{Event: &ExecutionEvent_Result{Result: final}})
+}
+
+// runOptions converts the execution settings of a request into executor options
+func runOptions(req *FunctionRequest) executor.RunOptions {
+	return executor.RunOptions{
+		Timeout:         time.Duration(req.TimeoutSeconds) * time.Second,
+		MemoryMB:        int(req.MemoryMb),
+		Env:             req.Env,
+		MaxOutputBytes:  int(req.MaxOutputBytes),
+		Args:            req.Args,
+		Input:           req.Input,
+		PullPolicy:      req.PullPolicy,
+		RegistryAuth:    registryAuth(req.RegistryAuth),
+		Digest:          req.ImageDigest,
+		VerifySignature: req.VerifySignature,
+	}
+}
+
+// failureReason classifies an execution error for FunctionResult.FailureReason
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, executor.ErrDigestMismatch):
+		return FailureDigestMismatch
+	case errors.Is(err, executor.ErrSignatureVerification):
+		return FailureSignature
+	case errors.Is(err, executor.ErrImagePull):
+		return FailureImagePull
+	default:
+		return FailureExecution
+	}
+}
+
+// registryAuth converts registry credentials from their wire form
+func registryAuth(auth *RegistryAuth) *executor.RegistryAuth {
+	if auth == nil {
+		return nil
+	}
+	return &executor.RegistryAuth{
+		Server:   auth.Server,
+		Username: auth.Username,
+		Password: auth.Password,
+	}
+}
+
+// functionResult converts the outcome of a container run into a FunctionResult.
+// result may be nil if the container never started.
+func (s *Server) functionResult(result *executor.RunResult, err error) *FunctionResult {
+	functionResult := &FunctionResult{
+		Success:  err == nil,
+		ExitCode: -1,
+		NodeId:   s.nodeID,
+	}
+	if err != nil {
+		functionResult.Error = fmt.Sprintf("Execution failed: %v", err)
+		functionResult.FailureReason = failureReason(err)
+	}
+	if result == nil {
+		return functionResult
+	}
+
+	// Partial output is kept for failed runs, it usually explains the failure
+	functionResult.Output = result.Output
+	functionResult.Stdout = result.Stdout
+	functionResult.Stderr = result.Stderr
+	functionResult.OutputTruncated = result.Truncated
+	functionResult.ExitCode = int32(result.ExitCode)
+	functionResult.StartedAt = result.StartedAt.UnixMilli()
+	functionResult.FinishedAt = result.FinishedAt.UnixMilli()
+	functionResult.ImageDigest = result.ImageDigest
+	return functionResult
 }
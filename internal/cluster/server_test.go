@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cares/internal/executor"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestExecuteFunctionFailureReasons(t *testing.T) {
+	const image = "ghcr.io/acme/app:1"
+
+	tests := []struct {
+		name        string
+		digest      string
+		setup       func(runtime *executor.FakeRuntime)
+		wantSuccess bool
+		wantReason  string
+		wantDigest  string
+	}{
+		{
+			name:        "pinned",
+			digest:      testDigest,
+			wantSuccess: true,
+			wantDigest:  testDigest,
+		},
+		{
+			name:       "registry serving other content",
+			digest:     testDigest,
+			setup:      func(runtime *executor.FakeRuntime) { runtime.TamperRepository("ghcr.io/acme/app") },
+			wantReason: FailureDigestMismatch,
+		},
+		{
+			name:       "pull failing",
+			setup:      func(runtime *executor.FakeRuntime) { runtime.FailPull(image, errors.New("connection refused")) },
+			wantReason: FailureImagePull,
+		},
+		{
+			name:       "pull refused without login",
+			setup:      func(runtime *executor.FakeRuntime) { runtime.RequireAuth(image, "acme", "secret") },
+			wantReason: FailureImagePull,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime := executor.NewFakeRuntime()
+			if tt.setup != nil {
+				tt.setup(runtime)
+			}
+			executor.SetRuntime(runtime)
+			t.Cleanup(func() { executor.SetRuntime(nil) })
+
+			server := NewServer()
+			server.SetNodeID("worker-1")
+			result, err := server.ExecuteFunction(context.Background(), &FunctionRequest{
+				DockerImage:  image,
+				FunctionName: "app",
+				Kind:         executor.KindContainer,
+				ImageDigest:  tt.digest,
+			})
+			if err != nil {
+				t.Fatalf("ExecuteFunction: %v", err)
+			}
+			if result.Success != tt.wantSuccess || result.FailureReason != tt.wantReason {
+				t.Errorf("success = %v with reason %q, want %v with %q (%s)",
+					result.Success, result.FailureReason, tt.wantSuccess, tt.wantReason, result.Error)
+			}
+			if tt.wantDigest != "" && result.ImageDigest != tt.wantDigest {
+				t.Errorf("image digest = %s, want %s", result.ImageDigest, tt.wantDigest)
+			}
+			if !tt.wantSuccess && result.StartedAt != 0 {
+				t.Error("container was started")
+			}
+		})
+	}
+}
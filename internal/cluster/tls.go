@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Environment variables naming the PEM files the cluster channel is secured
+// with; see TLSConfig.
+const (
+	TLSCertVariable = "CARES_TLS_CERT"
+	TLSKeyVariable  = "CARES_TLS_KEY"
+	TLSCAVariable   = "CARES_TLS_CA"
+)
+
+// TLSConfig names the PEM files securing the gRPC channel between the
+// orchestrator and workers. Every node presents CertFile, the certificate of
+// its address, with the key in KeyFile. Peers are verified with the CAs in
+// CAFile, or the system roots if it is empty; with a CAFile, servers also
+// require clients to present a certificate it signed (mutual TLS).
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+// TLSConfigFromEnv returns the TLS configuration set in the environment.
+func TLSConfigFromEnv() TLSConfig {
+	return TLSConfig{
+		CertFile: os.Getenv(TLSCertVariable),
+		KeyFile:  os.Getenv(TLSKeyVariable),
+		CAFile:   os.Getenv(TLSCAVariable),
+	}
+}
+
+// transport holds the credentials of the cluster channel, plaintext until
+// ConfigureTLS is called with a certificate.
+var transport = struct {
+	mu     sync.RWMutex
+	secure bool
+	client credentials.TransportCredentials
+	server credentials.TransportCredentials
+}{
+	client: insecure.NewCredentials(),
+	server: insecure.NewCredentials(),
+}
+
+// ConfigureTLS secures the connections dialed and the servers started from
+// now on with config, or makes them plaintext again if it has no certificate.
+func ConfigureTLS(config TLSConfig) error {
+	if config.CertFile == "" && config.KeyFile == "" {
+		if config.CAFile != "" {
+			return fmt.Errorf("%s is set without a certificate and key", TLSCAVariable)
+		}
+		transport.mu.Lock()
+		defer transport.mu.Unlock()
+		transport.secure = false
+		transport.client = insecure.NewCredentials()
+		transport.server = insecure.NewCredentials()
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	client := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	server := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in TLS CA file '%s'", config.CAFile)
+		}
+		client.RootCAs = pool
+		server.ClientCAs = pool
+		server.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+	transport.secure = true
+	transport.client = credentials.NewTLS(client)
+	transport.server = credentials.NewTLS(server)
+	return nil
+}
+
+// Secure reports whether the cluster channel is secured with TLS. Secrets,
+// such as registry credentials, are only sent to workers over a secure channel.
+func Secure() bool {
+	transport.mu.RLock()
+	defer transport.mu.RUnlock()
+	return transport.secure
+}
+
+// clientCredentials returns the credentials connections are dialed with.
+func clientCredentials() credentials.TransportCredentials {
+	transport.mu.RLock()
+	defer transport.mu.RUnlock()
+	return transport.client
+}
+
+// serverCredentials returns the credentials servers are started with.
+func serverCredentials() credentials.TransportCredentials {
+	transport.mu.RLock()
+	defer transport.mu.RUnlock()
+	return transport.server
+}
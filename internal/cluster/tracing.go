@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"cares/internal/tracing"
+)
+
+// gRPC calls join the trace of their context: the client opens a span and
+// sends its traceparent in the call metadata, and the server continues the
+// trace from it. Calls made outside a trace, such as the long-lived heartbeat
+// and log streams, are not traced.
+
+// traceUnaryClient is a client interceptor tracing unary calls.
+func traceUnaryClient(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !tracing.SpanContextFromContext(ctx).IsValid() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	ctx, span := startClientSpan(ctx, method, cc.Target())
+	defer span.End()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	span.RecordError(err)
+	return err
+}
+
+// traceStreamClient is a client interceptor tracing streams. The span ends
+// when the stream is finished or its context is cancelled.
+func traceStreamClient(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if !tracing.SpanContextFromContext(ctx).IsValid() {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	ctx, span := startClientSpan(ctx, method, cc.Target())
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		span.End()
+	}()
+	return &tracedClientStream{ClientStream: stream, span: span}, nil
+}
+
+// startClientSpan opens the span of an outgoing call and adds its traceparent
+// to the call metadata.
+func startClientSpan(ctx context.Context, method, target string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.StartKind(ctx, method, tracing.KindClient,
+		slog.String("rpc.system", "grpc"), slog.String("rpc.method", method), slog.String("server.address", target))
+	return metadata.AppendToOutgoingContext(ctx, tracing.TraceparentHeader, span.Context().Traceparent()), span
+}
+
+// tracedClientStream ends the span of a stream once it has been read to the end.
+type tracedClientStream struct {
+	grpc.ClientStream
+	span *tracing.Span
+}
+
+// RecvMsg receives a message, ending the span when the stream is finished.
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		if err != io.EOF {
+			s.span.RecordError(err)
+		}
+		s.span.End()
+	}
+	return err
+}
+
+// traceUnaryServer is a server interceptor continuing the caller's trace.
+func traceUnaryServer(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, span, ok := startServerSpan(ctx, info.FullMethod)
+	if !ok {
+		return handler(ctx, req)
+	}
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	span.RecordError(err)
+	return resp, err
+}
+
+// traceStreamServer is a server interceptor continuing the caller's trace on
+// streams.
+func traceStreamServer(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, span, ok := startServerSpan(stream.Context(), info.FullMethod)
+	if !ok {
+		return handler(srv, stream)
+	}
+	defer span.End()
+
+	err := handler(srv, &tracedServerStream{ServerStream: stream, ctx: ctx})
+	span.RecordError(err)
+	return err
+}
+
+// startServerSpan opens the span of an incoming call whose metadata carries a
+// traceparent. It reports false if there is none.
+func startServerSpan(ctx context.Context, method string) (context.Context, *tracing.Span, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(tracing.TraceparentHeader)
+	if len(values) == 0 {
+		return ctx, nil, false
+	}
+	parent, ok := tracing.ParseTraceparent(values[0])
+	if !ok {
+		return ctx, nil, false
+	}
+
+	ctx, span := tracing.StartKind(tracing.WithRemote(ctx, parent), method, tracing.KindServer,
+		slog.String("rpc.system", "grpc"), slog.String("rpc.method", method))
+	return ctx, span, true
+}
+
+// tracedServerStream hands the handler a context carrying the call's span.
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context carrying the span.
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,96 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"cares/internal/fsutil"
+	"cares/internal/logging"
+)
+
+// keySize is the AES-256 key length in bytes
+const keySize = 32
+
+// LoadKey returns the store encryption key. CARES_SECRET_KEY (base64, 32
+// bytes) takes precedence; otherwise the key is read from keyPath, which is
+// generated with mode 0600 on first use.
+func LoadKey(keyPath string) ([]byte, error) {
+	if encoded := os.Getenv("CARES_SECRET_KEY"); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("CARES_SECRET_KEY must be %d base64-encoded bytes", keySize)
+		}
+		return key, nil
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("key file '%s' is corrupt", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(keyPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	logging.Info("Generated credential encryption key at '%s'", keyPath)
+	return key, nil
+}
+
+// seal encrypts plaintext with AES-GCM, binding it to name so sealed secrets
+// can't be swapped between credentials. The result is base64(nonce|ciphertext).
+func seal(key []byte, plaintext, name string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open reverses seal.
+func open(key []byte, secret, name string) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed secret")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD creates the AES-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,214 @@
+// Package credentials keeps the private registry credentials functions use to
+// pull their images. Credentials live only on the orchestrator: passwords are
+// encrypted at rest with AES-256-GCM and are handed to a worker only inside the
+// execution request that needs them.
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cares/internal/fsutil"
+)
+
+// Default locations of the credential store and its encryption key
+const (
+	DefaultStorePath = "data/credentials.json"
+	DefaultKeyPath   = "data/credentials.key"
+)
+
+var (
+	// ErrNotFound is returned when no credential has the requested name.
+	ErrNotFound = errors.New("credential not found")
+	// ErrDuplicateName is returned when adding a credential whose name is taken.
+	ErrDuplicateName = errors.New("credential name already exists")
+	// ErrInvalid is returned when adding a credential that fails validation.
+	ErrInvalid = errors.New("invalid credential")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,62}$`)
+
+// Credential is a registry login. Password is never serialized to JSON; the
+// store persists it encrypted.
+type Credential struct {
+	Name      string    `json:"name"`
+	Registry  string    `json:"registry"` // Registry host, e.g. "ghcr.io" or "registry.local:5000"
+	Username  string    `json:"username"`
+	Password  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// sealedCredential is the on-disk form of a Credential.
+type sealedCredential struct {
+	Credential
+	Secret string `json:"secret"` // Encrypted password
+}
+
+// Validate checks that the credential is complete and well formed.
+func (c Credential) Validate() error {
+	switch {
+	case !namePattern.MatchString(c.Name):
+		return fmt.Errorf("name must be 1-63 letters, digits, '.', '_' or '-'")
+	case c.Registry == "" || strings.ContainsAny(c.Registry, "/ \t\n"):
+		return fmt.Errorf("registry must be a host name such as 'ghcr.io'")
+	case c.Username == "":
+		return fmt.Errorf("username is required")
+	case c.Password == "":
+		return fmt.Errorf("password is required")
+	}
+	return nil
+}
+
+// Store is a thread-safe, file-backed set of credentials.
+type Store struct {
+	mu          sync.RWMutex
+	path        string
+	key         []byte
+	credentials map[string]*Credential
+}
+
+// OpenDefaultStore opens the store at DefaultStorePath, taking the encryption
+// key from CARES_SECRET_KEY or, if unset, from DefaultKeyPath.
+func OpenDefaultStore() (*Store, error) {
+	key, err := LoadKey(DefaultKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return OpenStore(DefaultStorePath, key)
+}
+
+// OpenStore opens or creates a credential store at path, encrypted with key.
+func OpenStore(path string, key []byte) (*Store, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", keySize)
+	}
+
+	store := &Store{
+		path:        path,
+		key:         key,
+		credentials: make(map[string]*Credential),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential store: %w", err)
+	}
+
+	var sealed []sealedCredential
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to parse credential store: %w", err)
+	}
+	for _, entry := range sealed {
+		password, err := open(key, entry.Secret, entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt credential '%s' (wrong key?): %w", entry.Name, err)
+		}
+		credential := entry.Credential
+		credential.Password = password
+		store.credentials[credential.Name] = &credential
+	}
+
+	return store, nil
+}
+
+// Add stores a new credential.
+func (s *Store) Add(credential Credential) (*Credential, error) {
+	if err := credential.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	credential.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.credentials[credential.Name]; exists {
+		return nil, fmt.Errorf("credential '%s': %w", credential.Name, ErrDuplicateName)
+	}
+
+	s.credentials[credential.Name] = &credential
+	if err := s.saveLocked(); err != nil {
+		delete(s.credentials, credential.Name)
+		return nil, err
+	}
+
+	stored := credential
+	return &stored, nil
+}
+
+// Get returns the credential with the given name, including its password.
+func (s *Store) Get(name string) (*Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credential, exists := s.credentials[name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	stored := *credential
+	return &stored, nil
+}
+
+// List returns every credential sorted by name, with passwords removed.
+func (s *Store) List() []Credential {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Credential, 0, len(s.credentials))
+	for _, credential := range s.credentials {
+		redacted := *credential
+		redacted.Password = ""
+		list = append(list, redacted)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Remove deletes a credential.
+func (s *Store) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	credential, exists := s.credentials[name]
+	if !exists {
+		return ErrNotFound
+	}
+
+	delete(s.credentials, name)
+	if err := s.saveLocked(); err != nil {
+		s.credentials[name] = credential
+		return err
+	}
+	return nil
+}
+
+// saveLocked encrypts every password and writes the store. The caller must hold s.mu.
+func (s *Store) saveLocked() error {
+	sealed := make([]sealedCredential, 0, len(s.credentials))
+	for _, credential := range s.credentials {
+		secret, err := seal(s.key, credential.Password, credential.Name)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt credential '%s': %w", credential.Name, err)
+		}
+		sealed = append(sealed, sealedCredential{Credential: *credential, Secret: secret})
+	}
+	sort.Slice(sealed, func(i, j int) bool { return sealed[i].Name < sealed[j].Name })
+
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credential store: %w", err)
+	}
+	if err := fsutil.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write credential store: %w", err)
+	}
+	return nil
+}
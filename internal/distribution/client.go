@@ -0,0 +1,286 @@
+// Package distribution is a minimal client for the OCI distribution (registry
+// v2) HTTP API. The orchestrator uses it to resolve image tags to the manifest
+// digests they point at, and workers use it to fetch image signatures.
+package distribution
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"cares/internal/imageref"
+)
+
+// Limits applied to registry requests
+const (
+	requestTimeout   = 30 * time.Second
+	maxManifestBytes = 4 << 20 // Manifests and indexes are small JSON documents
+	maxBlobBytes     = 4 << 20 // Only small blobs such as signature payloads are fetched
+)
+
+// ErrNotFound is returned when a registry has no manifest or blob for a reference.
+var ErrNotFound = errors.New("not found in registry")
+
+// manifestTypes are the manifest media types accepted when resolving a tag.
+// Indexes come first so multi-platform tags resolve to the digest runtimes
+// record after pulling them.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// challengeParam matches one key="value" pair of a WWW-Authenticate header
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Credentials is a registry login.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to container registries. It is safe for concurrent use.
+type Client struct {
+	http     *http.Client
+	insecure map[string]bool // Registries reached over plain HTTP, never modified after NewClient
+}
+
+// NewClient creates a registry client. Registries on localhost and those listed
+// in CARES_INSECURE_REGISTRIES (comma-separated host[:port]) are reached over
+// plain HTTP, all others over HTTPS.
+func NewClient() *Client {
+	client := &Client{
+		http:     &http.Client{Timeout: requestTimeout},
+		insecure: make(map[string]bool),
+	}
+	for _, host := range strings.Split(os.Getenv("CARES_INSECURE_REGISTRIES"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			client.insecure[imageref.NormalizeRegistry(host)] = true
+		}
+	}
+	return client
+}
+
+// ResolveDigest returns the manifest digest image currently points at,
+// logging in with creds if it is not nil. References that already carry a
+// digest are returned without asking the registry.
+func (c *Client) ResolveDigest(ctx context.Context, image string, creds *Credentials) (string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	session := c.session(ref, creds)
+	resp, err := session.do(ctx, http.MethodHead, "/manifests/"+ref.Tag, manifestTypes)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); imageref.ValidDigest(digest) {
+		return digest, nil
+	}
+
+	// Not every registry sets the header on HEAD requests; hashing the
+	// manifest itself always works
+	manifest, _, err := session.manifest(ctx, ref.Tag)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(manifest)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// session is a series of requests to one repository, sharing the
+// authorization obtained by the first of them.
+type session struct {
+	client        *Client
+	ref           imageref.Reference
+	creds         *Credentials
+	authorization string
+}
+
+// session starts a series of requests to the repository of ref.
+func (c *Client) session(ref imageref.Reference, creds *Credentials) *session {
+	return &session{client: c, ref: ref, creds: creds}
+}
+
+// baseURL returns the API endpoint of the session's repository.
+func (s *session) baseURL() string {
+	host := s.ref.Registry
+	if host == imageref.DefaultRegistry {
+		host = "registry-1.docker.io"
+	}
+
+	scheme := "https"
+	if s.client.insecure[s.ref.Registry] || strings.HasPrefix(host, "localhost") || strings.HasPrefix(host, "127.0.0.1") {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s", scheme, host, s.ref.Repository)
+}
+
+// manifest fetches a manifest by tag or digest and returns it with its media type.
+func (s *session) manifest(ctx context.Context, reference string) ([]byte, string, error) {
+	resp, err := s.do(ctx, http.MethodGet, "/manifests/"+reference, manifestTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if len(data) > maxManifestBytes {
+		return nil, "", fmt.Errorf("manifest exceeds %d bytes", maxManifestBytes)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// blob fetches a small blob and checks it against its digest.
+func (s *session) blob(ctx context.Context, digest string) ([]byte, error) {
+	if !strings.HasPrefix(digest, "sha256:") || !imageref.ValidDigest(digest) {
+		return nil, fmt.Errorf("unsupported blob digest '%s'", digest)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, "/blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBlobBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	if len(data) > maxBlobBytes {
+		return nil, fmt.Errorf("blob %s exceeds %d bytes", digest, maxBlobBytes)
+	}
+	sum := sha256.Sum256(data)
+	if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("blob %s does not match its digest", digest)
+	}
+	return data, nil
+}
+
+// do sends a request to the repository API, authenticating as the registry
+// demands, and returns the response if it succeeded.
+func (s *session) do(ctx context.Context, method, path string, accept []string) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, s.baseURL()+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if s.authorization != "" {
+			req.Header.Set("Authorization", s.authorization)
+		}
+		return s.client.http.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, fmt.Errorf("registry request failed: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized && s.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if s.authorization, err = s.authorize(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = send(); err != nil {
+			return nil, fmt.Errorf("registry request failed: %w", err)
+		}
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s of %s: %w", strings.TrimPrefix(path, "/"), s.ref.Name(), ErrNotFound)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry %s returned %s", s.ref.Registry, resp.Status)
+	}
+}
+
+// authorize answers a WWW-Authenticate challenge, returning the Authorization
+// header to send. Bearer challenges are exchanged for a pull-scoped token.
+func (s *session) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if s.creds == nil {
+			return "", fmt.Errorf("registry %s requires credentials", s.ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(s.creds.Username+":"+s.creds.Password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s uses unsupported authentication '%s'", s.ref.Registry, scheme)
+	}
+
+	values := make(map[string]string)
+	for _, match := range challengeParam.FindAllStringSubmatch(params, -1) {
+		values[strings.ToLower(match[1])] = match[2]
+	}
+	if values["realm"] == "" {
+		return "", fmt.Errorf("registry %s sent a token challenge without realm", s.ref.Registry)
+	}
+	scope := values["scope"]
+	if scope == "" {
+		scope = "repository:" + s.ref.Repository + ":pull"
+	}
+
+	query := url.Values{"scope": {scope}}
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, values["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm: %w", err)
+	}
+	if s.creds != nil {
+		req.SetBasicAuth(s.creds.Username, s.creds.Password)
+	}
+
+	resp, err := s.client.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("registry token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s refused a token: %s", s.ref.Registry, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestBytes)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid registry token response: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("registry %s returned an empty token", s.ref.Registry)
+	}
+	return "Bearer " + token.Token, nil
+}
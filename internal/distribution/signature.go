@@ -0,0 +1,150 @@
+package distribution
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"cares/internal/imageref"
+)
+
+// Signatures follow the cosign layout: they are stored in the image's own
+// repository under the tag "sha256-<hex>.sig", one layer per signature, with
+// the signed payload as the layer blob and the signature in an annotation.
+const (
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	signaturePayload    = "cosign container image signature"
+)
+
+// ErrNoSignature is returned when an image has no signature at all.
+var ErrNoSignature = errors.New("image is not signed")
+
+// signatureManifest is the part of a signature manifest needed for verification.
+type signatureManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// simpleSigning is the payload a signature covers.
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// LoadPublicKeys reads PEM-encoded public keys (ECDSA, Ed25519 or RSA, as
+// written by "cosign generate-key-pair") from the given files.
+func LoadPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		for rest := data; ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "PUBLIC KEY" {
+				continue
+			}
+			key, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid public key in '%s': %w", path, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 && len(paths) > 0 {
+		return nil, fmt.Errorf("no PEM public keys found in %s", strings.Join(paths, ", "))
+	}
+	return keys, nil
+}
+
+// VerifySignature checks that the manifest digest of image carries at least
+// one signature made by one of keys. Signatures are fetched from the image's
+// registry, logging in with creds if it is not nil.
+func (c *Client) VerifySignature(ctx context.Context, image, digest string, keys []crypto.PublicKey, creds *Credentials) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no signature verification keys configured")
+	}
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return err
+	}
+	if !imageref.ValidDigest(digest) {
+		return fmt.Errorf("invalid digest '%s'", digest)
+	}
+
+	session := c.session(ref, creds)
+	data, _, err := session.manifest(ctx, strings.Replace(digest, ":", "-", 1)+".sig")
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s@%s: %w", ref.Name(), digest, ErrNoSignature)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch signatures: %w", err)
+	}
+
+	var manifest signatureManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid signature manifest: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[signatureAnnotation])
+		if err != nil || len(signature) == 0 {
+			continue
+		}
+		payload, err := session.blob(ctx, layer.Digest)
+		if err != nil {
+			return fmt.Errorf("failed to fetch signature payload: %w", err)
+		}
+
+		// The payload must name this very manifest, or a signature of
+		// another image could be replayed here
+		var signed simpleSigning
+		if err := json.Unmarshal(payload, &signed); err != nil ||
+			signed.Critical.Type != signaturePayload ||
+			signed.Critical.Image.DockerManifestDigest != digest {
+			continue
+		}
+		for _, key := range keys {
+			if verify(key, payload, signature) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no signature of %s@%s matches a trusted key", ref.Name(), digest)
+}
+
+// verify checks a signature over payload with key.
+func verify(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cares/internal/fsutil"
+)
+
+// DefaultAuditPath is the default location of the audit log.
+const DefaultAuditPath = "data/audit.jsonl"
+
+// DefaultAuditMemory is how many of the latest events an AuditLog keeps in
+// memory for queries.
+const DefaultAuditMemory = 10000
+
+// DefaultAuditRotateSize is the size at which an AuditLog rotates its file.
+const DefaultAuditRotateSize = 64 << 20
+
+// auditTailChunk is how much of the file load reads at a time, from the end.
+const auditTailChunk = 64 * 1024
+
+// AuditLog is an append-only, persistent record of events. Events are
+// appended to a JSON-lines file that is never rewritten, so nothing is lost
+// or altered. Once the file reaches its rotate size it is renamed after the
+// Seq of its last event, e.g. "audit.jsonl.1234", and a new file is started;
+// archived files are never deleted. The latest events are also kept in
+// memory, and only those can be queried.
+//
+// The highest Seq is also kept in a ".seq" file next to the log, so event
+// numbering carries on across rotations, including ones done externally.
+type AuditLog struct {
+	mu         sync.RWMutex
+	path       string
+	memory     int
+	rotateSize int64
+	events     []Event // Latest events, oldest first
+	lastSeq    uint64
+	file       *os.File
+	size       int64 // Bytes in the file
+}
+
+// OpenAuditLog loads the latest memory events stored at path (if any) and
+// opens it for appending. memory <= 0 uses DefaultAuditMemory.
+func OpenAuditLog(path string, memory int) (*AuditLog, error) {
+	if memory <= 0 {
+		memory = DefaultAuditMemory
+	}
+	a := &AuditLog{path: path, memory: memory, rotateSize: DefaultAuditRotateSize}
+
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+	if err := a.loadSeq(); err != nil {
+		return nil, err
+	}
+	if err := a.openFile(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// SetRotateSize sets the size in bytes at which the log file is rotated,
+// 0 to never rotate it.
+func (a *AuditLog) SetRotateSize(size int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rotateSize = size
+}
+
+// Append writes an event to the log.
+func (a *AuditLog) Append(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+	n, err := a.file.Write(append(data, '\n'))
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to append event: %v", err)
+	}
+	a.remember(event)
+
+	if a.rotateSize > 0 && a.size >= a.rotateSize {
+		if err := a.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %v", err)
+		}
+	}
+	return nil
+}
+
+// Query returns the matching events held in memory newest first, honouring
+// the filter's pagination, together with the total number of matches.
+func (a *AuditLog) Query(filter Filter) ([]Event, int) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var matches []Event
+	for i := len(a.events) - 1; i >= 0; i-- {
+		if filter.Matches(a.events[i]) {
+			matches = append(matches, a.events[i])
+		}
+	}
+
+	total := len(matches)
+	if filter.Offset >= total {
+		return []Event{}, total
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches, total
+}
+
+// LastSeq returns the Seq of the latest event in the log, 0 if it is empty.
+func (a *AuditLog) LastSeq() uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.lastSeq
+}
+
+// Close records the highest Seq and closes the log file.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	seqErr := a.saveSeq()
+	err := a.file.Close()
+	a.file = nil
+	if err == nil {
+		err = seqErr
+	}
+	return err
+}
+
+// rotate archives the log file under the Seq of its last event and starts a
+// new one. The caller must hold a.mu.
+func (a *AuditLog) rotate() error {
+	// The new file starts empty, so the numbering must be carried over first
+	if err := a.saveSeq(); err != nil {
+		return err
+	}
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	a.file = nil
+	if err := os.Rename(a.path, a.path+"."+strconv.FormatUint(a.lastSeq, 10)); err != nil {
+		// Keep appending to the full file rather than losing events
+		if reopenErr := a.openFile(); reopenErr != nil {
+			return reopenErr
+		}
+		return err
+	}
+	return a.openFile()
+}
+
+// openFile opens the log file for appending. The caller must hold a.mu (or
+// own a exclusively).
+func (a *AuditLog) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// seqPath returns the path of the file holding the highest Seq.
+func (a *AuditLog) seqPath() string {
+	return a.path + ".seq"
+}
+
+// saveSeq records the highest Seq. The caller must hold a.mu (or own a
+// exclusively).
+func (a *AuditLog) saveSeq() error {
+	data := []byte(strconv.FormatUint(a.lastSeq, 10) + "\n")
+	if err := fsutil.WriteFileAtomic(a.seqPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to record audit log seq: %v", err)
+	}
+	return nil
+}
+
+// loadSeq raises lastSeq to the recorded highest Seq, if it is higher, and
+// records the result so numbering survives the file being moved away.
+func (a *AuditLog) loadSeq() error {
+	data, err := os.ReadFile(a.seqPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read audit log seq: %v", err)
+	}
+	if err == nil {
+		seq, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid audit log seq file %s: %v", a.seqPath(), err)
+		}
+		if seq > a.lastSeq {
+			a.lastSeq = seq
+		}
+	}
+	return a.saveSeq()
+}
+
+// remember keeps an event in memory, forgetting the oldest beyond the limit.
+// The caller must hold a.mu (or own a exclusively).
+func (a *AuditLog) remember(event Event) {
+	a.events = append(a.events, event)
+	if len(a.events) > a.memory {
+		// Copy once the slice has doubled, so memory stays bounded
+		if cap(a.events) > 2*a.memory {
+			a.events = append([]Event(nil), a.events[len(a.events)-a.memory:]...)
+		} else {
+			a.events = a.events[len(a.events)-a.memory:]
+		}
+	}
+	if event.Seq > a.lastSeq {
+		a.lastSeq = event.Seq
+	}
+}
+
+// load reads the latest events stored in the log file, reading backwards
+// from its end so a large file is not scanned in full. A missing file is an
+// empty log.
+func (a *AuditLog) load() error {
+	file, err := os.Open(a.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %v", err)
+	}
+
+	// Read chunks from the end until they hold more lines than are kept; the
+	// first line is then dropped, since it may start before the data read
+	var tail []byte
+	offset := info.Size()
+	for offset > 0 && bytes.Count(tail, []byte("\n")) <= a.memory {
+		chunk := int64(auditTailChunk)
+		if chunk > offset {
+			chunk = offset
+		}
+		offset -= chunk
+		buf := make([]byte, chunk, chunk+int64(len(tail)))
+		if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read audit log: %v", err)
+		}
+		tail = append(buf, tail...)
+	}
+	lines := bytes.Split(tail, []byte("\n"))
+	if offset > 0 {
+		lines = lines[1:]
+	}
+
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			// A torn final line after a crash is expected; skip it
+			continue
+		}
+		a.remember(event)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// appendEvents appends events numbered from first to last.
+func appendEvents(t *testing.T, audit *AuditLog, first, last uint64) {
+	t.Helper()
+	for seq := first; seq <= last; seq++ {
+		if err := audit.Append(Event{Seq: seq, Type: NodeJoined, Subject: "worker-1"}); err != nil {
+			t.Fatalf("append %d: %v", seq, err)
+		}
+	}
+}
+
+func TestAuditLogLoadsTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	appendEvents(t, audit, 1, 2000)
+	audit.Close()
+
+	audit, err = OpenAuditLog(path, 100)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer audit.Close()
+
+	events, total := audit.Query(Filter{})
+	if total != 100 || events[0].Seq != 2000 || events[99].Seq != 1901 {
+		t.Errorf("loaded %d events, %d to %d, want 100 from 2000 to 1901", total, events[0].Seq, events[len(events)-1].Seq)
+	}
+	if audit.LastSeq() != 2000 {
+		t.Errorf("LastSeq = %d, want 2000", audit.LastSeq())
+	}
+}
+
+func TestAuditLogRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	audit.SetRotateSize(1024)
+	appendEvents(t, audit, 1, 50)
+
+	archives, _ := filepath.Glob(path + ".[0-9]*")
+	if len(archives) == 0 {
+		t.Fatal("log was not rotated")
+	}
+	if _, total := audit.Query(Filter{}); total != 50 {
+		t.Errorf("%d events in memory after rotating, want 50", total)
+	}
+
+	// A crash right after rotating leaves a new, nearly empty file
+	audit.mu.Lock()
+	audit.file.Close()
+	audit.file = nil
+	audit.mu.Unlock()
+	os.WriteFile(path, nil, 0644)
+
+	reopened, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.LastSeq() < 40 {
+		t.Errorf("LastSeq after rotation = %d, want the seq carried over", reopened.LastSeq())
+	}
+}
+
+func TestAuditLogSurvivesExternalRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	appendEvents(t, audit, 1, 10)
+	audit.Close()
+
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	bus := NewBus()
+	audit, err = OpenAuditLog(path, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer audit.Close()
+	bus.SetAuditLog(audit)
+	if bus.LastSeq() != 10 {
+		t.Errorf("numbering restarts after %d, want 10", bus.LastSeq())
+	}
+}
@@ -0,0 +1,284 @@
+// Package events carries typed cluster events, such as nodes joining and
+// leaving, function changes and finished invocations, from the components they
+// happen in to whoever is interested: the audit log, live API streams and
+// webhooks.
+//
+// Components publish to the process-wide bus with Publish. Every event records
+// its actor: who caused it, taken from the context the change was made with
+// (see WithActor), so REST calls are attributed to their caller and TUI actions
+// to the TUI. Subscribers receive events as they are published; when an
+// AuditLog is attached, every event is also appended to it first.
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"cares/internal/logging"
+	"cares/internal/tracing"
+)
+
+// Type identifies the kind of an event. Types are named "category.action".
+type Type string
+
+// Event types
+const (
+	NodeJoined        Type = "node.joined"         // A worker joined the cluster
+	NodeDisconnected  Type = "node.disconnected"   // A worker's heartbeat stream ended
+	NodeRemoved       Type = "node.removed"        // A worker was removed from the registry
+	NodeRuntimeDown   Type = "node.runtime_down"   // A worker's container runtime became unavailable
+	NodeRuntimeUp     Type = "node.runtime_up"     // A worker's container runtime is available again
+	NodeCommandQueued Type = "node.command_queued" // A command was queued for a worker, data: command, argument
+
+	FunctionRegistered       Type = "function.registered"
+	FunctionUpdated          Type = "function.updated"
+	FunctionDeleted          Type = "function.deleted"
+	FunctionStatusChanged    Type = "function.status_changed"    // data: status
+	FunctionScheduleChanged  Type = "function.schedule_changed"  // data: windows
+	FunctionVersionPublished Type = "function.version_published" // data: version, image
+	FunctionTrafficChanged   Type = "function.traffic_changed"   // data: weights
+	// A function's invocations keep failing, data: consecutive_failures, error,
+	// failure_reason. Published once when the failures start, unlike
+	// InvocationFailed, so it is the event to alert on.
+	FunctionFailing Type = "function.failing"
+	// A failing function succeeded again, data: failed_invocations
+	FunctionRecovered Type = "function.recovered"
+
+	InvocationSucceeded Type = "invocation.succeeded"
+	InvocationFailed    Type = "invocation.failed" // data: error, failure_reason
+)
+
+// Types lists every event type, in the order above.
+var Types = []Type{
+	NodeJoined, NodeDisconnected, NodeRemoved, NodeRuntimeDown, NodeRuntimeUp, NodeCommandQueued,
+	FunctionRegistered, FunctionUpdated, FunctionDeleted, FunctionStatusChanged, FunctionScheduleChanged,
+	FunctionVersionPublished, FunctionTrafficChanged, FunctionFailing, FunctionRecovered,
+	InvocationSucceeded, InvocationFailed,
+}
+
+// Category returns the part of the type before the dot, e.g. "node".
+func (t Type) Category() string {
+	category, _, _ := strings.Cut(string(t), ".")
+	return category
+}
+
+// Known reports whether t is one of Types or one of their categories.
+func Known(t Type) bool {
+	for _, known := range Types {
+		if t == known || string(t) == known.Category() {
+			return true
+		}
+	}
+	return false
+}
+
+// Actors of changes not made by an API caller
+const (
+	ActorSystem = "system" // The orchestrator itself, e.g. noticing a lost heartbeat
+	ActorTUI    = "tui"    // An operator using the terminal UI
+)
+
+// NodeActor returns the actor of events caused by a worker itself.
+func NodeActor(nodeID string) string {
+	return "node:" + nodeID
+}
+
+// Event is something that happened in the cluster.
+type Event struct {
+	Seq     uint64            `json:"seq"` // Position in the audit log, increasing
+	ID      string            `json:"id"`
+	Time    time.Time         `json:"time"`
+	Type    Type              `json:"type"`
+	Actor   string            `json:"actor"`             // Who caused the event
+	Subject string            `json:"subject,omitempty"` // ID of the node, function or invocation concerned
+	Name    string            `json:"name,omitempty"`    // Name of the function concerned, or hostname of the node
+	TraceID string            `json:"trace_id,omitempty"`
+	Data    map[string]string `json:"data,omitempty"` // Details, depending on Type
+}
+
+type actorKey struct{}
+
+// WithActor returns a context attributing changes made with it to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, ActorSystem if none.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return ActorSystem
+}
+
+// Filter selects events. Empty fields match everything.
+type Filter struct {
+	Types    []Type    // Event types or categories, e.g. "node.joined" or "function"
+	Actor    string    // Exact actor
+	Subject  string    // Exact subject
+	Since    time.Time // Only events at or after this time
+	AfterSeq uint64    // Only events with a greater Seq
+	Limit    int       // Maximum number of events returned by queries (0 = no limit)
+	Offset   int       // Number of matching events queries skip
+}
+
+// Matches reports whether an event passes the filter, ignoring pagination.
+func (f Filter) Matches(e Event) bool {
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if e.Type == t || string(t) == e.Type.Category() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Subject != "" && e.Subject != f.Subject {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return e.Seq > f.AfterSeq
+}
+
+// subscriptionBuffer is how many events a subscriber may fall behind before
+// events are dropped for it.
+const subscriptionBuffer = 256
+
+// Subscription receives the published events matching its filter on C until
+// it is closed. A subscriber that falls too far behind misses events rather
+// than holding up publishers.
+type Subscription struct {
+	C <-chan Event
+
+	bus     *Bus
+	filter  Filter
+	events  chan Event
+	dropped int // Guarded by bus.mu
+}
+
+// Close stops the subscription and closes C.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subscriptions[s]; ok {
+		delete(s.bus.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// Bus numbers published events, appends them to the audit log if one is
+// attached and hands them to the subscribers. It is safe for concurrent use.
+type Bus struct {
+	mu            sync.Mutex
+	seq           uint64
+	audit         *AuditLog
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewBus creates a bus without audit log or subscribers.
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[*Subscription]struct{})}
+}
+
+var defaultBus = NewBus()
+
+// Default returns the process-wide bus Publish publishes to.
+func Default() *Bus {
+	return defaultBus
+}
+
+// Publish publishes an event on the process-wide bus. See Bus.Publish.
+func Publish(ctx context.Context, event Event) {
+	defaultBus.Publish(ctx, event)
+}
+
+// SetAuditLog attaches the audit log every event is appended to, nil to
+// detach it. Numbering continues after the last event in the log.
+func (b *Bus) SetAuditLog(audit *AuditLog) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.audit = audit
+	if audit != nil && audit.LastSeq() > b.seq {
+		b.seq = audit.LastSeq()
+	}
+}
+
+// AuditLog returns the attached audit log, nil if there is none.
+func (b *Bus) AuditLog() *AuditLog {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.audit
+}
+
+// Publish numbers and timestamps an event and delivers it. The actor and
+// trace are taken from ctx unless the event sets them.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Actor == "" {
+		event.Actor = ActorFromContext(ctx)
+	}
+	if event.TraceID == "" {
+		event.TraceID = tracing.TraceIDFromContext(ctx)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.Seq = b.seq
+	if b.audit != nil {
+		if err := b.audit.Append(event); err != nil {
+			logging.Warn("Failed to append %s event to the audit log: %v", event.Type, err)
+		}
+	}
+
+	for subscription := range b.subscriptions {
+		if !subscription.filter.Matches(event) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			subscription.dropped++
+			if subscription.dropped == 1 {
+				logging.Warn("Event subscriber is falling behind, dropping %s events", event.Type)
+			}
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events published from now on that
+// match filter. Its Limit and Offset are ignored.
+func (b *Bus) Subscribe(filter Filter) *Subscription {
+	events := make(chan Event, subscriptionBuffer)
+	subscription := &Subscription{C: events, bus: b, filter: filter, events: events}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+// LastSeq returns the Seq of the latest published event, 0 if there is none.
+func (b *Bus) LastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"cares/internal/logging"
+)
+
+// CLIRuntime drives a Docker-compatible command line client, such as docker or
+// podman, for hosts where the Engine API socket is not accessible.
+type CLIRuntime struct {
+	binary string
+}
+
+// NewCLIRuntime creates a runtime invoking the given docker-compatible binary.
+func NewCLIRuntime(binary string) *CLIRuntime {
+	return &CLIRuntime{binary: binary}
+}
+
+// Name implements Runtime.
+func (r *CLIRuntime) Name() string {
+	if r.binary == "docker" {
+		return RuntimeDockerCLI
+	}
+	return r.binary
+}
+
+// Version implements Runtime.
+func (r *CLIRuntime) Version(ctx context.Context) (string, error) {
+	// docker reports the daemon under .Server, podman runs without a daemon
+	format := "{{.Server.Version}}"
+	if r.binary == "podman" {
+		format = "{{.Client.Version}}"
+	}
+	output, err := exec.CommandContext(ctx, r.binary, "version", "--format", format).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s unavailable: %s", r.binary, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s unavailable: %w", r.binary, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Pull implements Runtime.
+func (r *CLIRuntime) Pull(ctx context.Context, image string, auth *RegistryAuth) error {
+	args := []string{"pull", image}
+	if auth != nil {
+		// A throwaway auth file keeps the login out of the user's own
+		// configuration and out of the process list
+		configDir, err := writeAuthConfig(auth)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(configDir)
+
+		if r.binary == "podman" {
+			args = []string{"pull", "--authfile", filepath.Join(configDir, "config.json"), image}
+		} else {
+			args = []string{"--config", configDir, "pull", image}
+		}
+	}
+
+	output, err := exec.CommandContext(ctx, r.binary, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to pull image '%s': %w\nOutput: %s", image, err, string(output))
+	}
+	return nil
+}
+
+// Inspect implements Runtime.
+func (r *CLIRuntime) Inspect(ctx context.Context, image string) (*ImageInfo, error) {
+	output, err := exec.CommandContext(ctx, r.binary, "image", "inspect", image).Output()
+	if err != nil {
+		// docker reports "No such image", podman "image not known"
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			message := strings.ToLower(string(exitErr.Stderr))
+			if strings.Contains(message, "no such image") || strings.Contains(message, "not known") {
+				return nil, ErrImageNotFound
+			}
+		}
+		return nil, fmt.Errorf("failed to inspect image '%s': %w", image, err)
+	}
+
+	var images []struct {
+		ID          string   `json:"Id"`
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	if err := json.Unmarshal(output, &images); err != nil || len(images) == 0 {
+		return nil, fmt.Errorf("failed to inspect image '%s': unexpected response", image)
+	}
+
+	info := &ImageInfo{ID: images[0].ID, RepoDigests: images[0].RepoDigests}
+	if !strings.HasPrefix(info.ID, "sha256:") {
+		info.ID = "sha256:" + info.ID // podman reports bare hex IDs
+	}
+	return info, nil
+}
+
+// writeAuthConfig writes auth to a config.json in a new private directory and
+// returns the directory, which the caller must remove.
+func writeAuthConfig(auth *RegistryAuth) (string, error) {
+	configDir, err := os.MkdirTemp("", "cares-auth-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create registry auth directory: %w", err)
+	}
+
+	config := map[string]any{
+		"auths": map[string]any{
+			auth.serverAddress(): map[string]string{
+				"auth": base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password)),
+			},
+		},
+	}
+	data, err := json.Marshal(config)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(configDir, "config.json"), data, 0600)
+	}
+	if err != nil {
+		os.RemoveAll(configDir)
+		return "", fmt.Errorf("failed to write registry auth: %w", err)
+	}
+	return configDir, nil
+}
+
+// Images implements Runtime.
+func (r *CLIRuntime) Images(ctx context.Context) ([]string, error) {
+	output, err := exec.CommandContext(ctx, r.binary, "images", "--format", "{{.Repository}}:{{.Tag}}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var tags []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.Contains(line, "<none>") {
+			tags = append(tags, line)
+		}
+	}
+	return tags, nil
+}
+
+// Info implements Runtime.
+func (r *CLIRuntime) Info(ctx context.Context) (*EngineInfo, error) {
+	// The count goes first, so a root directory containing spaces stays intact
+	format := "{{.ContainersRunning}} {{.DockerRootDir}}"
+	if r.binary == "podman" {
+		format = "{{.Store.ContainerStore.Running}} {{.Store.GraphRoot}}"
+	}
+	output, err := exec.CommandContext(ctx, r.binary, "info", "--format", format).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s info: %w", r.binary, err)
+	}
+
+	count, rootDir, _ := strings.Cut(strings.TrimSpace(string(output)), " ")
+	running, err := strconv.Atoi(count)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %s info output '%s'", r.binary, strings.TrimSpace(string(output)))
+	}
+	return &EngineInfo{RootDir: rootDir, RunningContainers: running}, nil
+}
+
+// Run implements Runtime.
+func (r *CLIRuntime) Run(ctx context.Context, spec ContainerSpec, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.CommandContext(ctx, r.binary, r.runArgs(spec)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Killing the client alone would leave the container running
+	cmd.Cancel = func() error {
+		killCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		if err := r.Kill(killCtx, spec.Name); err != nil {
+			logging.Warn("%v", err)
+		}
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 5 * time.Second // Don't hang on pipes held open after the client is killed
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The client exits with the container's exit code
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to run container: %w", err)
+	}
+	return 0, nil
+}
+
+// Kill implements Runtime.
+func (r *CLIRuntime) Kill(ctx context.Context, container string) error {
+	output, err := exec.CommandContext(ctx, r.binary, "kill", container).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to kill container '%s': %w\nOutput: %s", container, err, string(output))
+	}
+	return nil
+}
+
+// Logs implements Runtime.
+func (r *CLIRuntime) Logs(ctx context.Context, container string, follow bool, stdout, stderr io.Writer) error {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	cmd := exec.CommandContext(ctx, r.binary, append(args, container)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to read logs of container '%s': %w", container, err)
+	}
+	return nil
+}
+
+// runArgs builds the arguments for "run" from the container spec.
+func (r *CLIRuntime) runArgs(spec ContainerSpec) []string {
+	args := []string{"run", "--rm", "--name", spec.Name}
+	if spec.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", spec.MemoryMB))
+	}
+	for _, env := range spec.sortedEnv() {
+		args = append(args, "--env", env)
+	}
+	return append(args, spec.Image)
+}
@@ -0,0 +1,377 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultDockerSocket is where the Docker daemon listens unless DOCKER_HOST says otherwise.
+const DefaultDockerSocket = "/var/run/docker.sock"
+
+// cleanupTimeout bounds the API calls made to kill and remove a container
+// after its run was cancelled.
+const cleanupTimeout = 30 * time.Second
+
+// DockerRuntime talks to the Docker Engine API directly, over the daemon's unix
+// socket or a TCP address, without needing the docker CLI.
+type DockerRuntime struct {
+	client *http.Client
+	host   string // Base URL of the API
+}
+
+// NewDockerRuntime creates a runtime for the daemon at host, given in DOCKER_HOST
+// form ("unix:///var/run/docker.sock" or "tcp://host:2375"). An empty host
+// uses DefaultDockerSocket.
+func NewDockerRuntime(host string) (*DockerRuntime, error) {
+	if host == "" {
+		host = "unix://" + DefaultDockerSocket
+	}
+
+	transport := &http.Transport{}
+	baseURL := ""
+	switch {
+	case strings.HasPrefix(host, "unix://"):
+		socket := strings.TrimPrefix(host, "unix://")
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		baseURL = "http://docker" // Host is ignored when dialing the socket
+	case strings.HasPrefix(host, "tcp://"):
+		baseURL = "http://" + strings.TrimPrefix(host, "tcp://")
+	default:
+		return nil, fmt.Errorf("unsupported DOCKER_HOST '%s' (expected unix:// or tcp://)", host)
+	}
+
+	return &DockerRuntime{
+		client: &http.Client{Transport: transport},
+		host:   baseURL,
+	}, nil
+}
+
+// Name implements Runtime.
+func (r *DockerRuntime) Name() string {
+	return RuntimeDocker
+}
+
+// Version implements Runtime.
+func (r *DockerRuntime) Version(ctx context.Context) (string, error) {
+	resp, err := r.do(ctx, "GET", "/version", nil)
+	if err != nil {
+		return "", fmt.Errorf("docker daemon unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var version struct {
+		Version string `json:"Version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", fmt.Errorf("failed to read docker version: %w", err)
+	}
+	return version.Version, nil
+}
+
+// Pull implements Runtime. The progress stream is consumed until the pull
+// finishes, since errors are only reported inside it.
+func (r *DockerRuntime) Pull(ctx context.Context, image string, auth *RegistryAuth) error {
+	// Credentials travel in a header of this one request and are not kept
+	header := make(http.Header)
+	if auth != nil {
+		encoded, err := json.Marshal(map[string]string{
+			"username":      auth.Username,
+			"password":      auth.Password,
+			"serveraddress": auth.serverAddress(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode registry credentials: %w", err)
+		}
+		header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(encoded))
+	}
+
+	resp, err := r.doWithHeader(ctx, "POST", "/images/create?fromImage="+url.QueryEscape(image), nil, header)
+	if err != nil {
+		return fmt.Errorf("failed to pull image '%s': %w", image, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to pull image '%s': %w", image, err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("failed to pull image '%s': %s", image, progress.Error)
+		}
+	}
+}
+
+// Inspect implements Runtime.
+func (r *DockerRuntime) Inspect(ctx context.Context, image string) (*ImageInfo, error) {
+	resp, err := r.do(ctx, "GET", "/images/"+image+"/json", nil)
+	if err != nil {
+		if apiErr, ok := err.(*dockerAPIError); ok && apiErr.status == http.StatusNotFound {
+			return nil, ErrImageNotFound
+		}
+		return nil, fmt.Errorf("failed to inspect image '%s': %w", image, err)
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		ID          string   `json:"Id"`
+		RepoDigests []string `json:"RepoDigests"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to inspect image '%s': %w", image, err)
+	}
+	return &ImageInfo{ID: info.ID, RepoDigests: info.RepoDigests}, nil
+}
+
+// Images implements Runtime.
+func (r *DockerRuntime) Images(ctx context.Context) ([]string, error) {
+	resp, err := r.do(ctx, "GET", "/images/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var images []struct {
+		RepoTags []string `json:"RepoTags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&images); err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var tags []string
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if tag != "<none>:<none>" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// Info implements Runtime.
+func (r *DockerRuntime) Info(ctx context.Context) (*EngineInfo, error) {
+	resp, err := r.do(ctx, "GET", "/info", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docker info: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		DockerRootDir     string `json:"DockerRootDir"`
+		ContainersRunning int    `json:"ContainersRunning"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to read docker info: %w", err)
+	}
+	return &EngineInfo{RootDir: info.DockerRootDir, RunningContainers: info.ContainersRunning}, nil
+}
+
+// Run implements Runtime. The container is created, started, followed through
+// its logs until it exits, and always removed afterwards.
+func (r *DockerRuntime) Run(ctx context.Context, spec ContainerSpec, stdout, stderr io.Writer) (int, error) {
+	config := map[string]interface{}{
+		"Image": spec.Image,
+		"Env":   spec.sortedEnv(),
+		"HostConfig": map[string]interface{}{
+			"Memory": int64(spec.MemoryMB) * 1024 * 1024,
+		},
+	}
+	body, err := json.Marshal(config)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	resp, err := r.do(ctx, "POST", "/containers/create?name="+url.QueryEscape(spec.Name), body)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create container: %w", err)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if err != nil {
+		return -1, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	// Remove the container however the run ends, even if ctx is already done
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		if resp, err := r.do(cleanupCtx, "DELETE", "/containers/"+created.ID+"?force=1", nil); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	resp, err = r.do(ctx, "POST", "/containers/"+created.ID+"/start", nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to start container: %w", err)
+	}
+	resp.Body.Close()
+
+	// Kill the container as soon as the run is cancelled or times out
+	stopKill := context.AfterFunc(ctx, func() {
+		killCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		r.Kill(killCtx, created.ID)
+	})
+	defer stopKill()
+
+	// Following the logs returns once the container exits
+	logErr := r.Logs(ctx, created.ID, true, stdout, stderr)
+	if ctx.Err() != nil {
+		return -1, ctx.Err()
+	}
+
+	exitCode, err := r.wait(ctx, created.ID)
+	if err != nil {
+		return -1, err
+	}
+	if logErr != nil {
+		return exitCode, logErr
+	}
+	return exitCode, nil
+}
+
+// Kill implements Runtime.
+func (r *DockerRuntime) Kill(ctx context.Context, container string) error {
+	resp, err := r.do(ctx, "POST", "/containers/"+container+"/kill", nil)
+	if err != nil {
+		return fmt.Errorf("failed to kill container '%s': %w", container, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Logs implements Runtime.
+func (r *DockerRuntime) Logs(ctx context.Context, container string, follow bool, stdout, stderr io.Writer) error {
+	path := "/containers/" + container + "/logs?stdout=1&stderr=1"
+	if follow {
+		path += "&follow=1"
+	}
+	resp, err := r.do(ctx, "GET", path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to read logs of container '%s': %w", container, err)
+	}
+	defer resp.Body.Close()
+
+	if err := demuxDockerStream(resp.Body, stdout, stderr); err != nil {
+		return fmt.Errorf("failed to read logs of container '%s': %w", container, err)
+	}
+	return nil
+}
+
+// wait blocks until the container has exited and returns its exit code.
+func (r *DockerRuntime) wait(ctx context.Context, container string) (int, error) {
+	resp, err := r.do(ctx, "POST", "/containers/"+container+"/wait", nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to wait for container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		StatusCode int `json:"StatusCode"`
+		Error      *struct {
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return -1, fmt.Errorf("failed to wait for container: %w", err)
+	}
+	if result.Error != nil && result.Error.Message != "" {
+		return -1, fmt.Errorf("failed to wait for container: %s", result.Error.Message)
+	}
+	return result.StatusCode, nil
+}
+
+// dockerAPIError is an error response from the Docker Engine API.
+type dockerAPIError struct {
+	status  int
+	message string
+}
+
+// Error implements the error interface.
+func (e *dockerAPIError) Error() string {
+	return fmt.Sprintf("docker API returned %d: %s", e.status, e.message)
+}
+
+// do sends an API request and returns the response if it succeeded. Error
+// responses are turned into a *dockerAPIError.
+func (r *DockerRuntime) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	return r.doWithHeader(ctx, method, path, body, nil)
+}
+
+// doWithHeader is do with additional request headers.
+func (r *DockerRuntime) doWithHeader(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, r.host+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &dockerAPIError{status: resp.StatusCode, message: apiErr.Message}
+	}
+	return resp, nil
+}
+
+// demuxDockerStream splits Docker's multiplexed stdout/stderr stream. Each
+// frame starts with an 8-byte header: the stream (1 = stdout, 2 = stderr),
+// three padding bytes, and the big-endian payload length.
+func demuxDockerStream(src io.Reader, stdout, stderr io.Writer) error {
+	reader := bufio.NewReader(src)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		dst := stdout
+		if header[0] == 2 {
+			dst = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, reader, size); err != nil {
+			return err
+		}
+	}
+}
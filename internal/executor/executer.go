@@ -1,103 +1,152 @@
-// Package executor provides functionality to execute external containers (Docker)
-// and capture their output. It handles Docker daemon management and supports
-// both local images and URL-based image references.
+// Package executor provides functionality to execute external containers
+// and capture their output. Containers run on a pluggable Runtime (the Docker
+// Engine API, the docker or podman CLI, or an in-memory fake), and both local
+// images and URL-based image references are supported. Functions of kind
+// "process" instead run a whitelisted executable directly on the worker, and
+// functions of kind "wasm" run a WASI module in-process, so workers without a
+// container engine can still execute functions.
 package executor
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"log/slog"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
+	"cares/internal/imageref"
 	"cares/internal/logging"
+	"cares/internal/tracing"
 )
 
-// ensureDockerRunning checks if Docker daemon is running and starts it if needed.
-// This function attempts to start Docker using systemctl on Linux systems.
-func ensureDockerRunning() error {
-	// First check if Docker is already running
-	cmd := exec.Command("docker", "info")
-	if err := cmd.Run(); err == nil {
-		logging.Debug("Docker daemon is already running")
-		return nil
-	}
+// ensureImage makes sure an image is available on the runtime according to
+// the pull policy, pulling with auth if needed, and returns its local description.
+func ensureImage(ctx context.Context, runtime Runtime, imageName, policy string, auth *RegistryAuth) (info *ImageInfo, err error) {
+	ctx, span := tracing.Start(ctx, "pull", slog.String("image", imageName), slog.String("pull_policy", policy))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
 
-	logging.Info("Docker daemon not running, attempting to start...")
-	
-	// Try to start Docker daemon using systemctl
-	startCmd := exec.Command("sudo", "systemctl", "start", "docker")
-	if err := startCmd.Run(); err != nil {
-		return fmt.Errorf("failed to start Docker daemon: %w", err)
+	if !validPullPolicy(policy) {
+		return nil, fmt.Errorf("unknown pull policy '%s'", policy)
+	}
+	if auth != nil && !auth.appliesTo(imageName) {
+		return nil, fmt.Errorf("registry credentials for '%s' do not apply to image '%s'", auth.Server, imageName)
 	}
 
-	// Wait a moment for Docker to fully start
-	time.Sleep(3 * time.Second)
+	if policy != PullAlways {
+		// Check if image exists locally
+		info, err := runtime.Inspect(ctx, imageName)
+		if err == nil {
+			logging.Debug("Image '%s' found locally", imageName)
+			span.SetAttributes(slog.Bool("pulled", false))
+			return info, nil
+		}
+		if !errors.Is(err, ErrImageNotFound) {
+			return nil, err
+		}
+		if policy == PullNever {
+			return nil, fmt.Errorf("image '%s' is not present and pull policy is %s", imageName, PullNever)
+		}
+	}
 
-	// Verify Docker is now running
-	verifyCmd := exec.Command("docker", "info")
-	if err := verifyCmd.Run(); err != nil {
-		return fmt.Errorf("Docker daemon failed to start properly: %w", err)
+	span.SetAttributes(slog.Bool("pulled", true))
+	logging.Info("Pulling image '%s'...", imageName)
+	if err := runtime.Pull(ctx, imageName, auth); err != nil {
+		return nil, err
 	}
+	logging.Info("Successfully pulled image '%s'", imageName)
+	noteImageCached(imageName)
 
-	logging.Info("Docker daemon started successfully")
-	return nil
+	return runtime.Inspect(ctx, imageName)
 }
 
-// pullImageIfNeeded checks if an image exists locally and pulls it if not.
-// Supports both standard image names and URL-based registry paths.
-func pullImageIfNeeded(imageName string) error {
-	// Check if image exists locally
-	cmd := exec.Command("docker", "image", "inspect", imageName)
-	if err := cmd.Run(); err == nil {
-		logging.Debug("Image '%s' found locally", imageName)
-		return nil
+// reportedDigest returns the digest to report for an image: the registry
+// manifest digest of the requested repository when the image was pulled from a
+// registry, otherwise the image ID itself (e.g. for locally built images).
+func reportedDigest(imageName string, info *ImageInfo) string {
+	repository := repositoryOf(imageName)
+	for _, repoDigest := range info.RepoDigests {
+		name, digest, ok := strings.Cut(repoDigest, "@")
+		if ok && (name == repository || strings.HasSuffix(name, "/"+repository)) {
+			return digest
+		}
 	}
-
-	logging.Info("Pulling image '%s'...", imageName)
-	
-	// Pull the image
-	pullCmd := exec.Command("docker", "pull", imageName)
-	output, err := pullCmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to pull image '%s': %w\nOutput: %s", imageName, err, string(output))
+	if len(info.RepoDigests) > 0 {
+		if _, digest, ok := strings.Cut(info.RepoDigests[0], "@"); ok {
+			return digest
+		}
 	}
+	return info.ID
+}
 
-	logging.Info("Successfully pulled image '%s'", imageName)
-	return nil
+// repositoryOf strips the tag and digest from an image reference.
+func repositoryOf(imageName string) string {
+	if ref, err := imageref.Parse(imageName); err == nil {
+		return ref.Name()
+	}
+	return imageName
 }
 
-// normalizeImageName handles URL-based image names and converts them to proper Docker format.
+// normalizeImageName parses an image reference, which may be URL-based, into
+// the form the runtimes use.
 // Examples:
-//   - "https://registry.hub.docker.com/nginx:latest" -> "nginx:latest"
-//   - "ghcr.io/user/repo:tag" -> "ghcr.io/user/repo:tag" (unchanged)
+//   - "https://ghcr.io/user/repo:tag" -> "ghcr.io/user/repo:tag"
+//   - "localhost:5000/app" -> "localhost:5000/app:latest"
 //   - "nginx" -> "nginx:latest" (add latest tag)
-func normalizeImageName(imageName string) string {
-	// Handle HTTP/HTTPS URLs by extracting the path
-	if strings.HasPrefix(imageName, "http://") || strings.HasPrefix(imageName, "https://") {
-		// Extract the path after the domain
-		parts := strings.Split(imageName, "/")
-		if len(parts) >= 4 {
-			// Skip protocol and domain, join the rest
-			imageName = strings.Join(parts[3:], "/")
-		}
+//   - "app@sha256:..." -> "app@sha256:..." (pinned, no tag added)
+func normalizeImageName(imageName string) (string, error) {
+	normalized, err := imageref.Normalize(imageName)
+	if err != nil {
+		return "", fmt.Errorf("invalid image reference '%s': %w", imageName, err)
 	}
+	return normalized, nil
+}
 
-	// Add :latest tag if no tag is specified
-	if !strings.Contains(imageName, ":") {
-		imageName += ":latest"
-	}
+// DefaultTimeout bounds container execution when a function has no timeout configured.
+const DefaultTimeout = 5 * time.Minute
 
-	return imageName
+// RunOptions carries the per-function execution settings for a run.
+// Zero values fall back to the defaults.
+type RunOptions struct {
+	Timeout         time.Duration     // Maximum wall-clock run time (DefaultTimeout if zero)
+	MemoryMB        int               // Memory limit in megabytes (unlimited if zero)
+	Env             map[string]string // Environment variables passed to the function
+	MaxOutputBytes  int               // Cap on captured output (DefaultMaxOutputBytes if zero)
+	Args            []string          // Command-line arguments (process and wasm functions)
+	Input           []byte            // Invocation input on stdin (process and wasm functions)
+	PullPolicy      string            // When to pull the image (PullIfNotPresent if empty)
+	RegistryAuth    *RegistryAuth     // Login for pulling from a private registry (nil for anonymous)
+	Digest          string            // Manifest digest the image is pinned to; other content is refused
+	VerifySignature bool              // Require a signature by a trusted key, see SignatureKeysFromEnv
+}
+
+// RunResult is the outcome of a function run.
+type RunResult struct {
+	Output      string    // Combined stdout and stderr, in the order it was produced
+	Stdout      string    // Program output
+	Stderr      string    // Diagnostics
+	Truncated   bool      // Output exceeded MaxOutputBytes and the rest was discarded
+	ExitCode    int       // Container exit code, -1 if it did not exit normally
+	StartedAt   time.Time // When the container was started
+	FinishedAt  time.Time // When the container exited
+	ImageDigest string    // Digest of the image that actually ran
 }
 
-// RunContainer runs the specified Docker image using the local Docker daemon.
-// It automatically ensures Docker is running, normalizes image names, and pulls
-// images if they're not available locally.
+// RunContainer runs the specified image on the current runtime (see
+// CurrentRuntime). It normalizes image names and pulls images if they're not
+// available locally (see RunOptions.PullPolicy).
 //
 // Parameters:
 //   - imageName: The name, tag, or URL of the Docker image to run
+//   - opts: Timeout, memory limit, environment and output cap for the container
 //
 // Returns the combined output (stdout and stderr) from the container, and any error encountered during execution.
+// Use RunContainerStream for the separate streams, exit code and timings.
 //
 // The function supports multiple image formats:
 //   - Standard names: "alpine", "nginx:1.21"
@@ -106,39 +155,124 @@ func normalizeImageName(imageName string) string {
 //
 // Example usage:
 //
-//	output, err := executor.RunContainer("alpine:latest")
+//	output, err := executor.RunContainer("alpine:latest", executor.RunOptions{})
 //	if err != nil {
 //	    // handle error
 //	}
 //	fmt.Println(output)
-func RunContainer(imageName string) (string, error) {
-	if imageName == "" {
-		return "", fmt.Errorf("image name cannot be empty")
+func RunContainer(imageName string, opts RunOptions) (string, error) {
+	result, err := RunContainerStream(context.Background(), imageName, opts, nil)
+	if result == nil {
+		return "", err
 	}
+	return result.Output, err
+}
 
-	// Ensure Docker daemon is running
-	if err := ensureDockerRunning(); err != nil {
-		return "", fmt.Errorf("Docker daemon error: %w", err)
+// RunContainerStream runs the specified image like RunContainer, but hands
+// stdout and stderr to onOutput chunk by chunk as the container produces them.
+// Cancelling ctx kills the container. onOutput may be nil.
+//
+// An image pinned with opts.Digest is pulled by digest and refused with
+// ErrDigestMismatch if the runtime ends up with other content; with
+// opts.VerifySignature it must also be signed by a trusted key, or
+// ErrSignatureVerification is returned. Pull failures wrap ErrImagePull.
+//
+// A non-nil RunResult is returned whenever the container was started, even if
+// it failed, so callers can report partial output.
+func RunContainerStream(ctx context.Context, imageName string, opts RunOptions, onOutput OutputFunc) (*RunResult, error) {
+	if imageName == "" {
+		return nil, fmt.Errorf("image name cannot be empty")
 	}
+	runtime := CurrentRuntime()
 
 	// Normalize the image name
-	normalizedImage := normalizeImageName(imageName)
+	normalizedImage, err := normalizeImageName(imageName)
+	if err != nil {
+		return nil, err
+	}
 	logging.Debug("Normalized image name: %s -> %s", imageName, normalizedImage)
 
-	// Pull image if not available locally
-	if err := pullImageIfNeeded(normalizedImage); err != nil {
-		return "", fmt.Errorf("image pull error: %w", err)
+	// A pinned image is pulled by digest, so a moved tag can't change what runs
+	pullImage := normalizedImage
+	if opts.Digest != "" {
+		if pullImage, err = pinnedImage(normalizedImage, opts.Digest); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pull the image as the function's pull policy demands
+	info, err := ensureImage(ctx, runtime, pullImage, opts.PullPolicy, opts.RegistryAuth)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImagePull, err)
+	}
+
+	// Refuse anything but the pinned, and if required signed, manifest
+	digest := reportedDigest(normalizedImage, info)
+	if opts.Digest != "" {
+		if err := verifyDigest(normalizedImage, info, opts.Digest); err != nil {
+			return nil, err
+		}
+		digest = opts.Digest
+	}
+	if opts.VerifySignature {
+		if len(info.RepoDigests) == 0 {
+			return nil, fmt.Errorf("%w: image '%s' was not pulled from a registry", ErrSignatureVerification, normalizedImage)
+		}
+		_, span := tracing.Start(ctx, "verify", slog.String("image", normalizedImage), slog.String("digest", digest))
+		err := verifyImageSignature(ctx, normalizedImage, digest, opts.RegistryAuth)
+		span.RecordError(err)
+		span.End()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Run the exact image we resolved, so a tag moving between inspect and run
+	// can't make the reported digest wrong
+	spec := ContainerSpec{
+		Name:     "cares-" + uuid.New().String(),
+		Image:    info.ID,
+		Env:      opts.Env,
+		MemoryMB: opts.MemoryMB,
+	}
+
+	// Run the container, capturing both streams through the same capped collector
+	logging.Debug("Running container '%s' with image: %s (%s)", spec.Name, normalizedImage, runtime.Name())
+	collector := newOutputCollector(opts.MaxOutputBytes, onOutput)
+	result := &RunResult{StartedAt: time.Now(), ImageDigest: digest}
+	runCtx, span := tracing.Start(ctx, "run", slog.String("container", spec.Name), slog.String("runtime", runtime.Name()))
+	exitCode, err := runtime.Run(runCtx, spec, collector.writer(StreamStdout), collector.writer(StreamStderr))
+	result.FinishedAt = time.Now()
+	result.ExitCode = exitCode
+	span.SetAttributes(slog.Int("exit_code", exitCode))
+	span.RecordError(err)
+	span.End()
 
-	// Run the container
-	logging.Debug("Running container with image: %s", normalizedImage)
-	cmd := exec.Command("docker", "run", "--rm", normalizedImage)
-	output, err := cmd.CombinedOutput()
-	
+	collector.fill(result)
+	if result.Truncated {
+		logging.Warn("Output of container '%s' exceeded %d bytes and was truncated", normalizedImage, collector.limit)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("container execution timed out after %s", timeout)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return result, fmt.Errorf("container execution cancelled")
+	}
 	if err != nil {
-		return string(output), fmt.Errorf("container execution failed: %w", err)
+		return result, fmt.Errorf("container execution failed: %w", err)
+	}
+	if exitCode != 0 {
+		return result, fmt.Errorf("container exited with code %d", exitCode)
 	}
 
-	logging.Debug("Container executed successfully, output length: %d bytes", len(output))
-	return string(output), nil
-}
\ No newline at end of file
+	logging.Debug("Container executed successfully, output length: %d bytes", len(result.Output))
+	return result, nil
+}
@@ -0,0 +1,334 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"cares/internal/imageref"
+)
+
+// FakeBehavior scripts what a container of a given image does in a FakeRuntime.
+type FakeBehavior struct {
+	Stdout   string        // Written to stdout when the container starts
+	Stderr   string        // Written to stderr when the container starts
+	ExitCode int           // Exit code once Duration has passed
+	Duration time.Duration // How long the container runs
+}
+
+// FakeRuntime is an in-memory Runtime that runs no real containers. It lets
+// the worker, cluster and API be exercised end to end on hosts without a
+// container engine: images are "pulled" instantly and containers replay a
+// scripted FakeBehavior.
+type FakeRuntime struct {
+	mu         sync.Mutex
+	images     map[string]*ImageInfo     // Local images by reference and ID
+	behaviors  map[string]FakeBehavior   // Scripted behavior by image reference
+	containers map[string]*fakeContainer // Containers by name, kept after exit for Logs
+	pullErrors map[string]error          // Images whose pull fails
+	pulls      []string                  // Every image pulled, in order
+	logins     map[string]RegistryAuth   // Images that can only be pulled with these credentials
+	tampered   map[string]bool           // Repositories whose pulls yield content not matching the digest
+	down       error                     // Reported by Version while the runtime is "down"
+}
+
+// fakeContainer is a container started by a FakeRuntime.
+type fakeContainer struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	killed chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewFakeRuntime creates an empty fake runtime.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		images:     make(map[string]*ImageInfo),
+		behaviors:  make(map[string]FakeBehavior),
+		containers: make(map[string]*fakeContainer),
+		pullErrors: make(map[string]error),
+		logins:     make(map[string]RegistryAuth),
+		tampered:   make(map[string]bool),
+	}
+}
+
+// Name implements Runtime.
+func (r *FakeRuntime) Name() string {
+	return RuntimeFake
+}
+
+// Version implements Runtime.
+func (r *FakeRuntime) Version(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.down != nil {
+		return "", r.down
+	}
+	return "fake", nil
+}
+
+// SetDown makes the runtime report itself unavailable with err, or available
+// again if err is nil.
+func (r *FakeRuntime) SetDown(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.down = err
+}
+
+// AddImage makes an image available locally, as if it had been pulled.
+func (r *FakeRuntime) AddImage(image string) *ImageInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.addImageLocked(image)
+}
+
+// SetBehavior scripts what containers of the given image reference do.
+// Images without a behavior print a greeting and exit with 0.
+func (r *FakeRuntime) SetBehavior(image string, behavior FakeBehavior) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.behaviors[image] = behavior
+}
+
+// FailPull makes pulling the given image fail with err.
+func (r *FakeRuntime) FailPull(image string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pullErrors[image] = err
+}
+
+// RequireAuth makes pulls of image fail unless they log in with username and
+// password, like an image in a private registry.
+func (r *FakeRuntime) RequireAuth(image, username, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logins[image] = RegistryAuth{Username: username, Password: password}
+}
+
+// TamperRepository makes pulls from repository yield an image whose manifest
+// digest differs from the one requested, like a compromised registry or mirror.
+func (r *FakeRuntime) TamperRepository(repository string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tampered[repositoryOf(repository)] = true
+}
+
+// Pulls returns every image pulled so far, in order.
+func (r *FakeRuntime) Pulls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.pulls...)
+}
+
+// Pull implements Runtime.
+func (r *FakeRuntime) Pull(ctx context.Context, image string, auth *RegistryAuth) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pulls = append(r.pulls, image)
+	if err := r.pullErrors[image]; err != nil {
+		return fmt.Errorf("failed to pull image '%s': %w", image, err)
+	}
+	if login, required := r.logins[image]; required {
+		if auth == nil || auth.Username != login.Username || auth.Password != login.Password {
+			return fmt.Errorf("failed to pull image '%s': unauthorized", image)
+		}
+	}
+	r.addImageLocked(image)
+	return nil
+}
+
+// Inspect implements Runtime.
+func (r *FakeRuntime) Inspect(ctx context.Context, image string) (*ImageInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, exists := r.images[image]
+	if !exists {
+		return nil, ErrImageNotFound
+	}
+	copied := *info
+	copied.RepoDigests = append([]string(nil), info.RepoDigests...)
+	return &copied, nil
+}
+
+// Images implements Runtime.
+func (r *FakeRuntime) Images(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tags []string
+	for ref, info := range r.images {
+		if ref != info.ID {
+			tags = append(tags, ref)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Info implements Runtime. The fake keeps its data in the temporary directory.
+func (r *FakeRuntime) Info(ctx context.Context) (*EngineInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.down != nil {
+		return nil, r.down
+	}
+	info := &EngineInfo{RootDir: os.TempDir()}
+	for _, container := range r.containers {
+		select {
+		case <-container.done:
+		default:
+			info.RunningContainers++
+		}
+	}
+	return info, nil
+}
+
+// Run implements Runtime.
+func (r *FakeRuntime) Run(ctx context.Context, spec ContainerSpec, stdout, stderr io.Writer) (int, error) {
+	r.mu.Lock()
+	info, exists := r.images[spec.Image]
+	if !exists {
+		r.mu.Unlock()
+		return -1, fmt.Errorf("failed to create container: %w", ErrImageNotFound)
+	}
+	if _, taken := r.containers[spec.Name]; taken {
+		r.mu.Unlock()
+		return -1, fmt.Errorf("failed to create container: name '%s' already in use", spec.Name)
+	}
+	behavior := r.behaviorLocked(info)
+	container := &fakeContainer{
+		killed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	r.containers[spec.Name] = container
+	r.mu.Unlock()
+
+	defer close(container.done)
+
+	// Output is recorded for Logs while it is copied to the caller
+	io.WriteString(io.MultiWriter(stdout, r.locked(&container.stdout)), behavior.Stdout)
+	io.WriteString(io.MultiWriter(stderr, r.locked(&container.stderr)), behavior.Stderr)
+
+	timer := time.NewTimer(behavior.Duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return behavior.ExitCode, nil
+	case <-container.killed:
+		return 137, nil // SIGKILL, as reported by Docker
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+}
+
+// Kill implements Runtime.
+func (r *FakeRuntime) Kill(ctx context.Context, name string) error {
+	r.mu.Lock()
+	container, exists := r.containers[name]
+	r.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("failed to kill container '%s': no such container", name)
+	}
+
+	container.once.Do(func() { close(container.killed) })
+	return nil
+}
+
+// Logs implements Runtime.
+func (r *FakeRuntime) Logs(ctx context.Context, name string, follow bool, stdout, stderr io.Writer) error {
+	r.mu.Lock()
+	container, exists := r.containers[name]
+	r.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("failed to read logs of container '%s': no such container", name)
+	}
+
+	if follow {
+		select {
+		case <-container.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stdout.Write(container.stdout.Bytes())
+	stderr.Write(container.stderr.Bytes())
+	return nil
+}
+
+// addImageLocked registers an image with deterministic fake digests. The
+// caller must hold r.mu.
+func (r *FakeRuntime) addImageLocked(image string) *ImageInfo {
+	if info, exists := r.images[image]; exists {
+		return info
+	}
+
+	sum := sha256.Sum256([]byte(image))
+	id := "sha256:" + hex.EncodeToString(sum[:])
+	manifest := sha256.Sum256(append([]byte("manifest:"), image...))
+	repoDigest := repositoryOf(image) + "@sha256:" + hex.EncodeToString(manifest[:])
+
+	// Images pulled by digest are content-addressed, unless the registry lies
+	if ref, err := imageref.Parse(image); err == nil && ref.Digest != "" && !r.tampered[ref.Name()] {
+		repoDigest = ref.Name() + "@" + ref.Digest
+	}
+	info := &ImageInfo{
+		ID:          id,
+		RepoDigests: []string{repoDigest},
+	}
+	r.images[image] = info
+	r.images[id] = info
+	return info
+}
+
+// behaviorLocked returns the scripted behavior for an image. The caller must hold r.mu.
+func (r *FakeRuntime) behaviorLocked(info *ImageInfo) FakeBehavior {
+	for ref, candidate := range r.images {
+		if candidate != info || ref == info.ID {
+			continue
+		}
+		if behavior, exists := r.behaviors[ref]; exists {
+			return behavior
+		}
+		return FakeBehavior{Stdout: fmt.Sprintf("Hello from %s\n", ref)}
+	}
+	return FakeBehavior{}
+}
+
+// locked wraps a buffer so writes to it are serialized by r.mu.
+func (r *FakeRuntime) locked(buf *bytes.Buffer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return buf.Write(p)
+	})
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+// Write implements io.Writer.
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"cares/internal/logging"
+)
+
+// Health check timing
+const (
+	DefaultHealthInterval = 15 * time.Second // Time between runtime health probes
+	healthProbeTimeout    = 5 * time.Second  // How long a single probe may take
+)
+
+// RuntimeHealth is the result of the latest container runtime health probe.
+type RuntimeHealth struct {
+	Runtime   string    // Runtime name, see Runtime.Name
+	Version   string    // Engine version reported by the runtime
+	Available bool      // Whether the runtime answered the last probe
+	Error     string    // Why the runtime is unavailable
+	CheckedAt time.Time // When the last probe finished (zero before the first)
+
+	RootDir           string // Where the engine stores its data, empty if unknown
+	RunningContainers int    // Containers running on the engine
+}
+
+var (
+	healthMu   sync.RWMutex
+	lastHealth RuntimeHealth
+)
+
+// CheckRuntimeHealth probes the current runtime once, records the result for
+// RuntimeHealthStatus and returns it. Transitions are logged. A healthy runtime
+// is also asked for its images, refreshing CachedImages. If no runtime could be
+// created, or the probe panics, the runtime is reported unavailable.
+func CheckRuntimeHealth(ctx context.Context) RuntimeHealth {
+	health := probeRuntime(ctx)
+
+	healthMu.Lock()
+	previous := lastHealth
+	lastHealth = health
+	healthMu.Unlock()
+
+	switch {
+	case health.Available && (!previous.Available || previous.CheckedAt.IsZero()):
+		logging.Info("Container runtime '%s' available (version %s)", health.Runtime, health.Version)
+	case !health.Available && (previous.Available || previous.CheckedAt.IsZero()):
+		logging.Warn("Container runtime '%s' unavailable: %s", health.Runtime, health.Error)
+	}
+	return health
+}
+
+// probeRuntime asks the current runtime for its version, images and engine info.
+func probeRuntime(ctx context.Context) (health RuntimeHealth) {
+	defer func() {
+		if r := recover(); r != nil {
+			health.Available = false
+			health.Error = fmt.Sprintf("health probe panicked: %v", r)
+			health.CheckedAt = time.Now()
+		}
+	}()
+
+	runtime, configErr := currentRuntime()
+	if runtime == nil {
+		return RuntimeHealth{Error: configErr.Error(), CheckedAt: time.Now()}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	health = RuntimeHealth{Runtime: runtime.Name()}
+	version, err := runtime.Version(ctx)
+	health.CheckedAt = time.Now()
+	if err != nil {
+		health.Error = err.Error()
+		if configErr != nil {
+			// Say why the fallback is being probed instead of the configured runtime
+			health.Error += fmt.Sprintf(" (%v)", configErr)
+		}
+	} else {
+		health.Available = true
+		health.Version = version
+		if err := refreshCachedImages(ctx, runtime); err != nil {
+			logging.Warn("Failed to list cached images: %v", err)
+		}
+		if info, err := runtime.Info(ctx); err != nil {
+			logging.Debug("Failed to read engine info: %v", err)
+		} else {
+			health.RootDir = info.RootDir
+			health.RunningContainers = info.RunningContainers
+		}
+	}
+	return health
+}
+
+// RuntimeHealthStatus returns the result of the latest health probe without
+// probing. Before the first probe the runtime is reported as unavailable.
+func RuntimeHealthStatus() RuntimeHealth {
+	healthMu.RLock()
+	defer healthMu.RUnlock()
+
+	if lastHealth.CheckedAt.IsZero() {
+		return RuntimeHealth{Error: "not checked yet"}
+	}
+	return lastHealth
+}
+
+// StartHealthMonitor probes the runtime immediately and then every interval
+// (DefaultHealthInterval if zero) until ctx is done. It returns at once; the
+// probes run in the background so executions never wait on them.
+func StartHealthMonitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+
+	go func() {
+		// A panicking probe must not take the worker down with it
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Error("Runtime health monitor stopped: %v", r)
+			}
+		}()
+
+		CheckRuntimeHealth(ctx)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				CheckRuntimeHealth(ctx)
+			}
+		}
+	}()
+}
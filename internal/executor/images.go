@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cares/internal/logging"
+)
+
+// Image pull policies, named as in Kubernetes
+const (
+	PullAlways       = "Always"       // Pull before every run, so moving tags like :latest refresh
+	PullIfNotPresent = "IfNotPresent" // Pull only when the image is missing (default)
+	PullNever        = "Never"        // Only run images already present on the worker
+)
+
+// prePullTimeout bounds a background pre-pull
+const prePullTimeout = 10 * time.Minute
+
+var (
+	imagesMu     sync.RWMutex
+	cachedImages = make(map[string]bool) // Local images as "repository:tag"
+)
+
+// CachedImages returns the images available locally on this worker, as last
+// listed by the health monitor or pulled since, sorted.
+func CachedImages() []string {
+	imagesMu.RLock()
+	defer imagesMu.RUnlock()
+
+	images := make([]string, 0, len(cachedImages))
+	for image := range cachedImages {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// noteImageCached records an image as available locally.
+func noteImageCached(image string) {
+	imagesMu.Lock()
+	defer imagesMu.Unlock()
+
+	cachedImages[image] = true
+}
+
+// refreshCachedImages replaces the cached image list with the runtime's.
+func refreshCachedImages(ctx context.Context, runtime Runtime) error {
+	images, err := runtime.Images(ctx)
+	if err != nil {
+		return err
+	}
+
+	imagesMu.Lock()
+	defer imagesMu.Unlock()
+
+	cachedImages = make(map[string]bool, len(images))
+	for _, image := range images {
+		cachedImages[image] = true
+	}
+	return nil
+}
+
+// PrePullImage pulls an image onto this worker ahead of its first invocation,
+// logging in with auth if it is not nil. Pre-pulls always fetch from the
+// registry, refreshing moving tags.
+func PrePullImage(ctx context.Context, imageName string, auth *RegistryAuth) error {
+	ctx, cancel := context.WithTimeout(ctx, prePullTimeout)
+	defer cancel()
+
+	normalizedImage, err := normalizeImageName(imageName)
+	if err != nil {
+		return err
+	}
+	if _, err := ensureImage(ctx, CurrentRuntime(), normalizedImage, PullAlways, auth); err != nil {
+		return fmt.Errorf("pre-pull of '%s' failed: %w", normalizedImage, err)
+	}
+	logging.Info("Pre-pulled image '%s'", normalizedImage)
+	return nil
+}
+
+// validPullPolicy reports whether policy is a known pull policy; empty means the default.
+func validPullPolicy(policy string) bool {
+	switch policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return true
+	}
+	return false
+}
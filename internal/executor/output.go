@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"bytes"
+	"sync"
+)
+
+// DefaultMaxOutputBytes caps captured container output when no limit is configured.
+const DefaultMaxOutputBytes = 1 << 20 // 1 MiB
+
+// Output stream names passed to OutputFunc
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// OutputFunc receives container output as it is produced. stream is
+// StreamStdout or StreamStderr. Calls are serialized, and the chunk must not
+// be retained after the call returns.
+type OutputFunc func(stream string, chunk []byte)
+
+// outputCollector captures the stdout and stderr of a container, both
+// separately and interleaved, up to a byte limit shared by the two streams.
+// Every captured chunk is forwarded to an optional OutputFunc. Output beyond
+// the limit is drained and discarded so the container never blocks on a full pipe.
+type outputCollector struct {
+	mu        sync.Mutex
+	combined  bytes.Buffer
+	stdout    bytes.Buffer
+	stderr    bytes.Buffer
+	limit     int
+	truncated bool
+	onOutput  OutputFunc
+}
+
+// newOutputCollector creates a collector capturing at most limit bytes
+// (DefaultMaxOutputBytes if limit is not positive).
+func newOutputCollector(limit int, onOutput OutputFunc) *outputCollector {
+	if limit <= 0 {
+		limit = DefaultMaxOutputBytes
+	}
+	return &outputCollector{limit: limit, onOutput: onOutput}
+}
+
+// writer returns an io.Writer feeding the named stream into the collector.
+func (c *outputCollector) writer(stream string) *streamWriter {
+	return &streamWriter{collector: c, stream: stream}
+}
+
+// write captures as much of p as still fits under the limit.
+func (c *outputCollector) write(stream string, p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	remaining := c.limit - c.combined.Len()
+	if remaining <= 0 {
+		c.truncated = true
+		return
+	}
+	if len(p) > remaining {
+		p = p[:remaining]
+		c.truncated = true
+	}
+
+	c.combined.Write(p)
+	if stream == StreamStderr {
+		c.stderr.Write(p)
+	} else {
+		c.stdout.Write(p)
+	}
+	if c.onOutput != nil {
+		c.onOutput(stream, p)
+	}
+}
+
+// fill copies the captured output into result.
+func (c *outputCollector) fill(result *RunResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result.Output = c.combined.String()
+	result.Stdout = c.stdout.String()
+	result.Stderr = c.stderr.String()
+	result.Truncated = c.truncated
+}
+
+// streamWriter adapts one output stream of a collector to io.Writer.
+type streamWriter struct {
+	collector *outputCollector
+	stream    string
+}
+
+// Write implements io.Writer. It never fails, so excess output is silently dropped.
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.collector.write(w.stream, p)
+	return len(p), nil
+}
@@ -0,0 +1,321 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cares/internal/logging"
+	"cares/internal/tracing"
+)
+
+// Function kinds the executor can run
+const (
+	KindContainer = "container" // A container image run on the current Runtime
+	KindProcess   = "process"   // A whitelisted executable run directly on the worker
+	KindWasm      = "wasm"      // A WASI module run in-process by the embedded Wasm runtime
+)
+
+// ErrProcessNotAllowed is returned when a process function names an executable
+// that is not on the worker's allowlist.
+var ErrProcessNotAllowed = errors.New("executable is not on the worker's allowlist")
+
+// ErrProcessEnvNotAllowed is returned when a process function sets an
+// environment variable that would make the loader or shell run other code.
+var ErrProcessEnvNotAllowed = errors.New("environment variable may not be set for processes")
+
+// reservedProcessEnv lists the variables functions may not set for processes:
+// with them, an allowlisted executable could be made to load or run anything.
+// Every variable starting with one of reservedProcessEnvPrefixes is reserved too.
+var reservedProcessEnv = map[string]bool{
+	"PATH":       true, // Resolves the commands run by the executable
+	"IFS":        true,
+	"ENV":        true, // Sourced by shells
+	"BASH_ENV":   true,
+	"SHELLOPTS":  true,
+	"BASHOPTS":   true,
+	"GCONV_PATH": true, // Loads glibc character set modules
+}
+
+var reservedProcessEnvPrefixes = []string{
+	"LD_",   // LD_PRELOAD, LD_LIBRARY_PATH, LD_AUDIT, ... of the dynamic loader
+	"DYLD_", // Its macOS counterparts
+}
+
+// Defaults applied to process functions
+const (
+	DefaultProcessMaxOpenFiles = 256
+	defaultProcessPath         = "/usr/local/bin:/usr/bin:/bin"
+)
+
+// ProcessConfig is the worker-side policy for process functions. Functions
+// choose what to run, but only the worker decides what may run and how.
+type ProcessConfig struct {
+	Allowed      []string // Absolute paths of executables that may be run; none if empty
+	User         string   // Account processes run as (the worker's own user if empty)
+	WorkRoot     string   // Directory holding a fresh working directory for every run
+	MaxOpenFiles int      // RLIMIT_NOFILE for processes (DefaultProcessMaxOpenFiles if zero)
+}
+
+// ProcessConfigFromEnv reads the process policy from the environment:
+// CARES_PROCESS_ALLOWLIST (comma-separated executable paths), CARES_PROCESS_USER
+// and CARES_PROCESS_WORKDIR.
+func ProcessConfigFromEnv() ProcessConfig {
+	config := ProcessConfig{
+		User:     os.Getenv("CARES_PROCESS_USER"),
+		WorkRoot: os.Getenv("CARES_PROCESS_WORKDIR"),
+	}
+	for _, path := range strings.Split(os.Getenv("CARES_PROCESS_ALLOWLIST"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			config.Allowed = append(config.Allowed, path)
+		}
+	}
+	if config.WorkRoot == "" {
+		config.WorkRoot = filepath.Join(os.TempDir(), "cares-process")
+	}
+	return config
+}
+
+var (
+	processConfigMu  sync.Mutex
+	processConfig    ProcessConfig
+	processConfigSet bool
+)
+
+// SetProcessConfig replaces the policy applied to process functions.
+func SetProcessConfig(config ProcessConfig) {
+	processConfigMu.Lock()
+	defer processConfigMu.Unlock()
+
+	processConfig = config
+	processConfigSet = true
+}
+
+// CurrentProcessConfig returns the policy applied to process functions. Unless
+// one was set with SetProcessConfig, it is read from the environment on first use.
+func CurrentProcessConfig() ProcessConfig {
+	processConfigMu.Lock()
+	defer processConfigMu.Unlock()
+
+	if !processConfigSet {
+		processConfig = ProcessConfigFromEnv()
+		processConfigSet = true
+	}
+	return processConfig
+}
+
+// RunFunctionStream runs a function of the given kind: a container image on the
+// current Runtime, an executable on this worker, or a WASI module. target is
+// the image, the executable path or the module location respectively. See RunContainerStream for the semantics of
+// onOutput and the returned RunResult.
+func RunFunctionStream(ctx context.Context, kind, target string, opts RunOptions, onOutput OutputFunc) (result *RunResult, err error) {
+	ctx, span := tracing.Start(ctx, "execute", slog.String("kind", kind), slog.String("target", target))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	switch kind {
+	case "", KindContainer:
+		return RunContainerStream(ctx, target, opts, onOutput)
+	case KindProcess:
+		return RunProcessStream(ctx, target, opts, onOutput)
+	case KindWasm:
+		return RunWasmStream(ctx, target, opts, onOutput)
+	default:
+		return nil, fmt.Errorf("unknown function kind '%s'", kind)
+	}
+}
+
+// RunProcessStream runs a whitelisted executable with opts.Args and opts.Input
+// on stdin, streaming its output to onOutput like RunContainerStream.
+//
+// The process runs as the configured user in a fresh working directory that is
+// removed afterwards, with only a minimal environment plus opts.Env. CPU time,
+// address space (from opts.MemoryMB) and open files are limited, and the whole
+// process group is killed when the timeout expires or ctx is cancelled.
+// ImageDigest reports the SHA-256 of the executable that ran.
+func RunProcessStream(ctx context.Context, executable string, opts RunOptions, onOutput OutputFunc) (*RunResult, error) {
+	config := CurrentProcessConfig()
+
+	path, err := allowedExecutable(config, executable)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProcessEnv(opts.Env); err != nil {
+		return nil, err
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read executable: %w", err)
+	}
+
+	// Every run gets its own working directory, owned by the run user
+	if err := os.MkdirAll(config.WorkRoot, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work root: %w", err)
+	}
+	workDir, err := os.MkdirTemp(config.WorkRoot, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			logging.Warn("Failed to remove process working directory '%s': %v", workDir, err)
+		}
+	}()
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// A shell applies the resource limits, then replaces itself with the executable
+	maxOpenFiles := config.MaxOpenFiles
+	if maxOpenFiles <= 0 {
+		maxOpenFiles = DefaultProcessMaxOpenFiles
+	}
+	limits := []string{
+		fmt.Sprintf("ulimit -t %d", int(timeout.Seconds())+1),
+		fmt.Sprintf("ulimit -n %d", maxOpenFiles),
+	}
+	if opts.MemoryMB > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -v %d", opts.MemoryMB*1024))
+	}
+	script := strings.Join(limits, " && ") + ` && exec "$0" "$@"`
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, path}, opts.Args...)...)
+	cmd.Dir = workDir
+	cmd.Env = processEnv(workDir, opts.Env)
+	cmd.Stdin = bytes.NewReader(opts.Input)
+	if err := configureProcess(cmd, config.User, workDir); err != nil {
+		return nil, err
+	}
+	cmd.WaitDelay = 5 * time.Second // Don't hang on pipes held open by orphaned children
+
+	collector := newOutputCollector(opts.MaxOutputBytes, onOutput)
+	cmd.Stdout = collector.writer(StreamStdout)
+	cmd.Stderr = collector.writer(StreamStderr)
+
+	logging.Debug("Running process '%s' in '%s'", path, workDir)
+	result := &RunResult{StartedAt: time.Now(), ImageDigest: digest}
+	err = cmd.Run()
+	result.FinishedAt = time.Now()
+	result.ExitCode = -1
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	collector.fill(result)
+	if result.Truncated {
+		logging.Warn("Output of process '%s' exceeded %d bytes and was truncated", path, collector.limit)
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("process execution timed out after %s", timeout)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return result, fmt.Errorf("process execution cancelled")
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if result.ExitCode < 0 {
+			return result, fmt.Errorf("process terminated: %v", exitErr)
+		}
+		return result, fmt.Errorf("process exited with code %d", result.ExitCode)
+	}
+	if err != nil {
+		return result, fmt.Errorf("process execution failed: %w", err)
+	}
+
+	logging.Debug("Process executed successfully, output length: %d bytes", len(result.Output))
+	return result, nil
+}
+
+// allowedExecutable resolves executable and checks it against the allowlist.
+// Symlinks are resolved on both sides, so a link can't smuggle in another binary.
+func allowedExecutable(config ProcessConfig, executable string) (string, error) {
+	if !filepath.IsAbs(executable) {
+		return "", fmt.Errorf("executable must be an absolute path, got '%s'", executable)
+	}
+	resolved, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return "", fmt.Errorf("executable '%s' not found: %w", executable, err)
+	}
+
+	for _, allowed := range config.Allowed {
+		allowedResolved, err := filepath.EvalSymlinks(allowed)
+		if err != nil {
+			continue
+		}
+		if allowedResolved == resolved {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: '%s'", ErrProcessNotAllowed, executable)
+}
+
+// processEnv builds a minimal environment for a process: nothing is inherited
+// from the worker except what the function configured, which checkProcessEnv
+// has accepted.
+func processEnv(workDir string, env map[string]string) []string {
+	base := map[string]string{
+		"PATH":   defaultProcessPath,
+		"HOME":   workDir,
+		"TMPDIR": workDir,
+	}
+	for key, value := range env {
+		base[key] = value
+	}
+
+	result := make([]string, 0, len(base))
+	for key, value := range base {
+		result = append(result, key+"="+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkProcessEnv rejects an environment setting a reserved variable, such as
+// PATH or LD_PRELOAD.
+func checkProcessEnv(env map[string]string) error {
+	for key := range env {
+		upper := strings.ToUpper(key)
+		reserved := reservedProcessEnv[upper]
+		for _, prefix := range reservedProcessEnvPrefixes {
+			reserved = reserved || strings.HasPrefix(upper, prefix)
+		}
+		if reserved {
+			return fmt.Errorf("%w: '%s'", ErrProcessEnvNotAllowed, key)
+		}
+	}
+	return nil
+}
+
+// fileDigest returns the SHA-256 of a file as "sha256:<hex>".
+func fileDigest(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// configureProcess reports that process functions need a Unix worker.
+func configureProcess(cmd *exec.Cmd, username, workDir string) error {
+	return fmt.Errorf("process functions are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProcessEnvRejectsReservedVariables(t *testing.T) {
+	const env = "/usr/bin/env"
+	if _, err := os.Stat(env); err != nil {
+		t.Skipf("%s is not available: %v", env, err)
+	}
+	previous := CurrentProcessConfig()
+	SetProcessConfig(ProcessConfig{Allowed: []string{env}, WorkRoot: t.TempDir()})
+	t.Cleanup(func() { SetProcessConfig(previous) })
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"plain variable", map[string]string{"GREETING": "hello"}, false},
+		{"PATH", map[string]string{"PATH": "/tmp/evil"}, true},
+		{"LD_PRELOAD", map[string]string{"LD_PRELOAD": "/tmp/evil.so"}, true},
+		{"LD_LIBRARY_PATH", map[string]string{"LD_LIBRARY_PATH": "/tmp"}, true},
+		{"lowercase loader variable", map[string]string{"ld_audit": "/tmp/evil.so"}, true},
+		{"DYLD_INSERT_LIBRARIES", map[string]string{"DYLD_INSERT_LIBRARIES": "/tmp/evil.dylib"}, true},
+		{"BASH_ENV", map[string]string{"BASH_ENV": "/tmp/evil.sh"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunProcessStream(context.Background(), env, RunOptions{Env: tt.env}, nil)
+			if tt.wantErr {
+				if !errors.Is(err, ErrProcessEnvNotAllowed) {
+					t.Fatalf("err = %v, want ErrProcessEnvNotAllowed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			if !strings.Contains(result.Stdout, "GREETING=hello\n") || !strings.Contains(result.Stdout, "PATH="+defaultProcessPath+"\n") {
+				t.Errorf("environment = %q, want GREETING and the default PATH", result.Stdout)
+			}
+		})
+	}
+}
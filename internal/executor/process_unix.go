@@ -0,0 +1,50 @@
+//go:build unix
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// configureProcess puts the process in its own process group, so the whole
+// tree can be killed, and switches it to the given user if one is configured.
+// Switching users requires the worker to run as root.
+func configureProcess(cmd *exec.Cmd, username, workDir string) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		// A negative PID signals the whole process group
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	if username == "" {
+		return nil
+	}
+
+	account, err := user.Lookup(username)
+	if err != nil {
+		return fmt.Errorf("failed to look up process user '%s': %w", username, err)
+	}
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid uid for process user '%s': %w", username, err)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid gid for process user '%s': %w", username, err)
+	}
+
+	if err := os.Chown(workDir, int(uid), int(gid)); err != nil {
+		return fmt.Errorf("failed to hand working directory to '%s': %w", username, err)
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    uint32(uid),
+		Gid:    uint32(gid),
+		Groups: []uint32{}, // Drop the worker's supplementary groups
+	}
+	return nil
+}
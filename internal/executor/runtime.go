@@ -0,0 +1,193 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"cares/internal/imageref"
+	"cares/internal/logging"
+)
+
+// ErrImageNotFound is returned by Runtime.Inspect when an image is not available locally.
+var ErrImageNotFound = errors.New("image not found")
+
+// Runtime kinds selectable through CARES_RUNTIME
+const (
+	RuntimeDocker    = "docker"     // Docker Engine API over the unix socket (default)
+	RuntimeDockerCLI = "docker-cli" // The docker command line client
+	RuntimePodman    = "podman"     // The podman command line client
+	RuntimeFake      = "fake"       // In-memory runtime for running without a container engine
+)
+
+// Runtime is a container engine the executor can run functions on.
+//
+// Implementations must be safe for concurrent use.
+type Runtime interface {
+	// Name identifies the runtime in logs and reports.
+	Name() string
+	// Version reports the engine's version, failing if the engine is unreachable.
+	// It doubles as the health probe, so it must not start or repair anything.
+	Version(ctx context.Context) (string, error)
+	// Pull fetches an image from its registry, logging in with auth if it is
+	// not nil. Credentials must not outlive the call or end up in logs.
+	Pull(ctx context.Context, image string, auth *RegistryAuth) error
+	// Inspect describes a locally available image, or returns ErrImageNotFound.
+	Inspect(ctx context.Context, image string) (*ImageInfo, error)
+	// Images lists the tagged images available locally, as "repository:tag".
+	Images(ctx context.Context) ([]string, error)
+	// Info reports where the engine keeps its data and how busy it is.
+	Info(ctx context.Context) (*EngineInfo, error)
+	// Run creates and starts a container, copies its stdout and stderr to the
+	// given writers until it exits, removes it, and returns its exit code. If
+	// ctx is done first, the container is killed and ctx's error returned.
+	Run(ctx context.Context, spec ContainerSpec, stdout, stderr io.Writer) (int, error)
+	// Kill stops a running container immediately.
+	Kill(ctx context.Context, container string) error
+	// Logs copies the output a container has produced so far to the given
+	// writers, and keeps copying until it exits if follow is set.
+	Logs(ctx context.Context, container string, follow bool, stdout, stderr io.Writer) error
+}
+
+// RegistryAuth is a login for a private registry, used only to pull images.
+type RegistryAuth struct {
+	Server   string // Registry host the credentials belong to, e.g. "ghcr.io"
+	Username string
+	Password string
+}
+
+// String implements fmt.Stringer without revealing the password, so an auth
+// that ends up in a log line by accident does no harm.
+func (a *RegistryAuth) String() string {
+	return fmt.Sprintf("%s@%s", a.Username, a.Server)
+}
+
+// appliesTo reports whether the credentials are for the registry image is
+// pulled from. Credentials are never sent to any other registry.
+func (a *RegistryAuth) appliesTo(image string) bool {
+	ref, err := imageref.Parse(image)
+	return err == nil && imageref.NormalizeRegistry(a.Server) == ref.Registry
+}
+
+// serverAddress is the address docker expects in its auth configuration.
+func (a *RegistryAuth) serverAddress() string {
+	if server := imageref.NormalizeRegistry(a.Server); server != imageref.DefaultRegistry {
+		return server
+	}
+	return "https://index.docker.io/v1/"
+}
+
+// ImageInfo describes a local image.
+type ImageInfo struct {
+	ID          string   // Content-addressable image ID (sha256:...)
+	RepoDigests []string // Registry manifest digests, as "repository@sha256:..."
+}
+
+// EngineInfo describes the state of a container engine.
+type EngineInfo struct {
+	RootDir           string // Directory holding images and containers, e.g. "/var/lib/docker"
+	RunningContainers int    // Containers currently running, including ones not started by CARES
+}
+
+// ContainerSpec describes a container to run.
+type ContainerSpec struct {
+	Name     string            // Unique container name, used to kill it or read its logs
+	Image    string            // Image reference or ID
+	Env      map[string]string // Environment variables
+	MemoryMB int               // Memory limit in megabytes (unlimited if zero)
+}
+
+// sortedEnv returns the environment as sorted KEY=value pairs, so command lines
+// and API requests are deterministic in logs.
+func (s ContainerSpec) sortedEnv() []string {
+	env := make([]string, 0, len(s.Env))
+	for key, value := range s.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// NewRuntime creates the runtime of the given kind.
+func NewRuntime(kind string) (Runtime, error) {
+	switch kind {
+	case "", RuntimeDocker:
+		// Returned explicitly, so a failure isn't a non-nil Runtime holding a nil *DockerRuntime
+		runtime, err := NewDockerRuntime(os.Getenv("DOCKER_HOST"))
+		if err != nil {
+			return nil, err
+		}
+		return runtime, nil
+	case RuntimeDockerCLI:
+		return NewCLIRuntime("docker"), nil
+	case RuntimePodman:
+		return NewCLIRuntime("podman"), nil
+	case RuntimeFake:
+		return NewFakeRuntime(), nil
+	default:
+		return nil, fmt.Errorf("unknown container runtime '%s' (expected '%s', '%s', '%s' or '%s')",
+			kind, RuntimeDocker, RuntimeDockerCLI, RuntimePodman, RuntimeFake)
+	}
+}
+
+var (
+	runtimeMu     sync.Mutex
+	activeRuntime Runtime
+	runtimeErr    error // Why the configured runtime could not be created
+)
+
+// SetRuntime replaces the runtime used by RunContainer and RunContainerStream.
+func SetRuntime(runtime Runtime) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	activeRuntime = runtime
+	runtimeErr = nil
+}
+
+// CurrentRuntime returns the runtime functions are executed on. Unless one was
+// set with SetRuntime, it is created from the CARES_RUNTIME and DOCKER_HOST
+// environment variables on first use. If they are invalid, the Docker Engine
+// API on DefaultDockerSocket is used instead, so the result is never nil.
+func CurrentRuntime() Runtime {
+	runtime, _ := currentRuntime()
+	return runtime
+}
+
+// currentRuntime is CurrentRuntime, also returning why the configured runtime
+// could not be created, if it couldn't. The runtime is nil only if no
+// fallback could be created either.
+func currentRuntime() (Runtime, error) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+
+	if activeRuntime == nil {
+		runtime, err := NewRuntime(os.Getenv("CARES_RUNTIME"))
+		if err != nil {
+			logging.Warn("%v, using %s on %s", err, RuntimeDocker, DefaultDockerSocket)
+			runtimeErr = err
+			runtime = defaultRuntime()
+		}
+		if runtime == nil {
+			return nil, runtimeErr
+		}
+		logging.Info("Using container runtime '%s'", runtime.Name())
+		activeRuntime = runtime
+	}
+	return activeRuntime, runtimeErr
+}
+
+// defaultRuntime returns the Docker Engine API on the default socket, or the
+// docker CLI should that ever fail to be created.
+func defaultRuntime() Runtime {
+	runtime, err := NewDockerRuntime("")
+	if err != nil {
+		logging.Warn("%v, using %s", err, RuntimeDockerCLI)
+		return NewCLIRuntime("docker")
+	}
+	return runtime
+}
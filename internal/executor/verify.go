@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"cares/internal/distribution"
+	"cares/internal/imageref"
+	"cares/internal/logging"
+)
+
+// Reasons an image is refused before it runs, distinguishable with errors.Is
+var (
+	ErrImagePull             = errors.New("image pull error")
+	ErrDigestMismatch        = errors.New("image digest mismatch")
+	ErrSignatureVerification = errors.New("signature verification failed")
+)
+
+var (
+	signatureMu      sync.Mutex
+	signatureKeys    []crypto.PublicKey
+	signatureKeysErr error
+	signatureKeysSet bool
+	verifiedImages   = make(map[string]bool) // "name@digest" verified with the current keys
+	registryClient   *distribution.Client
+)
+
+// SignatureKeysFromEnv loads the public keys image signatures are verified
+// against from the PEM files listed in CARES_SIGNATURE_KEYS (comma-separated).
+func SignatureKeysFromEnv() ([]crypto.PublicKey, error) {
+	var paths []string
+	for _, path := range strings.Split(os.Getenv("CARES_SIGNATURE_KEYS"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return distribution.LoadPublicKeys(paths)
+}
+
+// SetSignatureKeys replaces the public keys trusted to sign images.
+func SetSignatureKeys(keys []crypto.PublicKey) {
+	signatureMu.Lock()
+	defer signatureMu.Unlock()
+
+	signatureKeys, signatureKeysErr, signatureKeysSet = keys, nil, true
+	verifiedImages = make(map[string]bool)
+}
+
+// pinnedImage returns the reference that pulls exactly digest of image.
+func pinnedImage(image, digest string) (string, error) {
+	ref, err := imageref.Parse(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" && ref.Digest != digest {
+		return "", fmt.Errorf("%w: reference '%s' conflicts with pinned digest %s", ErrDigestMismatch, image, digest)
+	}
+	ref.Tag, ref.Digest = "", digest
+	return ref.String(), nil
+}
+
+// verifyDigest checks that an image is the manifest it was pinned to.
+func verifyDigest(imageName string, info *ImageInfo, digest string) error {
+	for _, repoDigest := range info.RepoDigests {
+		if _, candidate, ok := strings.Cut(repoDigest, "@"); ok && candidate == digest {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s' resolved to %s, expected %s", ErrDigestMismatch, imageName, reportedDigest(imageName, info), digest)
+}
+
+// verifyImageSignature checks that digest of image is signed by a trusted
+// key. Successful verifications are remembered, so only the first run of an
+// image costs registry round trips.
+func verifyImageSignature(ctx context.Context, image, digest string, auth *RegistryAuth) error {
+	signatureMu.Lock()
+	if !signatureKeysSet {
+		signatureKeys, signatureKeysErr = SignatureKeysFromEnv()
+		signatureKeysSet = true
+	}
+	if registryClient == nil {
+		registryClient = distribution.NewClient()
+	}
+	keys, keysErr, client := signatureKeys, signatureKeysErr, registryClient
+	key := repositoryOf(image) + "@" + digest
+	verified := verifiedImages[key]
+	signatureMu.Unlock()
+
+	if verified {
+		return nil
+	}
+	if keysErr != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureVerification, keysErr)
+	}
+
+	var creds *distribution.Credentials
+	if auth != nil && auth.appliesTo(image) {
+		creds = &distribution.Credentials{Username: auth.Username, Password: auth.Password}
+	}
+	if err := client.VerifySignature(ctx, image, digest, keys, creds); err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureVerification, err)
+	}
+	logging.Info("Verified signature of '%s'", key)
+
+	signatureMu.Lock()
+	verifiedImages[key] = true
+	signatureMu.Unlock()
+	return nil
+}
@@ -0,0 +1,236 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+
+	"cares/internal/imageref"
+	"cares/internal/logging"
+)
+
+// Limits applied to WebAssembly functions
+const (
+	DefaultWasmMemoryMB = 256              // Linear memory limit when a function sets none
+	MaxWasmModuleBytes  = 64 * 1024 * 1024 // Largest module that will be loaded
+	wasmPagesPerMB      = 16               // Wasm pages are 64KiB
+	maxWasmPages        = 65536            // 4GiB, the wasm32 address space
+	wasmDownloadTimeout = 2 * time.Minute  // Longest a module download may take
+)
+
+// wasmClient downloads modules served over http(s)
+var wasmClient = &http.Client{Timeout: wasmDownloadTimeout}
+
+// wasmMagic starts every binary WebAssembly module
+var wasmMagic = []byte("\x00asm")
+
+var (
+	wasmCachesMu sync.Mutex
+	// wasmCaches keeps compiled modules between runs, one cache per memory
+	// limit since the limit is part of the compiled module
+	wasmCaches = make(map[uint32]wazero.CompilationCache)
+)
+
+// wasmCache returns the compilation cache for runtimes with the given memory limit.
+func wasmCache(pages uint32) wazero.CompilationCache {
+	wasmCachesMu.Lock()
+	defer wasmCachesMu.Unlock()
+
+	cache, exists := wasmCaches[pages]
+	if !exists {
+		cache = wazero.NewCompilationCache()
+		wasmCaches[pages] = cache
+	}
+	return cache
+}
+
+// RunWasmStream runs a WASI module in-process with an embedded WebAssembly
+// runtime, streaming its output to onOutput like RunContainerStream.
+//
+// module is an absolute path on the worker or an http(s) URL. A URL may end
+// in a "#sha256:<hex>" fragment pinning the module's content, which plain
+// http URLs must do; opts.Digest pins it the same way. opts.Input is
+// passed on stdin and opts.Args as command-line arguments; the module gets no
+// filesystem access. Linear memory is capped at opts.MemoryMB
+// (DefaultWasmMemoryMB if zero) and the module is stopped when the timeout
+// expires or ctx is cancelled. ImageDigest reports the SHA-256 of the module.
+func RunWasmStream(ctx context.Context, module string, opts RunOptions, onOutput OutputFunc) (*RunResult, error) {
+	binary, digest, err := loadWasmModule(ctx, module, opts.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	memoryMB := opts.MemoryMB
+	if memoryMB <= 0 {
+		memoryMB = DefaultWasmMemoryMB
+	}
+	pages := uint32(maxWasmPages)
+	if memoryMB*wasmPagesPerMB < maxWasmPages {
+		pages = uint32(memoryMB * wasmPagesPerMB)
+	}
+
+	// Every run gets its own runtime so nothing leaks between invocations;
+	// closing the module when ctx is done stops even tight loops
+	config := wazero.NewRuntimeConfig().
+		WithCompilationCache(wasmCache(pages)).
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true)
+	runtime := wazero.NewRuntimeWithConfig(ctx, config)
+	defer runtime.Close(context.Background())
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return nil, fmt.Errorf("failed to set up WASI: %w", err)
+	}
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wasm module: %w", err)
+	}
+
+	// Compilation is cached and doesn't count against the function's timeout
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	collector := newOutputCollector(opts.MaxOutputBytes, onOutput)
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{filepath.Base(module)}, opts.Args...)...).
+		WithStdin(bytes.NewReader(opts.Input)).
+		WithStdout(collector.writer(StreamStdout)).
+		WithStderr(collector.writer(StreamStderr)).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithNanosleep(func(ns int64) {
+			// Sleeping modules must still notice the deadline
+			timer := time.NewTimer(time.Duration(ns))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}).
+		WithRandSource(rand.Reader)
+	for key, value := range opts.Env {
+		moduleConfig = moduleConfig.WithEnv(key, value)
+	}
+
+	logging.Debug("Running wasm module '%s' (%d bytes, %dMB memory)", module, len(binary), memoryMB)
+	result := &RunResult{StartedAt: time.Now(), ImageDigest: digest}
+	_, err = runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	result.FinishedAt = time.Now()
+
+	collector.fill(result)
+	if result.Truncated {
+		logging.Warn("Output of wasm module '%s' exceeded %d bytes and was truncated", module, collector.limit)
+	}
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr) && exitErr.ExitCode() < sys.ExitCodeDeadlineExceeded:
+		result.ExitCode = int(exitErr.ExitCode())
+	default:
+		result.ExitCode = -1
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("wasm execution timed out after %s", timeout)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return result, fmt.Errorf("wasm execution cancelled")
+	}
+	if result.ExitCode > 0 {
+		return result, fmt.Errorf("wasm module exited with code %d", result.ExitCode)
+	}
+	if err != nil {
+		return result, fmt.Errorf("wasm execution failed: %w", err)
+	}
+
+	logging.Debug("Wasm module executed successfully, output length: %d bytes", len(result.Output))
+	return result, nil
+}
+
+// loadWasmModule reads a module from an absolute path or downloads it from an
+// http(s) URL, refusing anything that is not a binary WebAssembly module, and
+// returns it with its digest. A module pinned by its "#sha256:<hex>" fragment
+// or to the pinned digest is refused with ErrDigestMismatch if its content
+// differs; modules are only fetched over plain http if they are pinned.
+func loadWasmModule(ctx context.Context, module, pinned string) ([]byte, string, error) {
+	location, expected, found := strings.Cut(module, "#")
+	if found {
+		if !strings.HasPrefix(expected, "sha256:") || !imageref.ValidDigest(expected) {
+			return nil, "", fmt.Errorf("invalid wasm module digest '%s', expected sha256:<64 hex digits>", expected)
+		}
+		if pinned != "" && pinned != expected {
+			return nil, "", fmt.Errorf("%w: wasm module URL pins %s, the function is pinned to %s", ErrDigestMismatch, expected, pinned)
+		}
+	} else {
+		expected = pinned
+	}
+
+	var reader io.Reader
+	switch {
+	case strings.HasPrefix(location, "http://") && expected == "":
+		return nil, "", fmt.Errorf("wasm module '%s' is served over plain http, use https or pin it with a #sha256:<hex> digest", module)
+	case strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid module URL: %w", err)
+		}
+		resp, err := wasmClient.Do(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to download wasm module: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("failed to download wasm module: %s", resp.Status)
+		}
+		reader = resp.Body
+	case filepath.IsAbs(location):
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open wasm module: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	default:
+		return nil, "", fmt.Errorf("wasm module must be an absolute path or http(s) URL, got '%s'", module)
+	}
+
+	binary, err := io.ReadAll(io.LimitReader(reader, MaxWasmModuleBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read wasm module: %w", err)
+	}
+	if len(binary) > MaxWasmModuleBytes {
+		return nil, "", fmt.Errorf("wasm module exceeds %d bytes", MaxWasmModuleBytes)
+	}
+	if !bytes.HasPrefix(binary, wasmMagic) {
+		return nil, "", fmt.Errorf("'%s' is not a binary wasm module", module)
+	}
+
+	sum := sha256.Sum256(binary)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if expected != "" && digest != expected {
+		return nil, "", fmt.Errorf("%w: wasm module '%s' is %s, expected %s", ErrDigestMismatch, location, digest, expected)
+	}
+	return binary, digest, nil
+}
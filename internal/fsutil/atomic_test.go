@@ -0,0 +1,61 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // Contents before the write, no file if empty
+	}{
+		{name: "new file"},
+		{name: "replacing a file", existing: "old contents\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "nested", "state.json")
+			if tt.existing != "" {
+				os.MkdirAll(filepath.Dir(path), 0755)
+				os.WriteFile(path, []byte(tt.existing), 0644)
+			}
+
+			if err := WriteFileAtomic(path, []byte("new contents\n"), 0600); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != "new contents\n" {
+				t.Errorf("file holds %q (%v), want the new contents", data, err)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("mode = %v (%v), want 0600", info.Mode().Perm(), err)
+			}
+			if leftovers, _ := filepath.Glob(path + ".tmp-*"); len(leftovers) != 0 {
+				t.Errorf("temporary files left behind: %v", leftovers)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicFailureKeepsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	os.WriteFile(path, []byte("old contents\n"), 0644)
+
+	// A directory can't be renamed over, so the write fails at the last step
+	target := filepath.Join(dir, "target")
+	os.MkdirAll(filepath.Join(target, "child"), 0755)
+	if err := WriteFileAtomic(target, []byte("new contents\n"), 0644); err == nil {
+		t.Fatal("write over a non-empty directory succeeded")
+	}
+	if leftovers, _ := filepath.Glob(target + ".tmp-*"); len(leftovers) != 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old contents\n" {
+		t.Errorf("unrelated file holds %q", data)
+	}
+}
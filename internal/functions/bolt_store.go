@@ -0,0 +1,89 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// functionsBucket holds one JSON document per function, keyed by function ID.
+var functionsBucket = []byte("functions")
+
+// BoltStore persists functions in an embedded bbolt key-value database.
+//
+// Unlike FileStore, each change only writes the affected function, and every
+// write is a fully durable bbolt transaction.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (creating if needed) the bbolt database at path.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// A timeout prevents hanging forever if another process holds the lock
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open function database: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(functionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize function database: %v", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Load returns every function in the database.
+func (s *BoltStore) Load() ([]*Function, error) {
+	var functions []*Function
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(functionsBucket).ForEach(func(key, value []byte) error {
+			var fn Function
+			if err := json.Unmarshal(value, &fn); err != nil {
+				return fmt.Errorf("failed to unmarshal function '%s': %v", key, err)
+			}
+			functions = append(functions, &fn)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return functions, nil
+}
+
+// Put stores fn in its own key.
+func (s *BoltStore) Put(fn *Function) error {
+	data, err := json.Marshal(fn)
+	if err != nil {
+		return fmt.Errorf("failed to marshal function: %v", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(functionsBucket).Put([]byte(fn.ID), data)
+	})
+}
+
+// Delete removes the function with the given ID.
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(functionsBucket).Delete([]byte(id))
+	})
+}
+
+// Close closes the underlying database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -1,19 +1,23 @@
 package functions
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
-	"cares/internal/logging"
-
 	"github.com/google/uuid"
+
+	"cares/internal/events"
+	"cares/internal/imageref"
+	"cares/internal/logging"
 )
 
+// statsFlushInterval is how often invocation statistics are persisted.
+const statsFlushInterval = 10 * time.Second
+
 // Function represents a registered function in the system
 type Function struct {
 	ID          string    `json:"id"`
@@ -22,42 +26,209 @@ type Function struct {
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	Status      string    `json:"status"` // "active", "inactive"
+	// Kind selects how workers run the function. For process functions Image
+	// holds the absolute path of the executable, for wasm functions the absolute
+	// path or http(s) URL of a WASI module, instead of a container image; a
+	// URL may pin the module's content with a "#sha256:<hex>" fragment.
+	Kind string `json:"kind"` // "container", "process", "wasm"
+
+	// StatusWindows schedule temporary status overrides; see EffectiveStatus.
+	StatusWindows []StatusWindow `json:"status_windows,omitempty"`
+
+	// Settings holds the tunable execution parameters of the function.
+	Settings FunctionSettings `json:"settings"`
+	// Revision is incremented on every change to the function definition and
+	// is used as the ETag for optimistic concurrency on updates.
+	Revision int64 `json:"revision"`
+
+	// Versions holds every published image of the function, oldest first.
+	// Image always mirrors the most recently published version.
+	Versions []FunctionVersion `json:"versions,omitempty"`
+	// TrafficSplit maps version numbers to the percentage of invocations
+	// routed to them. An empty split routes everything to the latest version.
+	TrafficSplit map[int]int `json:"traffic_split,omitempty"`
 }
 
-// Registry provides thread-safe management of registered functions
-type Registry struct {
-	mu        sync.RWMutex
-	functions map[string]*Function
+// FunctionSettings contains per-function execution parameters forwarded to workers.
+// Zero values mean "use the worker default".
+type FunctionSettings struct {
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	MemoryMB       int               `json:"memory_mb,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Args           []string          `json:"args,omitempty"`        // Command-line arguments of process and wasm functions
+	PullPolicy     string            `json:"pull_policy,omitempty"` // "Always", "IfNotPresent" (default) or "Never"
+	// RegistryCredential names the stored credential used to pull the image
+	// from a private registry (empty for public images)
+	RegistryCredential string `json:"registry_credential,omitempty"`
+	// VerifySignature makes workers refuse images without a signature by a
+	// key they trust (container functions only)
+	VerifySignature bool `json:"verify_signature,omitempty"`
+}
+
+// Image pull policies of container functions
+const (
+	PullAlways       = "Always"
+	PullIfNotPresent = "IfNotPresent"
+	PullNever        = "Never"
+)
+
+// Function kinds
+const (
+	KindContainer = "container"
+	KindProcess   = "process"
+	KindWasm      = "wasm"
+)
+
+// validateTarget checks that image is something a function of the given kind can run.
+func validateTarget(kind, image string) error {
+	switch kind {
+	case KindContainer:
+		if _, err := imageref.Parse(image); err != nil {
+			return &ValidationError{Fields: []FieldError{{Field: "image", Message: err.Error()}}}
+		}
+		return nil
+	case KindProcess:
+		if !filepath.IsAbs(image) {
+			return &ValidationError{Fields: []FieldError{{Field: "image", Message: "must be an absolute executable path for process functions"}}}
+		}
+		return nil
+	case KindWasm:
+		if !filepath.IsAbs(image) && !strings.HasPrefix(image, "http://") && !strings.HasPrefix(image, "https://") {
+			return &ValidationError{Fields: []FieldError{{Field: "image", Message: "must be an absolute path or http(s) URL of a wasm module"}}}
+		}
+		if strings.HasPrefix(image, "http://") && !strings.Contains(image, "#sha256:") {
+			return &ValidationError{Fields: []FieldError{{Field: "image", Message: "plain http wasm module URLs must be pinned with a #sha256:<hex> digest, or use https"}}}
+		}
+		return nil
+	default:
+		return &ValidationError{Fields: []FieldError{{Field: "kind", Message: fmt.Sprintf("must be '%s', '%s' or '%s'", KindContainer, KindProcess, KindWasm)}}}
+	}
 }
 
-// The default storage file path
-const DefaultStoragePath = "data/functions.json"
+// Registry provides thread-safe management of registered functions.
+// Every change is written through to the backing Store before it becomes
+// visible, so a failed write leaves the registry unchanged. Invocation
+// statistics are the exception: they change on every invocation, so they are
+// kept in memory and persisted every statsFlushInterval, with the next change
+// to the function, and on Close.
+type Registry struct {
+	mu         sync.RWMutex
+	functions  map[string]*Function
+	store      Store
+	statsDirty map[string]struct{} // Functions whose statistics are not persisted yet
+	stopFlush  chan struct{}
+	flushDone  chan struct{}
+	closeOnce  sync.Once
+}
 
-// NewRegistry creates a new function registry
-func NewRegistry() *Registry {
+// NewRegistry creates a function registry backed by store and loads the
+// functions already persisted there.
+func NewRegistry(store Store) (*Registry, error) {
 	registry := &Registry{
-		functions: make(map[string]*Function),
+		functions:  make(map[string]*Function),
+		store:      store,
+		statsDirty: make(map[string]struct{}),
+		stopFlush:  make(chan struct{}),
+		flushDone:  make(chan struct{}),
 	}
-	
-	// Try to load from default storage file
-	err := registry.LoadFromFile(DefaultStoragePath)
+
+	functions, err := store.Load()
 	if err != nil {
-		// Just log the error, don't fail
-		logging.Warn("Could not load function registry: %v", err)
+		return nil, fmt.Errorf("could not load function registry: %w", err)
 	}
-	
-	return registry
+	for _, fn := range functions {
+		backfill(fn)
+		registry.functions[fn.ID] = fn
+	}
+
+	go registry.flushStatsPeriodically()
+	return registry, nil
 }
 
-// AddFunction adds a new function to the registry
-func (r *Registry) AddFunction(name, image, description string) (*Function, error) {
+// Close persists the pending invocation statistics and closes the backing
+// store. The registry must not be used afterwards.
+func (r *Registry) Close() error {
+	r.closeOnce.Do(func() { close(r.stopFlush) })
+	<-r.flushDone
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flushErr := r.flushStatsLocked()
+	if err := r.store.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// FlushStats persists the invocation statistics recorded since the last flush.
+func (r *Registry) FlushStats() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.flushStatsLocked()
+}
+
+// flushStatsLocked persists the functions with pending statistics. A function
+// that fails to persist stays pending. The caller must hold r.mu.
+func (r *Registry) flushStatsLocked() error {
+	var firstErr error
+	for id := range r.statsDirty {
+		if fn, exists := r.functions[id]; exists {
+			if err := r.store.Put(fn); err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to persist statistics of function '%s': %w", fn.Name, err)
+				}
+				continue
+			}
+		}
+		delete(r.statsDirty, id)
+	}
+	return firstErr
+}
+
+// flushStatsPeriodically flushes the statistics every statsFlushInterval
+// until Close.
+func (r *Registry) flushStatsPeriodically() {
+	defer close(r.flushDone)
+
+	ticker := time.NewTicker(statsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.FlushStats(); err != nil {
+				logging.Warn("%v", err)
+			}
+		case <-r.stopFlush:
+			return
+		}
+	}
+}
+
+// AddFunction adds a new container function to the registry
+func (r *Registry) AddFunction(ctx context.Context, name, image, description string) (*Function, error) {
+	return r.AddFunctionOfKind(ctx, KindContainer, name, image, description)
+}
+
+// AddFunctionOfKind adds a new function of the given kind to the registry.
+// For process functions image is the absolute path of the executable, for wasm
+// functions the location of the module.
+//
+// Like every change to the registry, it is published as an event attributed
+// to the actor of ctx (see events.WithActor).
+func (r *Registry) AddFunctionOfKind(ctx context.Context, kind, name, image, description string) (*Function, error) {
+	if err := validateTarget(kind, image); err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	// Check if function name already exists
 	for _, fn := range r.functions {
 		if fn.Name == name {
-			return nil, fmt.Errorf("function with name '%s' already exists", name)
+			return nil, fmt.Errorf("function with name '%s' already exists: %w", name, ErrDuplicateName)
 		}
 	}
 
@@ -67,16 +238,20 @@ func (r *Registry) AddFunction(name, image, description string) (*Function, erro
 		Name:        name,
 		Image:       image,
 		Description: description,
+		Kind:        kind,
 		CreatedAt:   time.Now(),
-		Status:      "active",
+		Status:      StatusActive,
+		Revision:    1,
 	}
+	backfill(function)
 
+	if err := r.store.Put(function); err != nil {
+		return nil, fmt.Errorf("failed to persist function: %w", err)
+	}
 	r.functions[function.ID] = function
-	
-	// Save changes to file
-	go r.SaveToFile(DefaultStoragePath) // Run in background to avoid blocking
-	
-	return function, nil
+
+	publish(ctx, events.FunctionRegistered, function, map[string]string{"kind": kind, "image": image})
+	return function.clone(), nil
 }
 
 // GetFunction retrieves a function by ID
@@ -90,8 +265,7 @@ func (r *Registry) GetFunction(id string) (*Function, bool) {
 	}
 
 	// Return a copy to prevent concurrent access issues
-	fnCopy := *fn
-	return &fnCopy, true
+	return fn.clone(), true
 }
 
 // GetFunctionByName retrieves a function by name
@@ -102,8 +276,7 @@ func (r *Registry) GetFunctionByName(name string) (*Function, bool) {
 	for _, fn := range r.functions {
 		if fn.Name == name {
 			// Return a copy to prevent concurrent access issues
-			fnCopy := *fn
-			return &fnCopy, true
+			return fn.clone(), true
 		}
 	}
 
@@ -118,8 +291,7 @@ func (r *Registry) GetAllFunctions() []*Function {
 	functions := make([]*Function, 0, len(r.functions))
 	for _, fn := range r.functions {
 		// Return copies to prevent concurrent access issues
-		fnCopy := *fn
-		functions = append(functions, &fnCopy)
+		functions = append(functions, fn.clone())
 	}
 
 	// Sort functions by creation time to ensure consistent order
@@ -134,20 +306,25 @@ func (r *Registry) GetAllFunctions() []*Function {
 	return functions
 }
 
-// RemoveFunction removes a function from the registry
-func (r *Registry) RemoveFunction(id string) bool {
+// RemoveFunction removes a function from the registry.
+// Returns ErrFunctionNotFound if no function has the given ID.
+func (r *Registry) RemoveFunction(ctx context.Context, id string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	_, exists := r.functions[id]
-	if exists {
-		delete(r.functions, id)
-		
-		// Save changes to file
-		go r.SaveToFile(DefaultStoragePath) // Run in background to avoid blocking
+	function, exists := r.functions[id]
+	if !exists {
+		return ErrFunctionNotFound
+	}
+
+	if err := r.store.Delete(id); err != nil {
+		return fmt.Errorf("failed to persist function removal: %w", err)
 	}
+	delete(r.functions, id)
+	delete(r.statsDirty, id)
 
-	return exists
+	publish(ctx, events.FunctionDeleted, function, nil)
+	return nil
 }
 
 // GetFunctionCount returns the total number of functions
@@ -158,83 +335,56 @@ func (r *Registry) GetFunctionCount() int {
 	return len(r.functions)
 }
 
-// UpdateFunctionStatus updates the status of a function
-func (r *Registry) UpdateFunctionStatus(id, status string) bool {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// UpdateFunctionStatus updates the manual status of a function and returns a
+// copy of the updated function.
+func (r *Registry) UpdateFunctionStatus(ctx context.Context, id, status string) (*Function, error) {
+	if !validStatus(status) {
+		return nil, &ValidationError{Fields: []FieldError{{
+			Field:   "status",
+			Message: fmt.Sprintf("must be '%s' or '%s'", StatusActive, StatusInactive),
+		}}}
+	}
 
-	fn, exists := r.functions[id]
-	if !exists {
-		return false
+	function, err := r.mutate(id, func(fn *Function) error {
+		fn.Status = status
+		fn.Revision++
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	fn.Status = status
-	
-	// Save changes to file
-	go r.SaveToFile(DefaultStoragePath) // Run in background to avoid blocking
-	
-	return true
+	publish(ctx, events.FunctionStatusChanged, function, map[string]string{"status": status})
+	return function, nil
 }
 
-// SaveToFile saves the registry to a JSON file
-func (r *Registry) SaveToFile(filePath string) error {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
-	}
-	
-	// Convert registry to a slice for serialization
-	functions := r.GetAllFunctions()
-	
-	// Marshal to JSON
-	data, err := json.MarshalIndent(functions, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal registry: %v", err)
-	}
-	
-	// Write to file
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write registry file: %v", err)
-	}
-	
-	return nil
+// publish publishes an event about a function on the process-wide event bus.
+func publish(ctx context.Context, eventType events.Type, fn *Function, data map[string]string) {
+	events.Publish(ctx, events.Event{Type: eventType, Subject: fn.ID, Name: fn.Name, Data: data})
 }
 
-// LoadFromFile loads the registry from a JSON file
-func (r *Registry) LoadFromFile(filePath string) error {
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		// File doesn't exist, but that's not an error
-		return nil
-	}
-	
-	// Read file
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read registry file: %v", err)
-	}
-	
-	// Unmarshal JSON
-	var functions []*Function
-	if err := json.Unmarshal(data, &functions); err != nil {
-		return fmt.Errorf("failed to unmarshal registry: %v", err)
-	}
-	
-	// Lock and update registry
+// mutate applies change to a copy of the function, persists the copy and only
+// then swaps it into the registry. If change or the store fails, the registry
+// is left untouched and the error is returned.
+func (r *Registry) mutate(id string, change func(fn *Function) error) (*Function, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	
-	// Clear existing functions
-	r.functions = make(map[string]*Function)
-	
-	// Add loaded functions
-	for _, fn := range functions {
-		r.functions[fn.ID] = fn
+
+	current, exists := r.functions[id]
+	if !exists {
+		return nil, ErrFunctionNotFound
 	}
-	
-	return nil
+
+	updated := current.clone()
+	if err := change(updated); err != nil {
+		return nil, err
+	}
+
+	if err := r.store.Put(updated); err != nil {
+		return nil, fmt.Errorf("failed to persist function: %w", err)
+	}
+	r.functions[id] = updated
+	delete(r.statsDirty, id) // Persisted along with the change
+
+	return updated.clone(), nil
 }
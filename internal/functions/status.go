@@ -0,0 +1,98 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cares/internal/events"
+)
+
+// Function status values
+const (
+	StatusActive   = "active"
+	StatusInactive = "inactive"
+)
+
+// StatusWindow overrides a function's status for a period of time, e.g. to
+// disable it during a maintenance window or enable it only for a batch slot.
+type StatusWindow struct {
+	Status string    `json:"status"` // "active" or "inactive"
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// Contains reports whether t falls inside the window.
+func (w StatusWindow) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// EffectiveStatus returns the status in force at the given time. The first
+// scheduled window containing now wins; otherwise the manual Status applies.
+func (f *Function) EffectiveStatus(now time.Time) string {
+	for _, window := range f.StatusWindows {
+		if window.Contains(now) {
+			return window.Status
+		}
+	}
+	return f.Status
+}
+
+// IsInvocable reports whether the function may be invoked at the given time.
+func (f *Function) IsInvocable(now time.Time) bool {
+	return f.EffectiveStatus(now) == StatusActive
+}
+
+// ActiveWindow returns the scheduled window in force at the given time, if any.
+func (f *Function) ActiveWindow(now time.Time) *StatusWindow {
+	for i := range f.StatusWindows {
+		if f.StatusWindows[i].Contains(now) {
+			return &f.StatusWindows[i]
+		}
+	}
+	return nil
+}
+
+// validStatus reports whether status is one of the known function statuses.
+func validStatus(status string) bool {
+	return status == StatusActive || status == StatusInactive
+}
+
+// SetStatusWindows replaces the scheduled status windows of a function and
+// returns a copy of the updated function. Windows that have already ended are
+// dropped.
+func (r *Registry) SetStatusWindows(ctx context.Context, id string, windows []StatusWindow) (*Function, error) {
+	now := time.Now()
+	var fields []FieldError
+	var pending []StatusWindow
+	for i, window := range windows {
+		if !validStatus(window.Status) {
+			fields = append(fields, FieldError{
+				Field:   fmt.Sprintf("windows[%d].status", i),
+				Message: fmt.Sprintf("must be '%s' or '%s'", StatusActive, StatusInactive),
+			})
+		}
+		if !window.End.After(window.Start) {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("windows[%d].end", i), Message: "must be after start"})
+		}
+		if window.End.After(now) {
+			pending = append(pending, window)
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	function, err := r.mutate(id, func(fn *Function) error {
+		fn.StatusWindows = pending
+		fn.Revision++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publish(ctx, events.FunctionScheduleChanged, function, map[string]string{"windows": strconv.Itoa(len(pending))})
+	return function, nil
+}
@@ -0,0 +1,196 @@
+package functions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"cares/internal/fsutil"
+)
+
+// Store persists function definitions on behalf of the Registry.
+//
+// Implementations must be safe for concurrent use. Put and Delete return only
+// once the change is durable, so the registry can surface persistence errors
+// to its callers instead of silently losing writes.
+type Store interface {
+	// Load returns every persisted function.
+	Load() ([]*Function, error)
+	// Put creates or replaces a function.
+	Put(fn *Function) error
+	// Delete removes a function. Deleting an unknown ID is not an error.
+	Delete(id string) error
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// Storage backends selectable through CARES_FUNCTION_STORE
+const (
+	StoreFile = "file"
+	StoreBolt = "bolt"
+)
+
+// Default storage locations for each backend
+const (
+	DefaultStoragePath = "data/functions.json"
+	DefaultBoltPath    = "data/functions.db"
+)
+
+// OpenStore opens the storage backend of the given kind at path.
+func OpenStore(kind, path string) (Store, error) {
+	switch kind {
+	case "", StoreFile:
+		return NewFileStore(path), nil
+	case StoreBolt:
+		return OpenBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown function store '%s' (expected '%s' or '%s')", kind, StoreFile, StoreBolt)
+	}
+}
+
+// OpenDefaultStore opens the backend named by the CARES_FUNCTION_STORE
+// environment variable at its default location, falling back to the JSON file store.
+func OpenDefaultStore() (Store, error) {
+	kind := os.Getenv("CARES_FUNCTION_STORE")
+	path := DefaultStoragePath
+	if kind == StoreBolt {
+		path = DefaultBoltPath
+	}
+	return OpenStore(kind, path)
+}
+
+// FileStore keeps all functions in a single JSON file.
+//
+// Every change rewrites the whole file crash-safely: the snapshot is written to
+// a temporary file in the same directory, fsynced, and atomically renamed over
+// the previous version. Writers are serialized, so concurrent updates can never
+// interleave or overwrite each other with stale data.
+type FileStore struct {
+	mu        sync.Mutex
+	path      string
+	functions map[string]*Function // Last persisted snapshot
+	loaded    bool
+}
+
+// NewFileStore creates a file store backed by the JSON file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path:      path,
+		functions: make(map[string]*Function),
+	}
+}
+
+// Load reads all functions from the JSON file. A missing file is an empty store.
+func (s *FileStore) Load() ([]*Function, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+
+	functions := make([]*Function, 0, len(s.functions))
+	for _, fn := range s.functions {
+		functions = append(functions, fn.clone())
+	}
+	return functions, nil
+}
+
+// Put stores fn and rewrites the file.
+func (s *FileStore) Put(fn *Function) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+
+	previous, existed := s.functions[fn.ID]
+	s.functions[fn.ID] = fn.clone()
+	if err := s.writeLocked(); err != nil {
+		// Keep the in-memory snapshot in sync with what is on disk
+		if existed {
+			s.functions[fn.ID] = previous
+		} else {
+			delete(s.functions, fn.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the function with the given ID and rewrites the file.
+func (s *FileStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+
+	previous, existed := s.functions[id]
+	if !existed {
+		return nil
+	}
+	delete(s.functions, id)
+	if err := s.writeLocked(); err != nil {
+		s.functions[id] = previous
+		return err
+	}
+	return nil
+}
+
+// Close implements Store. The file store holds no open handles.
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// loadLocked reads the file into the snapshot once. The caller must hold s.mu.
+func (s *FileStore) loadLocked() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		// File doesn't exist yet, but that's not an error
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read registry file: %v", err)
+	}
+
+	var functions []*Function
+	if err := json.Unmarshal(data, &functions); err != nil {
+		return fmt.Errorf("failed to unmarshal registry: %v", err)
+	}
+	for _, fn := range functions {
+		s.functions[fn.ID] = fn
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// writeLocked atomically replaces the file with the current snapshot.
+// The caller must hold s.mu.
+func (s *FileStore) writeLocked() error {
+	functions := make([]*Function, 0, len(s.functions))
+	for _, fn := range s.functions {
+		functions = append(functions, fn)
+	}
+	// Keep the file in creation order so diffs of the data file stay readable
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].CreatedAt.Before(functions[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(functions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry: %v", err)
+	}
+
+	return fsutil.WriteFileAtomic(s.path, data, 0644)
+}
@@ -0,0 +1,144 @@
+package functions
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testFunction returns a function as the registry would store it.
+func testFunction(id, name string) *Function {
+	return &Function{
+		ID:        id,
+		Name:      name,
+		Image:     "ghcr.io/acme/" + name + ":1",
+		Status:    StatusActive,
+		Kind:      KindContainer,
+		Revision:  1,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// loadNames returns the names of the functions in store, sorted.
+func loadNames(t *testing.T, store Store) []string {
+	t.Helper()
+
+	functions, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	names := make([]string, 0, len(functions))
+	for _, fn := range functions {
+		names = append(names, fn.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	for _, kind := range []string{StoreFile, StoreBolt} {
+		t.Run(kind, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data", "functions")
+			store, err := OpenStore(kind, path)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+
+			for _, fn := range []*Function{testFunction("1", "app"), testFunction("2", "api"), testFunction("3", "cron")} {
+				if err := store.Put(fn); err != nil {
+					t.Fatalf("put %s: %v", fn.Name, err)
+				}
+			}
+			updated := testFunction("2", "api")
+			updated.Description = "edited"
+			updated.Revision = 2
+			if err := store.Put(updated); err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if err := store.Delete("3"); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if err := store.Delete("missing"); err != nil {
+				t.Errorf("deleting an unknown function: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			reopened, err := OpenStore(kind, path)
+			if err != nil {
+				t.Fatalf("reopen: %v", err)
+			}
+			defer reopened.Close()
+			functions, err := reopened.Load()
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if len(functions) != 2 {
+				t.Fatalf("loaded %d functions, want 2", len(functions))
+			}
+			for _, fn := range functions {
+				if fn.ID == "2" && (fn.Description != "edited" || fn.Revision != 2) {
+					t.Errorf("function 2 = %+v, want the update", fn)
+				}
+				if fn.ID == "1" && !fn.CreatedAt.Equal(testFunction("1", "app").CreatedAt) {
+					t.Errorf("function 1 created at %v, want the stored time", fn.CreatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreFailedPutKeepsPreviousFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "functions.json")
+	store := NewFileStore(path)
+	if err := store.Put(testFunction("1", "app")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	before, _ := os.ReadFile(path)
+
+	// Writes fail while the file's directory is replaced by a regular file
+	blocker := filepath.Join(dir, "blocker")
+	os.WriteFile(blocker, nil, 0644)
+	store.path = filepath.Join(blocker, "functions.json")
+	if err := store.Put(testFunction("2", "api")); err == nil {
+		t.Fatal("put succeeded without a writable directory")
+	}
+	store.path = path
+
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("file changed by a failed put:\n%s", after)
+	}
+	if names := loadNames(t, store); len(names) != 1 || names[0] != "app" {
+		t.Errorf("store holds %v after a failed put, want [app]", names)
+	}
+
+	// The failed function must not come back with the next successful write
+	if err := store.Put(testFunction("3", "cron")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if names := loadNames(t, NewFileStore(path)); len(names) != 2 || names[0] != "app" || names[1] != "cron" {
+		t.Errorf("file holds %v, want [app cron]", names)
+	}
+}
+
+func TestFileStoreIgnoresTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "functions.json")
+	if err := NewFileStore(path).Put(testFunction("1", "app")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	// A crash mid-write leaves a partial temporary file, never a partial store
+	os.WriteFile(path+".tmp-123", []byte(`[{"id":"2","name":"ap`), 0644)
+
+	if names := loadNames(t, NewFileStore(path)); len(names) != 1 || names[0] != "app" {
+		t.Errorf("loaded %v, want [app]", names)
+	}
+	if leftovers, _ := filepath.Glob(path + ".tmp-*"); len(leftovers) != 1 {
+		t.Errorf("temporary files = %v, want only the torn one", leftovers)
+	}
+}
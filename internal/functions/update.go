@@ -0,0 +1,178 @@
+package functions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cares/internal/events"
+)
+
+var (
+	// ErrFunctionNotFound is returned when an operation targets an unknown function ID.
+	ErrFunctionNotFound = errors.New("function not found")
+	// ErrRevisionConflict is returned when an update was made against a stale revision.
+	ErrRevisionConflict = errors.New("function was modified by another request")
+	// ErrDuplicateName is returned when registering a name that is already taken.
+	ErrDuplicateName = errors.New("function name already exists")
+	// ErrNoVersions is returned when a function has no version to invoke.
+	ErrNoVersions = errors.New("function has no published versions")
+)
+
+// Limits applied when validating editable function fields.
+const (
+	MaxDescriptionLength = 200
+	MaxTimeoutSeconds    = 3600
+	MinMemoryMB          = 6 // Docker refuses memory limits below 6MB
+	MaxMemoryMB          = 65536
+)
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// FunctionUpdate describes a partial update of a function. Nil fields are left
+// unchanged. Changing the image publishes a new version rather than rewriting
+// an existing one.
+type FunctionUpdate struct {
+	Image          *string
+	Description    *string
+	TimeoutSeconds *int
+	MemoryMB       *int
+	Env            map[string]string // Replaces the whole environment when non-nil
+	Args           []string          // Replaces the process arguments when non-nil
+	PullPolicy     *string
+	// RegistryCredential names a stored registry credential; "" removes it.
+	// Callers check that the credential exists.
+	RegistryCredential *string
+	VerifySignature    *bool
+}
+
+// FieldError describes why a single field of an update was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field-level problem found in an update.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return "invalid function update: " + strings.Join(messages, "; ")
+}
+
+// Validate checks every set field of the update and returns a *ValidationError
+// describing all problems, or nil if the update is acceptable.
+func (u FunctionUpdate) Validate() error {
+	var fields []FieldError
+	add := func(field, format string, args ...interface{}) {
+		fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if u.Image != nil {
+		image := *u.Image
+		if image == "" {
+			add("image", "must not be empty")
+		} else if strings.ContainsAny(image, " \t\n") {
+			add("image", "must not contain whitespace")
+		}
+	}
+	if u.Description != nil && len(*u.Description) > MaxDescriptionLength {
+		add("description", "must be at most %d characters", MaxDescriptionLength)
+	}
+	if u.TimeoutSeconds != nil && (*u.TimeoutSeconds < 0 || *u.TimeoutSeconds > MaxTimeoutSeconds) {
+		add("settings.timeout_seconds", "must be between 0 and %d", MaxTimeoutSeconds)
+	}
+	if u.MemoryMB != nil && *u.MemoryMB != 0 && (*u.MemoryMB < MinMemoryMB || *u.MemoryMB > MaxMemoryMB) {
+		add("settings.memory_mb", "must be 0 or between %d and %d", MinMemoryMB, MaxMemoryMB)
+	}
+	if u.PullPolicy != nil {
+		switch *u.PullPolicy {
+		case "", PullAlways, PullIfNotPresent, PullNever:
+		default:
+			add("settings.pull_policy", "must be '%s', '%s' or '%s'", PullAlways, PullIfNotPresent, PullNever)
+		}
+	}
+	for key := range u.Env {
+		if !envKeyPattern.MatchString(key) {
+			add("settings.env", "invalid variable name '%s'", key)
+		}
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// UpdateFunction applies a partial update to a function.
+//
+// If expectedRevision is non-zero the update is only applied when it matches the
+// function's current revision, otherwise ErrRevisionConflict is returned. On
+// success the revision is incremented and a copy of the updated function returned.
+func (r *Registry) UpdateFunction(ctx context.Context, id string, expectedRevision int64, update FunctionUpdate) (*Function, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	var published *FunctionVersion
+	function, err := r.mutate(id, func(fn *Function) error {
+		if expectedRevision != 0 && expectedRevision != fn.Revision {
+			return ErrRevisionConflict
+		}
+
+		if update.Image != nil && *update.Image != fn.Image {
+			if err := validateTarget(fn.Kind, *update.Image); err != nil {
+				return err
+			}
+			version := publishVersion(fn, *update.Image)
+			published = &version
+		}
+		if update.Description != nil {
+			fn.Description = *update.Description
+		}
+		if update.TimeoutSeconds != nil {
+			fn.Settings.TimeoutSeconds = *update.TimeoutSeconds
+		}
+		if update.MemoryMB != nil {
+			fn.Settings.MemoryMB = *update.MemoryMB
+		}
+		if update.Env != nil {
+			fn.Settings.Env = make(map[string]string, len(update.Env))
+			for key, value := range update.Env {
+				fn.Settings.Env[key] = value
+			}
+		}
+		if update.Args != nil {
+			fn.Settings.Args = append([]string{}, update.Args...)
+		}
+		if update.PullPolicy != nil {
+			fn.Settings.PullPolicy = *update.PullPolicy
+		}
+		if update.RegistryCredential != nil {
+			fn.Settings.RegistryCredential = *update.RegistryCredential
+		}
+		if update.VerifySignature != nil {
+			fn.Settings.VerifySignature = *update.VerifySignature
+		}
+		fn.Revision++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publish(ctx, events.FunctionUpdated, function, map[string]string{"revision": strconv.FormatInt(function.Revision, 10)})
+	if published != nil {
+		publish(ctx, events.FunctionVersionPublished, function, versionData(*published))
+	}
+	return function, nil
+}
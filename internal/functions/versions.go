@@ -0,0 +1,350 @@
+package functions
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cares/internal/events"
+	"cares/internal/imageref"
+)
+
+// FunctionVersion is an immutable, numbered image of a function. Versions are
+// published in order and never change their image once created, which makes
+// them safe targets for weighted traffic splitting during canary releases.
+//
+// Container versions are pinned to the manifest digest their tag resolved to
+// when they were published, so a tag pushed later never changes what runs.
+type FunctionVersion struct {
+	Version   int          `json:"version"`
+	Image     string       `json:"image"`
+	Digest    string       `json:"digest,omitempty"` // Pinned manifest digest, empty if unpinned
+	CreatedAt time.Time    `json:"created_at"`
+	Stats     VersionStats `json:"stats"`
+}
+
+// VersionStats aggregates invocation outcomes for a single function version
+// so operators can compare a canary against the stable version before promoting.
+type VersionStats struct {
+	Invocations    int64     `json:"invocations"`
+	Successes      int64     `json:"successes"`
+	Failures       int64     `json:"failures"`
+	TotalLatencyMs int64     `json:"total_latency_ms"`
+	MaxLatencyMs   int64     `json:"max_latency_ms"`
+	LastInvokedAt  time.Time `json:"last_invoked_at,omitempty"`
+}
+
+// SuccessRate returns the percentage (0.0 - 100.0) of successful invocations.
+func (s VersionStats) SuccessRate() float64 {
+	if s.Invocations == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Invocations) * 100
+}
+
+// AvgLatencyMs returns the mean invocation latency in milliseconds.
+func (s VersionStats) AvgLatencyMs() float64 {
+	if s.Invocations == 0 {
+		return 0
+	}
+	return float64(s.TotalLatencyMs) / float64(s.Invocations)
+}
+
+// PinnedImage returns the reference that pulls exactly the version's pinned
+// digest, "repository@digest", or its image as published if it is unpinned.
+func (v FunctionVersion) PinnedImage() string {
+	if v.Digest == "" {
+		return v.Image
+	}
+	ref, err := imageref.Parse(v.Image)
+	if err != nil {
+		return v.Image
+	}
+	ref.Tag, ref.Digest = "", v.Digest
+	return ref.String()
+}
+
+// LatestVersion returns the most recently published version of the function.
+func (f *Function) LatestVersion() *FunctionVersion {
+	if len(f.Versions) == 0 {
+		return nil
+	}
+	return &f.Versions[len(f.Versions)-1]
+}
+
+// FindVersion returns the version with the given number, or nil if it does not exist.
+func (f *Function) FindVersion(version int) *FunctionVersion {
+	for i := range f.Versions {
+		if f.Versions[i].Version == version {
+			return &f.Versions[i]
+		}
+	}
+	return nil
+}
+
+// TrafficWeight returns the percentage of invocations currently routed to the
+// given version, taking the implicit "latest gets everything" default into account.
+func (f *Function) TrafficWeight(version int) int {
+	if len(f.TrafficSplit) == 0 {
+		if latest := f.LatestVersion(); latest != nil && latest.Version == version {
+			return 100
+		}
+		return 0
+	}
+	return f.TrafficSplit[version]
+}
+
+// PublishVersion adds a new version of the function running the given image.
+// The new version becomes the function's current image; an explicit traffic
+// split is left untouched so a canary only receives traffic once it is routed.
+func (r *Registry) PublishVersion(ctx context.Context, id, image string) (*FunctionVersion, error) {
+	var version FunctionVersion
+	function, err := r.mutate(id, func(fn *Function) error {
+		if err := validateTarget(fn.Kind, image); err != nil {
+			return err
+		}
+		version = publishVersion(fn, image)
+		fn.Revision++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publish(ctx, events.FunctionVersionPublished, function, versionData(version))
+	return &version, nil
+}
+
+// versionData returns the event data describing a published version.
+func versionData(version FunctionVersion) map[string]string {
+	return map[string]string{"version": strconv.Itoa(version.Version), "image": version.Image}
+}
+
+// PinVersion pins a version to the manifest digest its image resolved to.
+// Pinning is permanent: a pinned version can't be re-pinned to another digest.
+func (r *Registry) PinVersion(id string, version int, digest string) (*Function, error) {
+	if !imageref.ValidDigest(digest) {
+		return nil, fmt.Errorf("invalid digest '%s'", digest)
+	}
+
+	return r.mutate(id, func(fn *Function) error {
+		v := fn.FindVersion(version)
+		if v == nil {
+			return fmt.Errorf("version %d does not exist for function '%s'", version, fn.Name)
+		}
+		if v.Digest != "" && v.Digest != digest {
+			return fmt.Errorf("version %d is already pinned to %s", version, v.Digest)
+		}
+		v.Digest = digest
+		fn.Revision++
+		return nil
+	})
+}
+
+// publishVersion appends the next version to fn and returns it.
+func publishVersion(fn *Function, image string) FunctionVersion {
+	next := 1
+	if latest := fn.LatestVersion(); latest != nil {
+		next = latest.Version + 1
+	}
+
+	fn.Versions = append(fn.Versions, FunctionVersion{
+		Version:   next,
+		Image:     image,
+		CreatedAt: time.Now(),
+	})
+	fn.Image = image
+
+	return fn.Versions[len(fn.Versions)-1]
+}
+
+// SetTrafficSplit replaces the weighted routing table of a function.
+//
+// Weights are percentages keyed by version number and must add up to 100.
+// Passing an empty map clears the split so all traffic goes to the latest version.
+// A copy of the updated function is returned.
+func (r *Registry) SetTrafficSplit(ctx context.Context, id string, weights map[int]int) (*Function, error) {
+	function, err := r.mutate(id, func(fn *Function) error {
+		if len(weights) == 0 {
+			fn.TrafficSplit = nil
+			fn.Revision++
+			return nil
+		}
+
+		total := 0
+		split := make(map[int]int, len(weights))
+		for version, weight := range weights {
+			if fn.FindVersion(version) == nil {
+				return invalidWeights("version %d does not exist for function '%s'", version, fn.Name)
+			}
+			if weight < 0 || weight > 100 {
+				return invalidWeights("weight for version %d must be between 0 and 100", version)
+			}
+			total += weight
+			if weight > 0 {
+				split[version] = weight
+			}
+		}
+		if total != 100 {
+			return invalidWeights("must add up to 100, got %d", total)
+		}
+
+		fn.TrafficSplit = split
+		fn.Revision++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publish(ctx, events.FunctionTrafficChanged, function, map[string]string{"weights": formatWeights(function.TrafficSplit)})
+	return function, nil
+}
+
+// invalidWeights reports a traffic split that can't be applied.
+func invalidWeights(format string, args ...interface{}) error {
+	return &ValidationError{Fields: []FieldError{{Field: "weights", Message: fmt.Sprintf(format, args...)}}}
+}
+
+// formatWeights formats a traffic split for events as "1=90,2=10", ordered by
+// version. An empty split is "" (all traffic goes to the latest version).
+func formatWeights(split map[int]int) string {
+	versions := make([]int, 0, len(split))
+	for version := range split {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	parts := make([]string, len(versions))
+	for i, version := range versions {
+		parts[i] = fmt.Sprintf("%d=%d", version, split[version])
+	}
+	return strings.Join(parts, ",")
+}
+
+// ResolveVersion looks up a function by name and picks the version that
+// should serve the next invocation according to its traffic split.
+func (r *Registry) ResolveVersion(name string) (*Function, *FunctionVersion, error) {
+	fn, exists := r.GetFunctionByName(name)
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: '%s'", ErrFunctionNotFound, name)
+	}
+
+	version := pickVersion(fn)
+	if version == nil {
+		return nil, nil, fmt.Errorf("%w: '%s'", ErrNoVersions, name)
+	}
+
+	return fn, version, nil
+}
+
+// RecordInvocation updates the per-version statistics after an invocation completes.
+// Statistics do not change the function's revision, and are persisted later
+// rather than written through (see Registry).
+func (r *Registry) RecordInvocation(id string, version int, success bool, latency time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn, exists := r.functions[id]
+	if !exists {
+		return ErrFunctionNotFound
+	}
+	v := fn.FindVersion(version)
+	if v == nil {
+		return fmt.Errorf("version %d does not exist for function '%s'", version, fn.Name)
+	}
+
+	ms := latency.Milliseconds()
+	v.Stats.Invocations++
+	if success {
+		v.Stats.Successes++
+	} else {
+		v.Stats.Failures++
+	}
+	v.Stats.TotalLatencyMs += ms
+	if ms > v.Stats.MaxLatencyMs {
+		v.Stats.MaxLatencyMs = ms
+	}
+	v.Stats.LastInvokedAt = time.Now()
+
+	r.statsDirty[id] = struct{}{}
+	return nil
+}
+
+// pickVersion chooses a version using the function's weighted traffic split.
+func pickVersion(fn *Function) *FunctionVersion {
+	if len(fn.TrafficSplit) == 0 {
+		return fn.LatestVersion()
+	}
+
+	// Iterate in a stable order so the same roll always maps to the same version
+	versions := make([]int, 0, len(fn.TrafficSplit))
+	for version := range fn.TrafficSplit {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	roll := rand.Intn(100)
+	for _, version := range versions {
+		roll -= fn.TrafficSplit[version]
+		if roll < 0 {
+			return fn.FindVersion(version)
+		}
+	}
+
+	return fn.LatestVersion()
+}
+
+// backfill upgrades functions persisted by older releases, adding version 1,
+// a starting revision and the container kind where they are missing.
+func backfill(fn *Function) {
+	if fn.Kind == "" {
+		fn.Kind = KindContainer
+	}
+	if fn.Revision == 0 {
+		fn.Revision = 1
+	}
+	if len(fn.Versions) > 0 {
+		return
+	}
+	fn.Versions = []FunctionVersion{{
+		Version:   1,
+		Image:     fn.Image,
+		CreatedAt: fn.CreatedAt,
+	}}
+}
+
+// clone returns a deep copy of the function so callers never share the
+// registry's slices or maps.
+func (f *Function) clone() *Function {
+	fnCopy := *f
+	if f.Settings.Env != nil {
+		fnCopy.Settings.Env = make(map[string]string, len(f.Settings.Env))
+		for key, value := range f.Settings.Env {
+			fnCopy.Settings.Env[key] = value
+		}
+	}
+	if f.Settings.Args != nil {
+		fnCopy.Settings.Args = make([]string, len(f.Settings.Args))
+		copy(fnCopy.Settings.Args, f.Settings.Args)
+	}
+	if f.StatusWindows != nil {
+		fnCopy.StatusWindows = make([]StatusWindow, len(f.StatusWindows))
+		copy(fnCopy.StatusWindows, f.StatusWindows)
+	}
+	if f.Versions != nil {
+		fnCopy.Versions = make([]FunctionVersion, len(f.Versions))
+		copy(fnCopy.Versions, f.Versions)
+	}
+	if f.TrafficSplit != nil {
+		fnCopy.TrafficSplit = make(map[int]int, len(f.TrafficSplit))
+		for version, weight := range f.TrafficSplit {
+			fnCopy.TrafficSplit[version] = weight
+		}
+	}
+	return &fnCopy
+}
@@ -0,0 +1,169 @@
+// Package imageref parses container image references such as "alpine",
+// "ghcr.io/acme/app:1.2" or "localhost:5000/app@sha256:...", following the
+// reference grammar of Docker and OCI registries. URL-style input like
+// "https://ghcr.io/acme/app" is accepted as well.
+package imageref
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Defaults applied to references that leave parts out
+const (
+	DefaultRegistry = "docker.io" // Docker Hub
+	DefaultTag      = "latest"
+
+	officialPrefix = "library/" // Namespace of Docker Hub's official images
+	maxNameLength  = 255        // Longest repository name registries accept
+)
+
+var (
+	hostPattern      = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*|\[[0-9a-fA-F:]+\])(?::[0-9]+)?$`)
+	componentPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagPattern       = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestPattern    = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]{32,}$`)
+	sha256Pattern    = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Reference is a parsed image reference.
+type Reference struct {
+	Registry   string // Registry host, DefaultRegistry for Docker Hub
+	Repository string // Path within the registry, e.g. "library/alpine"
+	Tag        string // Empty only if the reference is pinned by digest alone
+	Digest     string // Content digest as "algorithm:hex", empty if not pinned
+}
+
+// Parse parses an image reference. A reference with neither tag nor digest
+// gets DefaultTag, and one without a registry host refers to Docker Hub. The
+// first path component is taken as the registry host if it contains '.' or
+// ':', is "localhost", or follows an http(s):// scheme.
+func Parse(image string) (Reference, error) {
+	var ref Reference
+
+	name := strings.TrimSpace(image)
+	if name == "" {
+		return Reference{}, fmt.Errorf("image reference is empty")
+	}
+
+	// URLs name the registry explicitly, whatever the host looks like
+	explicitHost := false
+	for _, scheme := range []string{"https://", "http://", "docker://"} {
+		if len(name) > len(scheme) && strings.EqualFold(name[:len(scheme)], scheme) {
+			name = strings.TrimSuffix(name[len(scheme):], "/")
+			explicitHost = scheme != "docker://"
+			break
+		}
+	}
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !ValidDigest(ref.Digest) {
+			return Reference{}, fmt.Errorf("invalid digest '%s'", ref.Digest)
+		}
+	}
+
+	// A colon after the last slash starts the tag; earlier ones are ports
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagPattern.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid tag '%s'", ref.Tag)
+		}
+	}
+
+	host, path, hasPath := strings.Cut(name, "/")
+	switch {
+	case hasPath && (explicitHost || strings.ContainsAny(host, ".:") || host == "localhost"):
+		if !hostPattern.MatchString(host) {
+			return Reference{}, fmt.Errorf("invalid registry host '%s'", host)
+		}
+		ref.Registry = NormalizeRegistry(host)
+		name = path
+	case explicitHost:
+		return Reference{}, fmt.Errorf("image URL '%s' has no repository path", image)
+	default:
+		ref.Registry = DefaultRegistry
+	}
+
+	if name == "" {
+		return Reference{}, fmt.Errorf("image reference '%s' has no repository", image)
+	}
+	for _, component := range strings.Split(name, "/") {
+		if !componentPattern.MatchString(component) {
+			return Reference{}, fmt.Errorf("invalid repository '%s': path components must be lowercase letters and digits, separated by '.', '_' or '-'", name)
+		}
+	}
+	if ref.Registry == DefaultRegistry && !strings.Contains(name, "/") {
+		name = officialPrefix + name
+	}
+	ref.Repository = name
+
+	if len(ref.Name()) > maxNameLength {
+		return Reference{}, fmt.Errorf("image name exceeds %d characters", maxNameLength)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = DefaultTag
+	}
+	return ref, nil
+}
+
+// Normalize parses an image reference and returns it in the form container
+// runtimes display, e.g. "nginx" becomes "nginx:latest" and
+// "https://ghcr.io/acme/app" becomes "ghcr.io/acme/app:latest".
+func Normalize(image string) (string, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return "", err
+	}
+	return ref.String(), nil
+}
+
+// NormalizeRegistry returns the canonical name of a registry host, mapping
+// the aliases of Docker Hub to DefaultRegistry. It also accepts the URL forms
+// found in docker credential configurations, like "https://index.docker.io/v1/".
+func NormalizeRegistry(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host = strings.TrimSuffix(strings.TrimSuffix(host, "/"), "/v1")
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DefaultRegistry
+	}
+	return host
+}
+
+// ValidDigest reports whether digest is a well-formed "algorithm:hex" content
+// digest. SHA-256 digests must be exactly 64 lowercase hex characters.
+func ValidDigest(digest string) bool {
+	if strings.HasPrefix(digest, "sha256:") {
+		return sha256Pattern.MatchString(digest)
+	}
+	return digestPattern.MatchString(digest)
+}
+
+// Name returns the repository name as runtimes display it: Docker Hub's host
+// and the "library/" namespace of its official images are left out.
+func (r Reference) Name() string {
+	if r.Registry != DefaultRegistry {
+		return r.Registry + "/" + r.Repository
+	}
+	if official := strings.TrimPrefix(r.Repository, officialPrefix); !strings.Contains(official, "/") {
+		return official
+	}
+	return r.Repository
+}
+
+// String returns the normalized reference, "name[:tag][@digest]".
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
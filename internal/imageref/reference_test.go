@@ -0,0 +1,96 @@
+package imageref
+
+import (
+	"strings"
+	"testing"
+)
+
+const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+		str   string
+	}{
+		{"alpine", Reference{DefaultRegistry, "library/alpine", "latest", ""}, "alpine:latest"},
+		{"acme/app:1.2", Reference{DefaultRegistry, "acme/app", "1.2", ""}, "acme/app:1.2"},
+		{"docker.io/library/nginx", Reference{DefaultRegistry, "library/nginx", "latest", ""}, "nginx:latest"},
+		{"index.docker.io/acme/app", Reference{DefaultRegistry, "acme/app", "latest", ""}, "acme/app:latest"},
+		{"localhost/app", Reference{"localhost", "app", "latest", ""}, "localhost/app:latest"},
+		{"localhost:5000/app", Reference{"localhost:5000", "app", "latest", ""}, "localhost:5000/app:latest"},
+		{"app@" + digest, Reference{DefaultRegistry, "library/app", "", digest}, "app@" + digest},
+		{"ghcr.io/x/y:v1@" + digest, Reference{"ghcr.io", "x/y", "v1", digest}, "ghcr.io/x/y:v1@" + digest},
+		{"registry.local:5000/team/app:2.0", Reference{"registry.local:5000", "team/app", "2.0", ""}, "registry.local:5000/team/app:2.0"},
+		{"registry.local:5000/team/app:2.0@" + digest, Reference{"registry.local:5000", "team/app", "2.0", digest}, "registry.local:5000/team/app:2.0@" + digest},
+		{"registry.local:5000/team/app@" + digest, Reference{"registry.local:5000", "team/app", "", digest}, "registry.local:5000/team/app@" + digest},
+		{"[::1]:5000/app:1", Reference{"[::1]:5000", "app", "1", ""}, "[::1]:5000/app:1"},
+		{"https://ghcr.io/x/y", Reference{"ghcr.io", "x/y", "latest", ""}, "ghcr.io/x/y:latest"},
+		{"HTTPS://ghcr.io/x/y:1/", Reference{"ghcr.io", "x/y", "1", ""}, "ghcr.io/x/y:1"},
+		{"http://registry/app", Reference{"registry", "app", "latest", ""}, "registry/app:latest"},
+		{"docker://acme/app", Reference{DefaultRegistry, "acme/app", "latest", ""}, "acme/app:latest"},
+		{"  alpine:3.19  ", Reference{DefaultRegistry, "library/alpine", "3.19", ""}, "alpine:3.19"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := Parse(tt.image)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.image, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.image, got, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Errorf("String() = %q, want %q", got.String(), tt.str)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+	}{
+		{"empty", ""},
+		{"blank", "   "},
+		{"uppercase repository", "Acme/App"},
+		{"empty tag", "alpine:"},
+		{"invalid tag", "alpine:-1"},
+		{"short digest", "alpine@sha256:abc"},
+		{"uppercase sha256", "alpine@sha256:" + strings.ToUpper(digest[7:])},
+		{"digest without algorithm", "alpine@0123456789abcdef"},
+		{"invalid host", "-bad.io/app"},
+		{"url without path", "https://ghcr.io"},
+		{"empty component", "acme//app"},
+		{"trailing separator", "acme/app-"},
+		{"too long", "acme/" + strings.Repeat("a", maxNameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ref, err := Parse(tt.image); err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", tt.image, ref)
+			}
+		})
+	}
+}
+
+func TestNormalizeRegistry(t *testing.T) {
+	tests := map[string]string{
+		"docker.io":                   DefaultRegistry,
+		"https://index.docker.io/v1/": DefaultRegistry,
+		"registry-1.docker.io":        DefaultRegistry,
+		"GHCR.io":                     "ghcr.io",
+		"http://registry.local:5000/": "registry.local:5000",
+		" registry.local:5000 ":       "registry.local:5000",
+		"registry.hub.docker.com":     DefaultRegistry,
+		"https://quay.io":             "quay.io",
+	}
+	for host, want := range tests {
+		if got := NormalizeRegistry(host); got != want {
+			t.Errorf("NormalizeRegistry(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
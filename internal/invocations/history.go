@@ -0,0 +1,347 @@
+// Package invocations records every function invocation dispatched by the
+// orchestrator and keeps a bounded, queryable history of them.
+//
+// Records are appended to a JSON-lines file as they complete and kept in memory
+// for querying. Retention limits (maximum count and age) are enforced on every
+// append; the file is compacted once it holds noticeably more lines than the
+// retained records.
+package invocations
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"cares/internal/fsutil"
+	"cares/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+// Invocation outcomes
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// DefaultHistoryPath is the default location of the invocation history file.
+const DefaultHistoryPath = "data/invocations.jsonl"
+
+// MaxStoredOutput caps how much output is kept per record.
+const MaxStoredOutput = 4096
+
+// Record describes a single invocation of a function.
+type Record struct {
+	ID              string    `json:"id"`
+	FunctionID      string    `json:"function_id"`
+	FunctionName    string    `json:"function_name"`
+	Version         int       `json:"version"`
+	NodeID          string    `json:"node_id,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationMs      int64     `json:"duration_ms"`
+	Status          string    `json:"status"`              // "success" or "failed"
+	ExitCode        *int      `json:"exit_code,omitempty"` // Unset if the container never started
+	ImageDigest     string    `json:"image_digest,omitempty"`
+	Output          string    `json:"output,omitempty"`
+	OutputTruncated bool      `json:"output_truncated,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	FailureReason   string    `json:"failure_reason,omitempty"` // Set by the worker for failed runs
+	TraceID         string    `json:"trace_id,omitempty"`       // Trace of the invocation, if traced
+}
+
+// Retention bounds how much history is kept. Zero values disable a limit.
+type Retention struct {
+	MaxRecords int
+	MaxAge     time.Duration
+}
+
+// DefaultRetention keeps up to 10,000 invocations from the last 7 days.
+var DefaultRetention = Retention{
+	MaxRecords: 10000,
+	MaxAge:     7 * 24 * time.Hour,
+}
+
+// Filter selects records in Query. Empty fields match everything.
+type Filter struct {
+	Function string    // Function name or ID
+	Status   string    // "success" or "failed"
+	Since    time.Time // Only records started at or after this time
+	Limit    int       // Maximum number of records returned (0 = no limit)
+	Offset   int       // Number of matching records to skip
+}
+
+// History is a thread-safe, persistent log of invocations.
+type History struct {
+	mu        sync.RWMutex
+	path      string
+	retention Retention
+	records   []Record // Oldest first
+	file      *os.File
+	fileLines int  // Lines currently in the file, used to decide when to compact
+	closed    bool // Set by Close; until then a file lost to a failed reopen is retried
+}
+
+// OpenHistory loads the history stored at path (if any) and opens it for appending.
+func OpenHistory(path string, retention Retention) (*History, error) {
+	h := &History{
+		path:      path,
+		retention: retention,
+	}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+	h.prune(time.Now())
+
+	if err := h.openForAppend(); err != nil {
+		return nil, err
+	}
+	// Rewrite the file straight away if the loaded history exceeded the limits
+	if h.fileLines > len(h.records) {
+		if err := h.compact(); err != nil {
+			h.file.Close()
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// NewRecord creates a record for an invocation starting now.
+func NewRecord(functionID, functionName string, version int) Record {
+	return Record{
+		ID:           uuid.New().String(),
+		FunctionID:   functionID,
+		FunctionName: functionName,
+		Version:      version,
+		StartedAt:    time.Now(),
+	}
+}
+
+// Finish completes the record with its outcome, truncating the output to MaxStoredOutput.
+func (r *Record) Finish(success bool, output, errMsg string) {
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	r.Status = StatusFailed
+	if success {
+		r.Status = StatusSuccess
+	}
+	if len(output) > MaxStoredOutput {
+		// Cut at a rune boundary so the stored output stays valid UTF-8
+		end := MaxStoredOutput
+		for end > 0 && !utf8.RuneStart(output[end]) {
+			end--
+		}
+		output = output[:end]
+		r.OutputTruncated = true
+	}
+	r.Output = output
+	r.Error = errMsg
+}
+
+// Add appends a finished record to the history and persists it.
+func (h *History) Add(record Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invocation: %v", err)
+	}
+	if h.closed {
+		return fmt.Errorf("invocation history is closed")
+	}
+	if h.file == nil {
+		if err := h.openForAppend(); err != nil {
+			return err
+		}
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append invocation: %v", err)
+	}
+	h.fileLines++
+
+	h.records = append(h.records, record)
+	h.prune(time.Now())
+
+	// Compact once the file carries twice as many lines as we retain. The
+	// record is already saved, so a failure is only worth a warning: the next
+	// append tries again.
+	if h.fileLines > 2*len(h.records) && h.fileLines > 100 {
+		if err := h.compact(); err != nil {
+			logging.Warn("Invocation history keeps growing: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the record with the given ID.
+func (h *History) Get(id string) (*Record, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if h.records[i].ID == id {
+			record := h.records[i]
+			return &record, true
+		}
+	}
+	return nil, false
+}
+
+// Query returns matching records newest first, honouring the filter's
+// pagination, together with the total number of matches.
+func (h *History) Query(filter Filter) ([]Record, int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var matches []Record
+	for i := len(h.records) - 1; i >= 0; i-- {
+		record := h.records[i]
+		if filter.Function != "" && record.FunctionName != filter.Function && record.FunctionID != filter.Function {
+			continue
+		}
+		if filter.Status != "" && record.Status != filter.Status {
+			continue
+		}
+		if !filter.Since.IsZero() && record.StartedAt.Before(filter.Since) {
+			continue
+		}
+		matches = append(matches, record)
+	}
+
+	total := len(matches)
+	if filter.Offset >= total {
+		return []Record{}, total
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches, total
+}
+
+// Recent returns up to n of the newest records, newest first.
+func (h *History) Recent(n int) []Record {
+	records, _ := h.Query(Filter{Limit: n})
+	return records
+}
+
+// Count returns the number of retained records.
+func (h *History) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.records)
+}
+
+// Close closes the history file.
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// load reads every record from the history file. A missing file is empty history.
+func (h *History) load() error {
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read invocation history: %v", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		h.fileLines++
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A torn final line after a crash is expected; skip it
+			continue
+		}
+		h.records = append(h.records, record)
+	}
+	return scanner.Err()
+}
+
+// prune drops records outside the retention limits. The caller must hold h.mu
+// (or own h exclusively).
+func (h *History) prune(now time.Time) {
+	start := 0
+	if h.retention.MaxAge > 0 {
+		cutoff := now.Add(-h.retention.MaxAge)
+		for start < len(h.records) && h.records[start].StartedAt.Before(cutoff) {
+			start++
+		}
+	}
+	if h.retention.MaxRecords > 0 && len(h.records)-start > h.retention.MaxRecords {
+		start = len(h.records) - h.retention.MaxRecords
+	}
+	if start > 0 {
+		h.records = h.records[start:]
+		// Copy once the slice has twice the room it needs, so memory stays bounded
+		if cap(h.records) > 2*len(h.records) {
+			h.records = append([]Record(nil), h.records...)
+		}
+	}
+}
+
+// compact rewrites the history file with only the retained records and reopens
+// it for appending. If the rewrite fails, appends carry on to the old file.
+func (h *History) compact() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range h.records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal invocation: %v", err)
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(h.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to compact invocation history: %v", err)
+	}
+	h.fileLines = len(h.records)
+
+	// The file was replaced, so appends must go to the new one
+	if h.file != nil {
+		h.file.Close()
+		h.file = nil
+	}
+	return h.openForAppend()
+}
+
+// openForAppend opens the history file for appending new records.
+func (h *History) openForAppend() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open invocation history: %v", err)
+	}
+	h.file = file
+	return nil
+}
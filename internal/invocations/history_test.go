@@ -0,0 +1,63 @@
+package invocations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFinishTruncatesAtRuneBoundary(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantLen int
+	}{
+		{"ascii", strings.Repeat("a", MaxStoredOutput+10), MaxStoredOutput},
+		{"rune across the limit", strings.Repeat("a", MaxStoredOutput-1) + "é", MaxStoredOutput - 1},
+		{"four-byte rune across the limit", strings.Repeat("a", MaxStoredOutput-2) + "🙂", MaxStoredOutput - 2},
+		{"rune ending at the limit", strings.Repeat("a", MaxStoredOutput-2) + "éz", MaxStoredOutput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := NewRecord("fn-1", "app", 1)
+			record.Finish(true, tt.output, "")
+			if len(record.Output) != tt.wantLen || !record.OutputTruncated {
+				t.Errorf("stored %d bytes (truncated: %v), want %d truncated", len(record.Output), record.OutputTruncated, tt.wantLen)
+			}
+			if !utf8.ValidString(record.Output) {
+				t.Error("stored output is not valid UTF-8")
+			}
+		})
+	}
+}
+
+func TestAddSurvivesFailedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	h, err := OpenHistory(filepath.Join(dir, "history.jsonl"), Retention{MaxRecords: 10})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer h.Close()
+
+	// Compaction replaces the file through its directory, which is now a file
+	blocker := filepath.Join(dir, "blocker")
+	os.WriteFile(blocker, nil, 0644)
+	h.path = filepath.Join(blocker, "history.jsonl")
+
+	for i := 0; i < 300; i++ {
+		record := NewRecord("fn-1", "app", 1)
+		record.Finish(true, "ok", "")
+		if err := h.Add(record); err != nil {
+			t.Fatalf("add %d: %v", i, err)
+		}
+	}
+	if h.Count() != 10 {
+		t.Errorf("%d records retained, want 10", h.Count())
+	}
+	if cap(h.records) > 2*h.retention.MaxRecords+1 {
+		t.Errorf("records hold room for %d, want it bounded by the retention", cap(h.records))
+	}
+}
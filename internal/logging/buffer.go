@@ -0,0 +1,198 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize is the number of entries kept by the buffer Recent returns.
+const DefaultBufferSize = 2000
+
+// Entry is a logged record as handed to subscribers.
+type Entry struct {
+	Seq     uint64 // Position in the Buffer holding the entry, starting at 1
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Source  string  // file:line of the logging call
+	Fields  []Field // In logging order; group names prefix keys, e.g. "request.id"
+	NodeID  string  // Worker the entry was relayed from, "" for entries of this process
+}
+
+// Field is a key and value of an Entry.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Field returns the value of the field with the given key, "" if there is none.
+func (e Entry) Field(key string) string {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Subscriber receives every logged entry that passes the level. Publish is
+// called synchronously by the logging goroutine, so it must be quick and must
+// not log itself.
+type Subscriber interface {
+	Publish(entry Entry)
+}
+
+var (
+	subscribersMu sync.RWMutex
+	subscribers   []Subscriber
+
+	recent = NewBuffer(DefaultBufferSize)
+)
+
+func init() {
+	Subscribe(recent)
+}
+
+// Subscribe adds a subscriber for the entries logged from now on.
+func Subscribe(subscriber Subscriber) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, subscriber)
+}
+
+// Unsubscribe removes a subscriber added with Subscribe.
+func Unsubscribe(subscriber Subscriber) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	for i, s := range subscribers {
+		if s == subscriber {
+			subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Publish hands an entry to the subscribers without writing it to the log
+// output. It is used for entries relayed from other processes.
+func Publish(entry Entry) {
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+	for _, subscriber := range subscribers {
+		subscriber.Publish(entry)
+	}
+}
+
+// Recent returns the process-wide buffer of the latest DefaultBufferSize entries.
+func Recent() *Buffer {
+	return recent
+}
+
+// Buffer is a Subscriber keeping the most recent entries in a ring buffer.
+// It is safe for concurrent use.
+type Buffer struct {
+	mu      sync.RWMutex
+	entries []Entry // Ring buffer, next is the slot written next
+	next    int
+	count   int
+	seq     uint64 // Seq of the latest entry
+}
+
+// NewBuffer creates a buffer keeping the last size entries.
+func NewBuffer(size int) *Buffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Buffer{entries: make([]Entry, size)}
+}
+
+// Publish stores an entry, numbering it and evicting the oldest if full.
+func (b *Buffer) Publish(entry Entry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	entry.Seq = b.seq
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.count < len(b.entries) {
+		b.count++
+	}
+}
+
+// Since returns the entries after seq that are still kept, oldest first.
+// Since(0) returns every kept entry.
+func (b *Buffer) Since(seq uint64) []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if seq >= b.seq {
+		return nil
+	}
+	n := b.count
+	if pending := b.seq - seq; pending < uint64(n) {
+		n = int(pending)
+	}
+	entries := make([]Entry, n)
+	for i := range entries {
+		entries[i] = b.entries[(b.next-n+i+len(b.entries))%len(b.entries)]
+	}
+	return entries
+}
+
+// LastSeq returns the Seq of the latest entry, 0 if there is none.
+func (b *Buffer) LastSeq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// newEntry converts a record, with the fields and groups added through With
+// before it, into an Entry.
+func newEntry(record slog.Record, ops []handlerOp, source string) Entry {
+	entry := Entry{Time: record.Time, Level: record.Level, Message: record.Message, Source: source}
+	prefix := ""
+	for _, op := range ops {
+		if op.group != "" {
+			prefix += op.group + "."
+			continue
+		}
+		for _, attr := range op.attrs {
+			entry.Fields = appendFields(entry.Fields, prefix, attr)
+		}
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		entry.Fields = appendFields(entry.Fields, prefix, attr)
+		return true
+	})
+	return entry
+}
+
+// appendFields appends attr to fields, flattening groups into prefixed keys.
+func appendFields(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			fields = appendFields(fields, prefix, member)
+		}
+		return fields
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	return append(fields, Field{Key: prefix + attr.Key, Value: value.String()})
+}
+
+// String returns the message followed by the fields as key=value pairs.
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, field := range e.Fields {
+		b.WriteString(" " + field.Key + "=" + field.Value)
+	}
+	return b.String()
+}
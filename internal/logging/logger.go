@@ -1,82 +1,313 @@
-// Package logging provides a centralized logging system that prevents interference
-// with the TUI by directing logs to files instead of stdout/stderr when in TUI mode.
+// Package logging provides the structured, levelled logger of the process.
+// It is built on log/slog: records carry fields such as node_id, function and
+// invocation_id, are written as text or JSON, and go to a rotating log file in
+// TUI mode so they never interfere with the TUI. The level is set at startup
+// and can be changed at any time with SetLevel. Every record logged is also
+// published to subscribers, such as the in-memory buffer behind the TUI's
+// Logs view (see Recent).
+//
+// Code logs either printf-style with Info, Warn, Error and Debug, or with
+// fields through the *slog.Logger returned by With:
+//
+//	logging.With(logging.KeyNodeID, nodeID).Info("Node joined", "address", address)
+//
+// Records logged with a context (InfoContext and friends) also carry the
+// fields of SetContextFields, such as the trace_id of the current span.
 package logging
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
-var (
-	// Logger is the global logger instance
-	Logger *log.Logger
-	logFile *os.File
-	isTUIMode bool
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-// InitLogger initializes the logging system
-// If tui is true, logs go to a file to avoid interfering with the TUI
-// If tui is false, logs go to stderr for debugging
-func InitLogger(tui bool) error {
-	isTUIMode = tui
-	
+// Keys of the fields records are commonly tagged with
+const (
+	KeyNodeID       = "node_id"
+	KeyFunction     = "function"
+	KeyInvocationID = "invocation_id"
+	KeyTraceID      = "trace_id"
+	KeySpanID       = "span_id"
+)
+
+// Config configures the logger.
+type Config struct {
+	Level  slog.Level
+	Format string // FormatText (default) or FormatJSON
+	File   string // Log file, "" logs to stderr
+
+	// Rotation of File; zero values disable the respective limit
+	MaxSize    int64         // Bytes written to a file before it is rotated
+	MaxAge     time.Duration // Time a file is written to before it is rotated
+	MaxBackups int           // Rotated files kept, oldest are deleted first
+}
+
+// DefaultConfig returns the configuration of InitLogger. If tui is true, logs
+// go to logs/cares.log, rotated at 10 MB or daily with a week of backups;
+// otherwise they go to stderr.
+func DefaultConfig(tui bool) Config {
+	config := Config{Level: slog.LevelInfo, Format: FormatText}
 	if tui {
-		// Create logs directory if it doesn't exist
-		logDir := "logs"
-		if err := os.MkdirAll(logDir, 0755); err != nil {
+		config.File = filepath.Join("logs", "cares.log")
+		config.MaxSize = 10 << 20
+		config.MaxAge = 24 * time.Hour
+		config.MaxBackups = 7
+	}
+	return config
+}
+
+var (
+	level = new(slog.LevelVar) // Shared by every handler, so SetLevel applies at once
+
+	mu            sync.RWMutex
+	output        slog.Handler // Formats and writes records, nil before Init
+	logFile       io.Closer
+	contextFields func(ctx context.Context) []slog.Attr
+)
+
+// Init (re)configures the logger. Loggers obtained from With before the call
+// write to the new output afterwards.
+func Init(config Config) error {
+	var out io.Writer = os.Stderr
+	var closer io.Closer
+	if config.File != "" {
+		if err := os.MkdirAll(filepath.Dir(config.File), 0755); err != nil {
 			return fmt.Errorf("failed to create log directory: %w", err)
 		}
-		
-		// Open log file
-		var err error
-		logFile, err = os.OpenFile(filepath.Join(logDir, "cares.log"), 
-			os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		file, err := openRotatingFile(config.File, config.MaxSize, config.MaxAge, config.MaxBackups)
 		if err != nil {
 			return fmt.Errorf("failed to open log file: %w", err)
 		}
-		
-		Logger = log.New(logFile, "", log.LstdFlags|log.Lshortfile)
-	} else {
-		// Development mode - log to stderr
-		Logger = log.New(os.Stderr, "[CARES] ", log.LstdFlags|log.Lshortfile)
+		out, closer = file, file
+	}
+
+	options := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       level,
+		ReplaceAttr: shortSource,
+	}
+	var handler slog.Handler
+	switch config.Format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(out, options)
+	case FormatText, "":
+		handler = slog.NewTextHandler(out, options)
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return fmt.Errorf("unknown log format '%s', use '%s' or '%s'", config.Format, FormatText, FormatJSON)
+	}
+	level.Set(config.Level)
+
+	mu.Lock()
+	previous := logFile
+	output, logFile = handler, closer
+	mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
 	}
-	
 	return nil
 }
 
+// InitLogger initializes the logging system with DefaultConfig.
+// If tui is true, logs go to a file to avoid interfering with the TUI
+// If tui is false, logs go to stderr for debugging
+func InitLogger(tui bool) error {
+	return Init(DefaultConfig(tui))
+}
+
 // Close closes the log file if it was opened
 func Close() {
+	mu.Lock()
+	defer mu.Unlock()
 	if logFile != nil {
 		logFile.Close()
+		logFile = nil
 	}
+	output = nil
+}
+
+// SetLevel changes the minimum level of records that are logged.
+func SetLevel(l slog.Level) {
+	previous := level.Level()
+	level.Set(l)
+	if l != previous {
+		logf(slog.LevelInfo, "Log level changed from %s to %s", []interface{}{LevelName(previous), LevelName(l)})
+	}
+}
+
+// Level returns the minimum level of records that are logged.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// ParseLevel parses a level name: "debug", "info", "warn" or "error", in any case.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level '%s', use debug, info, warn or error", name)
+}
+
+// LevelName returns the name ParseLevel accepts for a level, e.g. "info".
+func LevelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
+
+// SetContextFields sets a function returning fields to add to records logged
+// with a context, e.g. through InfoContext. The tracing package uses it to tag
+// records with the current trace and span.
+func SetContextFields(fields func(ctx context.Context) []slog.Attr) {
+	mu.Lock()
+	defer mu.Unlock()
+	contextFields = fields
+}
+
+// With returns a logger adding the given fields, alternating keys and values
+// or slog.Attrs, to every record.
+func With(args ...any) *slog.Logger {
+	return slog.New(rootHandler{}).With(args...)
 }
 
 // Info logs an info message
 func Info(format string, args ...interface{}) {
-	if Logger != nil {
-		Logger.Printf("[INFO] "+format, args...)
-	}
+	logf(slog.LevelInfo, format, args)
 }
 
 // Error logs an error message
 func Error(format string, args ...interface{}) {
-	if Logger != nil {
-		Logger.Printf("[ERROR] "+format, args...)
-	}
+	logf(slog.LevelError, format, args)
 }
 
-// Debug logs a debug message (only in non-TUI mode)
+// Debug logs a debug message
 func Debug(format string, args ...interface{}) {
-	if Logger != nil && !isTUIMode {
-		Logger.Printf("[DEBUG] "+format, args...)
-	}
+	logf(slog.LevelDebug, format, args)
 }
 
 // Warn logs a warning message
 func Warn(format string, args ...interface{}) {
-	if Logger != nil {
-		Logger.Printf("[WARN] "+format, args...)
+	logf(slog.LevelWarn, format, args)
+}
+
+// logf formats and logs a message, attributing it to the caller of the
+// exported function calling logf.
+func logf(l slog.Level, format string, args []interface{}) {
+	handler := rootHandler{}
+	if !handler.Enabled(context.Background(), l) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // runtime.Callers, logf, Info/Warn/...
+	record := slog.NewRecord(time.Now(), l, fmt.Sprintf(format, args...), pcs[0])
+	handler.Handle(context.Background(), record)
+}
+
+// rootHandler passes records to the output configured by Init and to the
+// subscribers, applying the fields and groups added with WithAttrs and
+// WithGroup on the way.
+type rootHandler struct {
+	ops []handlerOp
+}
+
+// handlerOp is a WithAttrs or WithGroup call on a rootHandler.
+type handlerOp struct {
+	group string // Set for WithGroup
+	attrs []slog.Attr
+}
+
+// Enabled reports whether records of a level are logged.
+func (h rootHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= level.Level()
+}
+
+// Handle writes a record to the current output and publishes it.
+func (h rootHandler) Handle(ctx context.Context, record slog.Record) error {
+	mu.RLock()
+	handler, fields := output, contextFields
+	mu.RUnlock()
+
+	if fields != nil && ctx != nil {
+		if attrs := fields(ctx); len(attrs) > 0 {
+			record = record.Clone()
+			record.AddAttrs(attrs...)
+		}
+	}
+
+	var err error
+	if handler != nil {
+		for _, op := range h.ops {
+			if op.group != "" {
+				handler = handler.WithGroup(op.group)
+			} else {
+				handler = handler.WithAttrs(op.attrs)
+			}
+		}
+		err = handler.Handle(ctx, record)
+	}
+	Publish(newEntry(record, h.ops, recordSource(record)))
+	return err
+}
+
+// WithAttrs returns a handler adding attrs to every record.
+func (h rootHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(handlerOp{attrs: attrs})
+}
+
+// WithGroup returns a handler nesting later fields under name.
+func (h rootHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(handlerOp{group: name})
+}
+
+// with returns a copy of h with op appended.
+func (h rootHandler) with(op handlerOp) rootHandler {
+	ops := make([]handlerOp, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return rootHandler{ops: append(ops, op)}
+}
+
+// recordSource returns the file:line a record was logged at, "" if unknown.
+func recordSource(record slog.Record) string {
+	if record.PC == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+	if frame.File == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+}
+
+// shortSource reduces the source field to file:line, as log.Lshortfile did.
+func shortSource(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.SourceKey && len(groups) == 0 {
+		if source, ok := attr.Value.Any().(*slog.Source); ok {
+			return slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", filepath.Base(source.File), source.Line))
+		}
 	}
+	return attr
 }
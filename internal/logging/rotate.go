@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat stamps rotated files, e.g. cares-20240102-150405.000.log
+const rotatedTimeFormat = "20060102-150405.000"
+
+// rotatingFile is a log file that is renamed with a timestamp and replaced by
+// a fresh one once it grows past maxSize or was written to for maxAge. Only
+// the newest maxBackups rotated files are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File // Nil after Close, or while a failed reopen is retried
+	size   int64
+	opened time.Time // When the current file was started, for maxAge
+	closed bool
+}
+
+// openRotatingFile opens path for appending, rotating it as configured. Zero
+// limits are not enforced.
+func openRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating it first if p would exceed the limits.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	tooBig := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	tooOld := f.maxAge > 0 && time.Since(f.opened) >= f.maxAge
+	if tooBig || tooOld {
+		// If rotating fails, keep writing to the current file rather than lose records
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file at f.path for appending.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	if info.Size() > 0 {
+		f.opened = f.started(info)
+	}
+	return nil
+}
+
+// started returns when the existing file at f.path was started, so restarts
+// don't reset its age: when the newest backup was rotated away, or, if it
+// was never rotated, its modification time. The caller must hold f.mu.
+func (f *rotatingFile) started(info os.FileInfo) time.Time {
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+	backups, _ := filepath.Glob(prefix + "*" + ext)
+	sort.Strings(backups) // Timestamps sort chronologically
+	for i := len(backups) - 1; i >= 0; i-- {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(backups[i], prefix), ext)
+		if rotated, err := time.ParseInLocation(rotatedTimeFormat, stamp, time.Local); err == nil {
+			return rotated
+		}
+	}
+	return info.ModTime()
+}
+
+// rotate renames the current file aside, opens a new one and deletes the
+// backups beyond maxBackups. If the new file can't be opened, the old one is
+// moved back and written to until the next rotation; should that fail too,
+// the next Write retries. The caller must hold f.mu.
+func (f *rotatingFile) rotate() error {
+	ext := filepath.Ext(f.path)
+	rotated := strings.TrimSuffix(f.path, ext) + "-" + time.Now().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(f.path, rotated); err != nil {
+		return err
+	}
+	f.file.Close()
+	f.file = nil
+	if err := f.open(); err != nil {
+		if os.Rename(rotated, f.path) == nil {
+			f.open()
+		}
+		return err
+	}
+	f.prune()
+	return nil
+}
+
+// prune deletes the oldest rotated files beyond maxBackups. The caller must
+// hold f.mu.
+func (f *rotatingFile) prune() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	ext := filepath.Ext(f.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext)
+	if err != nil || len(backups) <= f.maxBackups {
+		return
+	}
+	sort.Strings(backups) // Timestamps sort chronologically
+	for _, backup := range backups[:len(backups)-f.maxBackups] {
+		os.Remove(backup)
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileKeepsAgeAcrossRestarts(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastRotated time.Duration // Age of the newest backup, none if zero
+		modified    time.Duration // Age of the file's last write
+		wantRotate  bool
+	}{
+		{name: "rotated long ago", lastRotated: 2 * time.Hour, modified: time.Minute, wantRotate: true},
+		{name: "rotated recently", lastRotated: 10 * time.Minute, modified: time.Minute},
+		{name: "never rotated, written long ago", modified: 2 * time.Hour, wantRotate: true},
+		{name: "never rotated, written recently", modified: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "cares.log")
+			os.WriteFile(path, []byte("earlier record\n"), 0644)
+			modified := time.Now().Add(-tt.modified)
+			os.Chtimes(path, modified, modified)
+			backups := 0
+			if tt.lastRotated > 0 {
+				stamp := time.Now().Add(-tt.lastRotated).Format(rotatedTimeFormat)
+				os.WriteFile(filepath.Join(dir, "cares-"+stamp+".log"), []byte("older record\n"), 0644)
+				backups++
+			}
+
+			f, err := openRotatingFile(path, 0, time.Hour, 0)
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			defer f.Close()
+			if _, err := f.Write([]byte("record\n")); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			rotated, _ := filepath.Glob(filepath.Join(dir, "cares-*.log"))
+			if got := len(rotated) > backups; got != tt.wantRotate {
+				t.Errorf("rotated = %v, want %v", got, tt.wantRotate)
+			}
+		})
+	}
+}
+
+func TestRotatingFileReopensAfterLosingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cares.log")
+	f, err := openRotatingFile(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	// As left by a rotation whose new file could not be opened
+	f.mu.Lock()
+	f.file.Close()
+	f.file = nil
+	f.mu.Unlock()
+
+	if _, err := f.Write([]byte("record\n")); err != nil {
+		t.Fatalf("write after losing the file: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "record\n" {
+		t.Errorf("file holds %q, want the record", data)
+	}
+
+	f.Close()
+	if _, err := f.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("write after Close: %v, want os.ErrClosed", err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package logstore keeps the structured log records shipped to the
+// orchestrator by workers, bounded and queryable by node, level and time.
+//
+// Like the invocation history, records are appended to a JSON-lines file as
+// they arrive and kept in memory for querying. Retention limits (maximum count
+// and age) are enforced on every append; the file is compacted once it holds
+// noticeably more lines than the retained records.
+package logstore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cares/internal/fsutil"
+	"cares/internal/logging"
+)
+
+// DefaultPath is the default location of the log store file.
+const DefaultPath = "data/logs.jsonl"
+
+// Record is a log record of a worker.
+type Record struct {
+	Time    time.Time         `json:"time"`
+	NodeID  string            `json:"node_id"`
+	Level   slog.Level        `json:"level"`
+	Message string            `json:"message"`
+	Source  string            `json:"source,omitempty"` // file:line of the logging call
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Retention bounds how many records are kept. Zero values disable a limit.
+type Retention struct {
+	MaxRecords int
+	MaxAge     time.Duration
+}
+
+// DefaultRetention keeps up to 100,000 records from the last 3 days.
+var DefaultRetention = Retention{
+	MaxRecords: 100000,
+	MaxAge:     3 * 24 * time.Hour,
+}
+
+// Filter selects records in Query. Empty fields match everything.
+type Filter struct {
+	Node         string    // Node ID
+	Level        string    // Minimum level: "debug", "info", "warn" or "error"
+	Since        time.Time // Only records logged at or after this time
+	Function     string    // Value of the function field
+	InvocationID string    // Value of the invocation_id field
+	Limit        int       // Maximum number of records returned (0 = no limit)
+	Offset       int       // Number of matching records to skip
+}
+
+// Store is a thread-safe, persistent store of log records.
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	retention Retention
+	records   []Record // In arrival order
+	file      *os.File
+	fileLines int  // Lines currently in the file, used to decide when to compact
+	closed    bool // Set by Close; until then a file lost to a failed reopen is retried
+}
+
+// Open loads the records stored at path (if any) and opens it for appending.
+func Open(path string, retention Retention) (*Store, error) {
+	s := &Store{
+		path:      path,
+		retention: retention,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.prune(time.Now())
+
+	if err := s.openForAppend(); err != nil {
+		return nil, err
+	}
+	// Rewrite the file straight away if the loaded records exceeded the limits
+	if s.fileLines > len(s.records) {
+		if err := s.compact(); err != nil {
+			s.file.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add appends records to the store and persists them with a single write.
+func (s *Store) Add(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal log record: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("log store is closed")
+	}
+	if s.file == nil {
+		if err := s.openForAppend(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append log records: %v", err)
+	}
+	s.fileLines += len(records)
+
+	s.records = append(s.records, records...)
+	s.prune(time.Now())
+
+	// Compact once the file carries twice as many lines as we retain. The
+	// records are already saved, so a failure is only worth a warning: the
+	// next append tries again.
+	if s.fileLines > 2*len(s.records) && s.fileLines > 1000 {
+		if err := s.compact(); err != nil {
+			logging.Warn("Log store keeps growing: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Query returns matching records newest first, honouring the filter's
+// pagination, together with the total number of matches.
+func (s *Store) Query(filter Filter) ([]Record, int, error) {
+	minLevel := slog.LevelDebug
+	if filter.Level != "" {
+		level, err := logging.ParseLevel(filter.Level)
+		if err != nil {
+			return nil, 0, err
+		}
+		minLevel = level
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Record
+	for i := len(s.records) - 1; i >= 0; i-- {
+		record := s.records[i]
+		if filter.Node != "" && record.NodeID != filter.Node {
+			continue
+		}
+		if record.Level < minLevel {
+			continue
+		}
+		if !filter.Since.IsZero() && record.Time.Before(filter.Since) {
+			continue
+		}
+		if filter.Function != "" && record.Fields[logging.KeyFunction] != filter.Function {
+			continue
+		}
+		if filter.InvocationID != "" && record.Fields[logging.KeyInvocationID] != filter.InvocationID {
+			continue
+		}
+		matches = append(matches, record)
+	}
+
+	total := len(matches)
+	if filter.Offset >= total {
+		return []Record{}, total, nil
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches, total, nil
+}
+
+// Count returns the number of retained records.
+func (s *Store) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.records)
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// load reads every record from the store file. A missing file is an empty store.
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read log store: %v", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		s.fileLines++
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A torn final line after a crash is expected; skip it
+			continue
+		}
+		s.records = append(s.records, record)
+	}
+	return scanner.Err()
+}
+
+// prune drops records outside the retention limits. Records arrive roughly in
+// time order, so only the oldest end is checked for age. The caller must hold
+// s.mu (or own s exclusively).
+func (s *Store) prune(now time.Time) {
+	start := 0
+	if s.retention.MaxAge > 0 {
+		cutoff := now.Add(-s.retention.MaxAge)
+		for start < len(s.records) && s.records[start].Time.Before(cutoff) {
+			start++
+		}
+	}
+	if s.retention.MaxRecords > 0 && len(s.records)-start > s.retention.MaxRecords {
+		start = len(s.records) - s.retention.MaxRecords
+	}
+	if start > 0 {
+		s.records = s.records[start:]
+		// Copy once the slice has twice the room it needs, so memory stays bounded
+		if cap(s.records) > 2*len(s.records) {
+			s.records = append([]Record(nil), s.records...)
+		}
+	}
+}
+
+// compact rewrites the store file with only the retained records and reopens
+// it for appending. If the rewrite fails, appends carry on to the old file.
+func (s *Store) compact() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range s.records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal log record: %v", err)
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to compact log store: %v", err)
+	}
+	s.fileLines = len(s.records)
+
+	// The file was replaced, so appends must go to the new one
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	return s.openForAppend()
+}
+
+// openForAppend opens the store file for appending new records.
+func (s *Store) openForAppend() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log store: %v", err)
+	}
+	s.file = file
+	return nil
+}
@@ -1,7 +1,9 @@
 // Package metrics provides utilities for collecting system resource usage
-// statistics such as CPU and memory utilization. The functions in this package
-// are safe to call from other packages and return values in percentage units
-// (0.0 - 100.0).
+// statistics. GetCPUUsage and GetMemoryUsage return utilization in percentage
+// units (0.0 - 100.0); GetNodeStats reports absolute capacity, disk, network,
+// load and uptime. A Sampler takes these readings in the background so that
+// consumers get them without waiting. The functions in this package are safe
+// to call from other packages and from multiple goroutines.
 package metrics
 
 import (
@@ -15,6 +17,8 @@ import (
 //
 // It samples the CPU usage over a 1-second interval and returns the average usage
 // as a float64 value (0.0 - 100.0). If an error occurs or no data is available, it returns 0 and the error.
+// The call blocks for that second; long-running consumers should read the
+// shared Sampler returned by Default instead.
 //
 // Example usage:
 //     cpu, err := metrics.GetCPUUsage()
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// NodeStats describes the capacity and load of the machine a worker runs on.
+// Sizes are in bytes and rates in bytes per second.
+type NodeStats struct {
+	CPUCores        int
+	MemoryTotal     uint64
+	MemoryAvailable uint64
+	DiskPath        string // Filesystem path DiskTotal and DiskFree were measured on
+	DiskTotal       uint64
+	DiskFree        uint64
+	NetworkRxRate   float64 // Received over all external interfaces
+	NetworkTxRate   float64 // Sent over all external interfaces
+	Load1           float64 // Load averages over 1, 5 and 15 minutes
+	Load5           float64
+	Load15          float64
+	Uptime          time.Duration
+}
+
+// virtualInterfaces are name prefixes of interfaces that only relay traffic
+// already counted on a physical interface, or never leave the machine.
+var virtualInterfaces = []string{"lo", "veth", "docker", "br-", "cni", "podman", "virbr"}
+
+// networkSample is the previous reading of the interface byte counters,
+// which throughput is computed against.
+var (
+	networkMu     sync.Mutex
+	networkSample struct {
+		rx, tx uint64
+		at     time.Time
+	}
+)
+
+// GetNodeStats collects the machine's capacity, disk, network, load and uptime.
+// Disk space is measured on the filesystem holding diskPath, or on "/" if
+// diskPath is empty or inaccessible.
+//
+// Network throughput is averaged since the previous call, so the first call
+// reports zero rates. Stats that cannot be read are left zero and reported in
+// the returned error; the others are still filled in.
+//
+// Example usage:
+//
+//	stats, err := metrics.GetNodeStats("/var/lib/docker")
+//	if err != nil {
+//	    // some stats are missing
+//	}
+//	fmt.Printf("Free disk: %d bytes\n", stats.DiskFree)
+func GetNodeStats(diskPath string) (NodeStats, error) {
+	stats := NodeStats{CPUCores: runtime.NumCPU()}
+	var errs []error
+
+	if vmStat, err := mem.VirtualMemory(); err != nil {
+		errs = append(errs, fmt.Errorf("memory: %w", err))
+	} else {
+		stats.MemoryTotal = vmStat.Total
+		stats.MemoryAvailable = vmStat.Available
+	}
+
+	if err := stats.readDisk(diskPath); err != nil {
+		errs = append(errs, fmt.Errorf("disk: %w", err))
+	}
+
+	if err := stats.readNetwork(); err != nil {
+		errs = append(errs, fmt.Errorf("network: %w", err))
+	}
+
+	if avg, err := load.Avg(); err != nil {
+		errs = append(errs, fmt.Errorf("load average: %w", err))
+	} else {
+		stats.Load1, stats.Load5, stats.Load15 = avg.Load1, avg.Load5, avg.Load15
+	}
+
+	if uptime, err := host.Uptime(); err != nil {
+		errs = append(errs, fmt.Errorf("uptime: %w", err))
+	} else {
+		stats.Uptime = time.Duration(uptime) * time.Second
+	}
+
+	return stats, errors.Join(errs...)
+}
+
+// readDisk measures the filesystem holding path, falling back to the root
+// filesystem, e.g. when the container engine runs on another machine.
+func (s *NodeStats) readDisk(path string) error {
+	usage, err := disk.Usage(path)
+	if path == "" || err != nil {
+		path = "/"
+		if runtime.GOOS == "windows" {
+			path = "C:\\"
+		}
+		usage, err = disk.Usage(path)
+	}
+	if err != nil {
+		return err
+	}
+	s.DiskPath = path
+	s.DiskTotal = usage.Total
+	s.DiskFree = usage.Free
+	return nil
+}
+
+// readNetwork computes the throughput since the previous reading.
+func (s *NodeStats) readNetwork() error {
+	counters, err := psnet.IOCounters(true)
+	if err != nil {
+		return err
+	}
+
+	var rx, tx uint64
+	for _, counter := range counters {
+		if !isVirtualInterface(counter.Name) {
+			rx += counter.BytesRecv
+			tx += counter.BytesSent
+		}
+	}
+	now := time.Now()
+
+	networkMu.Lock()
+	defer networkMu.Unlock()
+
+	// Counters shrink when an interface goes away; skip that interval
+	previous := networkSample
+	if elapsed := now.Sub(previous.at).Seconds(); !previous.at.IsZero() && elapsed > 0 && rx >= previous.rx && tx >= previous.tx {
+		s.NetworkRxRate = float64(rx-previous.rx) / elapsed
+		s.NetworkTxRate = float64(tx-previous.tx) / elapsed
+	}
+	networkSample.rx, networkSample.tx, networkSample.at = rx, tx, now
+	return nil
+}
+
+// isVirtualInterface reports whether an interface is excluded from throughput.
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfaces {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
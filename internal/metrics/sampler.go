@@ -0,0 +1,264 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+
+	"cares/internal/logging"
+)
+
+// Defaults of the process-wide sampler returned by Default
+const (
+	DefaultSampleInterval = 2 * time.Second
+	DefaultSampleHistory  = 300 // Ten minutes at DefaultSampleInterval
+
+	warmup = time.Second // Longest wait between priming the counters and the first sample
+)
+
+// Sample is one reading taken by a Sampler.
+type Sample struct {
+	Time        time.Time
+	CPUUsage    float64 // Average over the interval since the previous sample, 0.0 - 100.0
+	MemoryUsage float64 // 0.0 - 100.0
+	Node        NodeStats
+	Err         error // Set if CPU or memory usage could not be read
+}
+
+// Sampler reads system metrics in the background at a fixed interval and
+// keeps the most recent samples in a ring buffer. Readers never wait for a
+// measurement, so any number of consumers can share one Sampler. It is safe
+// for concurrent use.
+type Sampler struct {
+	interval time.Duration
+
+	mu       sync.RWMutex
+	samples  []Sample // Ring buffer, next is the slot written next
+	next     int
+	count    int
+	diskPath string
+	lastCPU  *cpu.TimesStat // Counters at the previous sample, nil before the first
+	started  bool
+}
+
+var (
+	defaultMu      sync.Mutex
+	defaultSampler *Sampler
+)
+
+// NewSampler creates a sampler taking a sample every interval and remembering
+// the last size samples. It does not sample until Start is called.
+func NewSampler(interval time.Duration, size int) *Sampler {
+	if interval <= 0 {
+		interval = DefaultSampleInterval
+	}
+	if size <= 0 {
+		size = DefaultSampleHistory
+	}
+	return &Sampler{
+		interval: interval,
+		samples:  make([]Sample, size),
+	}
+}
+
+// Default returns the process-wide sampler, starting it with
+// DefaultSampleInterval and DefaultSampleHistory on first use. It runs for
+// the lifetime of the process.
+func Default() *Sampler {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultSampler == nil {
+		defaultSampler = NewSampler(DefaultSampleInterval, DefaultSampleHistory)
+		defaultSampler.Start(context.Background())
+	}
+	return defaultSampler
+}
+
+// Start takes the first sample shortly after it is called, and then one
+// every interval until ctx is done. It returns at once; sampling happens in
+// the background. Starting a sampler more than once has no effect.
+func (s *Sampler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	go func() {
+		// Usage and throughput are rates, measured between two readings
+		s.prime()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(min(s.interval, warmup)):
+		}
+		s.sample()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	}()
+}
+
+// Interval returns the time between samples.
+func (s *Sampler) Interval() time.Duration {
+	return s.interval
+}
+
+// SetDiskPath sets the path whose filesystem NodeStats disk space is measured
+// on, see GetNodeStats. It takes effect from the next sample.
+func (s *Sampler) SetDiskPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.diskPath = path
+}
+
+// Latest returns the most recent sample. It reports false if no sample has
+// been taken yet.
+func (s *Sampler) Latest() (Sample, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.count == 0 {
+		return Sample{}, false
+	}
+	return s.samples[(s.next-1+len(s.samples))%len(s.samples)], true
+}
+
+// Average returns the latest sample with CPU and memory usage averaged over
+// the samples taken within window. Samples that failed are left out. It
+// reports false if there is no successful sample in the window.
+func (s *Sampler) Average(window time.Duration) (Sample, bool) {
+	history := s.History(0)
+	if len(history) == 0 {
+		return Sample{}, false
+	}
+
+	average := history[len(history)-1]
+	since := average.Time.Add(-window)
+	var cpuSum, memorySum float64
+	n := 0
+	for _, sample := range history {
+		if sample.Err == nil && !sample.Time.Before(since) {
+			cpuSum += sample.CPUUsage
+			memorySum += sample.MemoryUsage
+			n++
+		}
+	}
+	if n == 0 {
+		return Sample{}, false
+	}
+	average.CPUUsage, average.MemoryUsage, average.Err = cpuSum/float64(n), memorySum/float64(n), nil
+	return average, true
+}
+
+// History returns up to the last n samples, oldest first. n <= 0 returns all
+// samples kept.
+func (s *Sampler) History(n int) []Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if n <= 0 || n > s.count {
+		n = s.count
+	}
+	history := make([]Sample, n)
+	for i := range history {
+		history[i] = s.samples[(s.next-n+i+len(s.samples))%len(s.samples)]
+	}
+	return history
+}
+
+// prime takes the readings the first sample's rates are computed against.
+func (s *Sampler) prime() {
+	times, err := cpu.Times(false)
+
+	s.mu.Lock()
+	if err == nil && len(times) > 0 {
+		s.lastCPU = &times[0]
+	}
+	diskPath := s.diskPath
+	s.mu.Unlock()
+
+	GetNodeStats(diskPath)
+}
+
+// sample takes one sample and stores it in the ring buffer.
+func (s *Sampler) sample() {
+	s.mu.RLock()
+	diskPath, lastCPU := s.diskPath, s.lastCPU
+	s.mu.RUnlock()
+
+	sample := Sample{Time: time.Now()}
+	var errs []error
+
+	times, err := cpu.Times(false)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("cpu: %w", err))
+	case len(times) == 0:
+		errs = append(errs, errors.New("cpu: no counters reported"))
+	default:
+		if lastCPU != nil {
+			sample.CPUUsage = cpuBusy(*lastCPU, times[0])
+		}
+		lastCPU = &times[0]
+	}
+
+	if vmStat, err := mem.VirtualMemory(); err != nil {
+		errs = append(errs, fmt.Errorf("memory: %w", err))
+	} else {
+		sample.MemoryUsage = vmStat.UsedPercent
+	}
+	sample.Err = errors.Join(errs...)
+
+	// Missing node stats are left zero; they never fail the sample
+	stats, err := GetNodeStats(diskPath)
+	if err != nil {
+		logging.Debug("Incomplete node stats: %v", err)
+	}
+	sample.Node = stats
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastCPU = lastCPU
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.count < len(s.samples) {
+		s.count++
+	}
+}
+
+// cpuBusy returns the share of CPU time spent busy between two readings of
+// the CPU counters, in percent.
+func cpuBusy(before, after cpu.TimesStat) float64 {
+	total := func(t cpu.TimesStat) (float64, float64) {
+		all := t.Total()
+		if runtime.GOOS == "linux" {
+			all -= t.Guest + t.GuestNice // Already counted in user and nice time
+		}
+		return all, all - t.Idle - t.Iowait
+	}
+	beforeAll, beforeBusy := total(before)
+	afterAll, afterBusy := total(after)
+	if afterAll <= beforeAll || afterBusy <= beforeBusy {
+		return 0
+	}
+	return math.Min(100, (afterBusy-beforeBusy)/(afterAll-beforeAll)*100)
+}
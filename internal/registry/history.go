@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"time"
+)
+
+// MetricPoint is the resource usage of a node at one moment, or averaged over
+// the interval a downsampled point covers, starting at Time.
+type MetricPoint struct {
+	Time              time.Time `json:"time"`
+	CPUUsage          float64   `json:"cpu_usage"`
+	MemoryUsage       float64   `json:"memory_usage"`
+	Load1             float64   `json:"load1"`
+	NetworkRxRate     float64   `json:"network_rx_rate"`
+	NetworkTxRate     float64   `json:"network_tx_rate"`
+	DiskFree          float64   `json:"disk_free"`
+	RunningContainers float64   `json:"running_containers"`
+}
+
+// HistoryTier is a resolution node metrics are kept at, and for how long.
+type HistoryTier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// DefaultHistoryTiers keep an hour of metrics at heartbeat resolution and a
+// day at one point per minute.
+var DefaultHistoryTiers = []HistoryTier{
+	{Resolution: 2 * time.Second, Retention: time.Hour},
+	{Resolution: time.Minute, Retention: 24 * time.Hour},
+}
+
+// nodeHistory is the metric history of one node, kept at every tier.
+type nodeHistory struct {
+	tiers []*tierSeries
+}
+
+// tierSeries is a ring buffer of points at one resolution. Incoming points
+// are averaged into the current bucket until one arrives for a later bucket.
+type tierSeries struct {
+	tier   HistoryTier
+	points []MetricPoint
+	next   int
+	count  int
+
+	bucket time.Time   // Start of the bucket being accumulated, zero before the first point
+	sum    MetricPoint // Sum of the points in the current bucket
+	n      int
+}
+
+// newNodeHistory creates an empty history with the given tiers.
+func newNodeHistory(tiers []HistoryTier) *nodeHistory {
+	history := &nodeHistory{}
+	for _, tier := range tiers {
+		size := int(tier.Retention / tier.Resolution)
+		if size < 1 {
+			size = 1
+		}
+		history.tiers = append(history.tiers, &tierSeries{tier: tier, points: make([]MetricPoint, size)})
+	}
+	return history
+}
+
+// add records a point in every tier.
+func (h *nodeHistory) add(point MetricPoint) {
+	for _, series := range h.tiers {
+		series.add(point)
+	}
+}
+
+// add averages point into its bucket, completing the current bucket first if
+// point belongs to a later one.
+func (t *tierSeries) add(point MetricPoint) {
+	bucket := point.Time.Truncate(t.tier.Resolution)
+	if t.n > 0 && bucket.After(t.bucket) {
+		t.points[t.next] = t.average()
+		t.next = (t.next + 1) % len(t.points)
+		if t.count < len(t.points) {
+			t.count++
+		}
+		t.n = 0
+	}
+	if t.n == 0 {
+		t.bucket, t.sum = bucket, MetricPoint{}
+	}
+	t.sum = t.sum.plus(point)
+	t.n++
+}
+
+// average returns the point the current bucket adds up to.
+func (t *tierSeries) average() MetricPoint {
+	point := t.sum.scaled(1 / float64(t.n))
+	point.Time = t.bucket
+	return point
+}
+
+// since returns the points from the given time on, oldest first, including
+// the bucket still being accumulated.
+func (t *tierSeries) since(from time.Time) []MetricPoint {
+	var points []MetricPoint
+	for i := 0; i < t.count; i++ {
+		point := t.points[(t.next-t.count+i+len(t.points))%len(t.points)]
+		if !point.Time.Before(from.Truncate(t.tier.Resolution)) {
+			points = append(points, point)
+		}
+	}
+	if t.n > 0 && !t.bucket.Before(from.Truncate(t.tier.Resolution)) {
+		points = append(points, t.average())
+	}
+	return points
+}
+
+// plus returns the field-wise sum of two points, keeping p's time.
+func (p MetricPoint) plus(q MetricPoint) MetricPoint {
+	p.CPUUsage += q.CPUUsage
+	p.MemoryUsage += q.MemoryUsage
+	p.Load1 += q.Load1
+	p.NetworkRxRate += q.NetworkRxRate
+	p.NetworkTxRate += q.NetworkTxRate
+	p.DiskFree += q.DiskFree
+	p.RunningContainers += q.RunningContainers
+	return p
+}
+
+// scaled returns p with every value multiplied by factor.
+func (p MetricPoint) scaled(factor float64) MetricPoint {
+	p.CPUUsage *= factor
+	p.MemoryUsage *= factor
+	p.Load1 *= factor
+	p.NetworkRxRate *= factor
+	p.NetworkTxRate *= factor
+	p.DiskFree *= factor
+	p.RunningContainers *= factor
+	return p
+}
+
+// MaxHistoryRange returns the longest range History can answer.
+func (nr *NodeRegistry) MaxHistoryRange() time.Duration {
+	var longest time.Duration
+	for _, tier := range nr.historyTiers {
+		if tier.Retention > longest {
+			longest = tier.Retention
+		}
+	}
+	return longest
+}
+
+// RecordHistory adds the metrics a node currently reports to its history.
+// Returns true if the node exists, false otherwise.
+func (nr *NodeRegistry) RecordHistory(nodeID string) bool {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[nodeID]
+	if !exists {
+		return false
+	}
+
+	history, exists := nr.history[nodeID]
+	if !exists {
+		history = newNodeHistory(nr.historyTiers)
+		nr.history[nodeID] = history
+	}
+	history.add(MetricPoint{
+		Time:              time.Now(),
+		CPUUsage:          node.CPUUsage,
+		MemoryUsage:       node.MemoryUsage,
+		Load1:             node.Resources.Load1,
+		NetworkRxRate:     node.Resources.NetworkRxRate,
+		NetworkTxRate:     node.Resources.NetworkTxRate,
+		DiskFree:          float64(node.Resources.DiskFree),
+		RunningContainers: float64(node.Resources.RunningContainers),
+	})
+	return true
+}
+
+// History returns a node's metrics over the last window, oldest first, from
+// the finest tier that retains the whole window, along with that tier's
+// resolution. Windows longer than MaxHistoryRange are cut to it. The final
+// boolean is false if the node does not exist.
+func (nr *NodeRegistry) History(nodeID string, window time.Duration) ([]MetricPoint, time.Duration, bool) {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+
+	if _, exists := nr.nodes[nodeID]; !exists {
+		return nil, 0, false
+	}
+	history, exists := nr.history[nodeID]
+	if !exists || len(history.tiers) == 0 {
+		return nil, 0, true
+	}
+
+	series := history.tiers[len(history.tiers)-1]
+	for _, candidate := range history.tiers {
+		if candidate.tier.Retention >= window && candidate.tier.Resolution < series.tier.Resolution {
+			series = candidate
+		}
+	}
+	return series.since(time.Now().Add(-window)), series.tier.Resolution, true
+}
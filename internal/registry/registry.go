@@ -4,8 +4,14 @@
 package registry
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
+
+	"cares/internal/events"
+	"cares/internal/imageref"
+	"cares/internal/logging"
 )
 
 // NodeStatus represents the current status of a node in the cluster.
@@ -22,27 +28,61 @@ const (
 
 // Node represents a worker node in the cluster with its current state and metrics.
 type Node struct {
-	ID           string     `json:"id"`
-	Address      string     `json:"address"`
-	Hostname     string     `json:"hostname"`
-	Status       NodeStatus `json:"status"`
-	CPUUsage     float64    `json:"cpu_usage"`
-	MemoryUsage  float64    `json:"memory_usage"`
-	LastSeen     time.Time  `json:"last_seen"`
-	JoinedAt     time.Time  `json:"joined_at"`
+	ID          string        `json:"id"`
+	Address     string        `json:"address"`
+	Hostname    string        `json:"hostname"`
+	Status      NodeStatus    `json:"status"`
+	CPUUsage    float64       `json:"cpu_usage"`
+	MemoryUsage float64       `json:"memory_usage"`
+	LastSeen    time.Time     `json:"last_seen"`
+	JoinedAt    time.Time     `json:"joined_at"`
+	Runtime     RuntimeStatus `json:"runtime"`
+	Images      []string      `json:"images,omitempty"` // Images cached on the node
+	Resources   NodeResources `json:"resources"`
+}
+
+// NodeResources is the machine capacity and load last reported by a node.
+// Sizes are in bytes, rates in bytes per second.
+type NodeResources struct {
+	CPUCores          int     `json:"cpu_cores"`
+	MemoryTotal       uint64  `json:"memory_total"`
+	MemoryAvailable   uint64  `json:"memory_available"`
+	DiskPath          string  `json:"disk_path,omitempty"` // Filesystem the disk figures belong to
+	DiskTotal         uint64  `json:"disk_total"`
+	DiskFree          uint64  `json:"disk_free"`
+	NetworkRxRate     float64 `json:"network_rx_rate"`
+	NetworkTxRate     float64 `json:"network_tx_rate"`
+	Load1             float64 `json:"load1"`
+	Load5             float64 `json:"load5"`
+	Load15            float64 `json:"load15"`
+	RunningContainers int     `json:"running_containers"`
+	UptimeSeconds     int64   `json:"uptime_seconds"`
+}
+
+// RuntimeStatus is the container runtime health last reported by a node.
+type RuntimeStatus struct {
+	Name      string `json:"name,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
 }
 
 // NodeRegistry provides thread-safe management of cluster nodes.
 // It maintains a registry of all nodes and their current state.
 type NodeRegistry struct {
-	mu    sync.RWMutex
-	nodes map[string]*Node
+	mu           sync.RWMutex
+	nodes        map[string]*Node
+	history      map[string]*nodeHistory // Metric history by node ID
+	historyTiers []HistoryTier
 }
 
-// NewNodeRegistry creates a new thread-safe node registry.
+// NewNodeRegistry creates a new thread-safe node registry that keeps metric
+// history at DefaultHistoryTiers.
 func NewNodeRegistry() *NodeRegistry {
 	return &NodeRegistry{
-		nodes: make(map[string]*Node),
+		nodes:        make(map[string]*Node),
+		history:      make(map[string]*nodeHistory),
+		historyTiers: DefaultHistoryTiers,
 	}
 }
 
@@ -65,6 +105,14 @@ func (nr *NodeRegistry) AddNode(id, address, hostname string) *Node {
 	}
 
 	nr.nodes[id] = node
+
+	events.Publish(context.Background(), events.Event{
+		Type:    events.NodeJoined,
+		Actor:   events.NodeActor(id),
+		Subject: id,
+		Name:    hostname,
+		Data:    map[string]string{"address": address},
+	})
 	return node
 }
 
@@ -87,6 +135,89 @@ func (nr *NodeRegistry) UpdateMetrics(nodeID string, cpuUsage, memoryUsage float
 	return true
 }
 
+// UpdateResources records the machine capacity and load reported by a node.
+// Returns true if the node exists, false otherwise.
+func (nr *NodeRegistry) UpdateResources(nodeID string, resources NodeResources) bool {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[nodeID]
+	if !exists {
+		return false
+	}
+
+	node.Resources = resources
+	return true
+}
+
+// UpdateRuntime records the container runtime health reported by a node.
+// Returns true if the node exists, false otherwise.
+func (nr *NodeRegistry) UpdateRuntime(nodeID string, runtime RuntimeStatus) bool {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[nodeID]
+	if !exists {
+		return false
+	}
+
+	// Log transitions, and a runtime that is down from the first report
+	firstReport := node.Runtime.Name == ""
+	switch {
+	case !runtime.Available && (firstReport || node.Runtime.Available):
+		logging.With(logging.KeyNodeID, nodeID).Warn("Container runtime is down", "error", runtime.Error)
+		events.Publish(context.Background(), events.Event{
+			Type:    events.NodeRuntimeDown,
+			Actor:   events.NodeActor(nodeID),
+			Subject: nodeID,
+			Name:    node.Hostname,
+			Data:    map[string]string{"runtime": runtime.Name, "error": runtime.Error},
+		})
+	case runtime.Available && !firstReport && !node.Runtime.Available:
+		logging.With(logging.KeyNodeID, nodeID).Info("Container runtime is available again")
+		events.Publish(context.Background(), events.Event{
+			Type:    events.NodeRuntimeUp,
+			Actor:   events.NodeActor(nodeID),
+			Subject: nodeID,
+			Name:    node.Hostname,
+			Data:    map[string]string{"runtime": runtime.Name},
+		})
+	}
+	node.Runtime = runtime
+	return true
+}
+
+// UpdateImages records the images a node reports as cached locally.
+// Returns true if the node exists, false otherwise.
+func (nr *NodeRegistry) UpdateImages(nodeID string, images []string) bool {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[nodeID]
+	if !exists {
+		return false
+	}
+
+	// Replaced rather than modified, so copies handed out stay consistent
+	node.Images = append([]string(nil), images...)
+	return true
+}
+
+// HasImage reports whether the node has the image cached. References are
+// compared in normalized form, so "nginx" matches "nginx:latest".
+func (n *Node) HasImage(image string) bool {
+	image, err := imageref.Normalize(image)
+	if err != nil {
+		return false
+	}
+	for _, cached := range n.Images {
+		if cached == image {
+			return true
+		}
+	}
+	return false
+}
+
 // GetNode retrieves a node by ID. Returns nil if not found.
 func (nr *NodeRegistry) GetNode(nodeID string) *Node {
 	nr.mu.RLock()
@@ -102,8 +233,9 @@ func (nr *NodeRegistry) GetNode(nodeID string) *Node {
 	return &nodeCopy
 }
 
-// GetAllNodes returns a snapshot of all nodes in the registry.
-// The returned slice contains copies of the nodes to prevent concurrent access issues.
+// GetAllNodes returns a snapshot of all nodes in the registry, in the order
+// they joined. The returned slice contains copies of the nodes to prevent
+// concurrent access issues.
 func (nr *NodeRegistry) GetAllNodes() []*Node {
 	nr.mu.RLock()
 	defer nr.mu.RUnlock()
@@ -115,6 +247,13 @@ func (nr *NodeRegistry) GetAllNodes() []*Node {
 		nodes = append(nodes, &nodeCopy)
 	}
 
+	// A stable order keeps table selections on the same node between refreshes
+	sort.Slice(nodes, func(i, j int) bool {
+		if !nodes[i].JoinedAt.Equal(nodes[j].JoinedAt) {
+			return nodes[i].JoinedAt.Before(nodes[j].JoinedAt)
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
 	return nodes
 }
 
@@ -124,9 +263,11 @@ func (nr *NodeRegistry) RemoveNode(nodeID string) bool {
 	nr.mu.Lock()
 	defer nr.mu.Unlock()
 
-	_, exists := nr.nodes[nodeID]
+	node, exists := nr.nodes[nodeID]
 	if exists {
 		delete(nr.nodes, nodeID)
+		delete(nr.history, nodeID)
+		events.Publish(context.Background(), events.Event{Type: events.NodeRemoved, Subject: nodeID, Name: node.Hostname})
 	}
 
 	return exists
@@ -143,7 +284,10 @@ func (nr *NodeRegistry) MarkDisconnected(nodeID string) bool {
 		return false
 	}
 
-	node.Status = NodeStatusDisconnected
+	if node.Status != NodeStatusDisconnected {
+		node.Status = NodeStatusDisconnected
+		events.Publish(context.Background(), events.Event{Type: events.NodeDisconnected, Subject: nodeID, Name: node.Hostname})
+	}
 	return true
 }
 
@@ -1,6 +1,7 @@
 // Package scheduler provides intelligent worker node selection for function execution.
 // It implements a cost-based scheduling algorithm that considers CPU and memory
-// usage to distribute workload optimally across available worker nodes.
+// usage to distribute workload optimally across available worker nodes, skipping
+// nodes that cannot run the function at all.
 package scheduler
 
 import (
@@ -26,12 +27,33 @@ func NewScheduler() *Scheduler {
 	return &Scheduler{}
 }
 
-// SelectNodeForExecution selects the optimal worker node for function execution
-// based on a cost model that considers CPU and memory usage.
+// Requirements describes what a function needs from the node that runs it.
+type Requirements struct {
+	// ContainerRuntime requires a container runtime that passed its last health
+	// check. Process and wasm functions run without one.
+	ContainerRuntime bool
+	// Image prefers nodes that already have the image cached, sparing the
+	// invocation a pull.
+	Image string
+}
+
+// coldImagePenalty is added to the cost of nodes that would have to pull the
+// function's image first, so a warm node wins unless it is much busier.
+const coldImagePenalty = 20.0
+
+// SelectNodeForExecution selects the optimal worker node for a function without
+// special requirements. See SelectNode.
+func (s *Scheduler) SelectNodeForExecution(nodeRegistry *registry.NodeRegistry) (*registry.Node, error) {
+	return s.SelectNode(nodeRegistry, Requirements{})
+}
+
+// SelectNode selects the optimal worker node for function execution based on a
+// cost model that considers CPU and memory usage.
 //
 // The selection algorithm:
-//  1. Filters for only active worker nodes
-//  2. Calculates cost score: (cpu_usage * 0.5) + (memory_usage * 0.5)
+//  1. Filters for only active worker nodes that meet the requirements
+//  2. Calculates cost score: (cpu_usage * 0.5) + (memory_usage * 0.5), plus
+//     coldImagePenalty if the node does not have the required image cached
 //  3. Selects the node with the lowest cost score (least utilized)
 //
 // Parameters:
@@ -43,12 +65,12 @@ func NewScheduler() *Scheduler {
 //
 // Example usage:
 //
-//	selectedNode, err := scheduler.SelectNodeForExecution(nodeRegistry)
+//	selectedNode, err := scheduler.SelectNode(nodeRegistry, Requirements{ContainerRuntime: true})
 //	if err != nil {
 //	    return fmt.Errorf("no workers available: %w", err)
 //	}
 //	// Execute function on selectedNode
-func (s *Scheduler) SelectNodeForExecution(nodeRegistry *registry.NodeRegistry) (*registry.Node, error) {
+func (s *Scheduler) SelectNode(nodeRegistry *registry.NodeRegistry, requirements Requirements) (*registry.Node, error) {
 	if nodeRegistry == nil {
 		return nil, fmt.Errorf("node registry is nil")
 	}
@@ -69,6 +91,20 @@ func (s *Scheduler) SelectNodeForExecution(nodeRegistry *registry.NodeRegistry)
 	if len(activeNodes) == 0 {
 		return nil, fmt.Errorf("no active worker nodes available")
 	}
+
+	// Nodes whose container runtime is down can't run container functions
+	if requirements.ContainerRuntime {
+		var healthyNodes []*registry.Node
+		for _, node := range activeNodes {
+			if node.Runtime.Available {
+				healthyNodes = append(healthyNodes, node)
+			}
+		}
+		if len(healthyNodes) == 0 {
+			return nil, fmt.Errorf("no active worker nodes with an available container runtime")
+		}
+		activeNodes = healthyNodes
+	}
 	
 	// Find the node with the lowest cost score
 	var bestNode *registry.Node
@@ -77,6 +113,9 @@ func (s *Scheduler) SelectNodeForExecution(nodeRegistry *registry.NodeRegistry)
 	for _, node := range activeNodes {
 		// Cost model: (cpu_usage * 0.5) + (memory_usage * 0.5)
 		score := (node.CPUUsage * 0.5) + (node.MemoryUsage * 0.5)
+		if requirements.Image != "" && !node.HasImage(requirements.Image) {
+			score += coldImagePenalty
+		}
 		
 		if lowestScore == -1 || score < lowestScore {
 			lowestScore = score
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cares/internal/executor"
+	"cares/internal/registry"
+)
+
+func TestSelectNodeFollowsRuntimeHealth(t *testing.T) {
+	runtime := executor.NewFakeRuntime()
+	executor.SetRuntime(runtime)
+	t.Cleanup(func() { executor.SetRuntime(nil) })
+
+	// worker-1 is idle but runs on the fake runtime; worker-2 is busier
+	nodes := registry.NewNodeRegistry()
+	nodes.AddNode("worker-1", "127.0.0.1:50052", "worker-1")
+	nodes.UpdateMetrics("worker-1", 10, 10)
+	nodes.AddNode("worker-2", "127.0.0.2:50052", "worker-2")
+	nodes.UpdateMetrics("worker-2", 60, 60)
+	nodes.UpdateRuntime("worker-2", registry.RuntimeStatus{Name: executor.RuntimeFake, Available: true})
+
+	// report probes the fake runtime and reports its health for worker-1, as
+	// its heartbeats would
+	report := func() {
+		health := executor.CheckRuntimeHealth(context.Background())
+		nodes.UpdateRuntime("worker-1", registry.RuntimeStatus{
+			Name:      health.Runtime,
+			Version:   health.Version,
+			Available: health.Available,
+			Error:     health.Error,
+		})
+	}
+
+	steps := []struct {
+		name          string
+		down          error
+		wantContainer string // Node selected for container functions
+		wantOther     string // Node selected for functions needing no runtime
+	}{
+		{name: "healthy", wantContainer: "worker-1", wantOther: "worker-1"},
+		{name: "runtime down", down: errors.New("docker daemon not responding"), wantContainer: "worker-2", wantOther: "worker-1"},
+		{name: "recovered", wantContainer: "worker-1", wantOther: "worker-1"},
+	}
+
+	scheduler := NewScheduler()
+	for _, step := range steps {
+		runtime.SetDown(step.down)
+		report()
+
+		node, err := scheduler.SelectNode(nodes, Requirements{ContainerRuntime: true})
+		if err != nil || node.ID != step.wantContainer {
+			t.Errorf("%s: container function scheduled on %v (%v), want %s", step.name, node, err, step.wantContainer)
+		}
+		node, err = scheduler.SelectNode(nodes, Requirements{})
+		if err != nil || node.ID != step.wantOther {
+			t.Errorf("%s: process function scheduled on %v (%v), want %s", step.name, node, err, step.wantOther)
+		}
+	}
+
+	// Without any healthy runtime, container functions can't be scheduled
+	runtime.SetDown(errors.New("docker daemon not responding"))
+	report()
+	nodes.UpdateRuntime("worker-2", registry.RuntimeStatus{Name: executor.RuntimeFake, Error: "stopped"})
+	if node, err := scheduler.SelectNode(nodes, Requirements{ContainerRuntime: true}); err == nil {
+		t.Errorf("container function scheduled on %s with every runtime down", node.ID)
+	}
+}
@@ -0,0 +1,337 @@
+// Package telemetry exposes process metrics in the Prometheus text exposition
+// format. Counters, gauges and histograms are registered once, usually as
+// package-level variables next to the code updating them, and written out by
+// Handler on every scrape together with values computed at scrape time by
+// Collectors.
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cares/internal/logging"
+)
+
+// Metric types of the exposition format
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of latency histograms:
+// from 5ms for short process functions up to 5 minutes for slow containers.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// labelSeparator joins label values into series keys; it cannot occur in UTF-8 text.
+const labelSeparator = "\xff"
+
+// Collector writes metrics computed at scrape time, such as gauges derived
+// from registries that are updated elsewhere.
+type Collector func(w *Writer)
+
+// registry holds the registered metrics of the process.
+type registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+var defaultRegistry = &registry{families: make(map[string]*family)}
+
+// family is a metric and all its labelled series.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64 // Histograms only
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one combination of label values of a family.
+type series struct {
+	values []string
+	value  float64  // Counters and gauges
+	counts []uint64 // Histograms: observations per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// register adds a metric family. Registering a name twice is a programming
+// error and panics, as metrics are registered during package initialization.
+func (r *registry) register(name, help, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.families[name]; exists {
+		panic(fmt.Sprintf("telemetry: metric '%s' registered twice", name))
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// with returns the series for labelValues, creating it on first use. The
+// caller must hold f.mu.
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("telemetry: metric '%s' takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelSeparator)
+	s, exists := f.series[key]
+	if !exists {
+		s = &series{values: append([]string(nil), labelValues...)}
+		if f.kind == TypeHistogram {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter is a cumulative metric that only goes up.
+type Counter struct{ family *family }
+
+// NewCounter registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{defaultRegistry.register(name, help, TypeCounter, nil, labels)}
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds delta, which must not be negative, to the series with the given label values.
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.family.mu.Lock()
+	defer c.family.mu.Unlock()
+	c.family.with(labelValues).value += delta
+}
+
+// Gauge is a metric that can go up and down.
+type Gauge struct{ family *family }
+
+// NewGauge registers a gauge with the given label names.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{defaultRegistry.register(name, help, TypeGauge, nil, labels)}
+}
+
+// Set sets the series with the given label values to value.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.with(labelValues).value = value
+}
+
+// Add adds delta, which may be negative, to the series with the given label values.
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.family.mu.Lock()
+	defer g.family.mu.Unlock()
+	g.family.with(labelValues).value += delta
+}
+
+// Histogram counts observations into buckets, such as request latencies.
+type Histogram struct{ family *family }
+
+// NewHistogram registers a histogram with the given bucket upper bounds, in
+// increasing order, and label names. nil buckets use DefaultBuckets.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{defaultRegistry.register(name, help, TypeHistogram, buckets, labels)}
+}
+
+// Observe records value in the series with the given label values.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.family.mu.Lock()
+	defer h.family.mu.Unlock()
+
+	s := h.family.with(labelValues)
+	s.counts[sort.SearchFloat64s(h.family.buckets, value)]++
+	s.sum += value
+	s.count++
+}
+
+// Writer formats metrics in the Prometheus text exposition format. Write
+// errors are kept and returned by Err; later writes are skipped.
+type Writer struct {
+	out      io.Writer
+	declared map[string]bool
+	err      error
+}
+
+// NewWriter creates a writer producing text format on out.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out, declared: make(map[string]bool)}
+}
+
+// Declare writes the HELP and TYPE lines of a metric. It must precede the
+// metric's samples; declaring a metric again has no effect.
+func (w *Writer) Declare(name, kind, help string) {
+	if w.declared[name] {
+		return
+	}
+	w.declared[name] = true
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+}
+
+// Sample writes one sample of a metric. labels are alternating label names
+// and values.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	w.printf("%s%s %s\n", name, formatLabels(labels), formatValue(value))
+}
+
+// Err returns the first error encountered while writing.
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// printf writes to the output unless an earlier write failed.
+func (w *Writer) printf(format string, args ...interface{}) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.out, format, args...)
+	}
+}
+
+// writeFamily writes all series of a registered metric.
+func (w *Writer) writeFamily(f *family) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.Declare(f.name, f.kind, f.help)
+	for _, key := range keys {
+		s := f.series[key]
+		labels := make([]string, 0, 2*len(f.labels)+2)
+		for i, name := range f.labels {
+			labels = append(labels, name, s.values[i])
+		}
+
+		if f.kind != TypeHistogram {
+			w.Sample(f.name, s.value, labels...)
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			w.Sample(f.name+"_bucket", float64(cumulative), append(labels, "le", formatValue(bound))...)
+		}
+		w.Sample(f.name+"_bucket", float64(s.count), append(labels, "le", "+Inf")...)
+		w.Sample(f.name+"_sum", s.sum, labels...)
+		w.Sample(f.name+"_count", float64(s.count), labels...)
+	}
+}
+
+// WriteText writes every registered metric, followed by the output of the
+// collectors, to out.
+func WriteText(out io.Writer, collectors ...Collector) error {
+	defaultRegistry.mu.Lock()
+	families := make([]*family, 0, len(defaultRegistry.families))
+	for _, f := range defaultRegistry.families {
+		families = append(families, f)
+	}
+	defaultRegistry.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	w := NewWriter(out)
+	for _, f := range families {
+		w.writeFamily(f)
+	}
+	for _, collect := range collectors {
+		collect(w)
+	}
+	return w.Err()
+}
+
+// Handler serves the registered metrics and the collectors' output for
+// Prometheus to scrape.
+func Handler(collectors ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WriteText(w, collectors...); err != nil {
+			logging.Debug("Failed to write metrics: %v", err)
+		}
+	})
+}
+
+// StartServer serves Handler at /metrics on the specified port, for processes
+// without another HTTP server. This function blocks until the server fails.
+func StartServer(port string, collectors ...Collector) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(collectors...))
+
+	logging.Info("Metrics server starting on port %s", port)
+	return http.ListenAndServe(":"+port, mux)
+}
+
+// formatLabels formats alternating label names and values as {name="value",...}.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatValue formats a sample value as the exposition format expects.
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// escapeLabel escapes a label value.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes a HELP text.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,362 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"cares/internal/logging"
+	"cares/internal/telemetry"
+)
+
+// DefaultServiceName is the service.name spans are reported under.
+const DefaultServiceName = "cares"
+
+// OTLP export limits
+const (
+	exportQueueSize     = 4096            // Finished spans queued before new ones are dropped
+	exportBatchSize     = 512             // Spans sent in one request at most
+	exportFlushInterval = 5 * time.Second // Longest a span waits for its batch to fill up
+	exportTimeout       = 10 * time.Second
+)
+
+var (
+	spansExported = telemetry.NewCounter("cares_trace_spans_exported_total",
+		"Spans sent to the OTLP endpoint.")
+	spansDropped = telemetry.NewCounter("cares_trace_spans_dropped_total",
+		"Spans dropped because the OTLP export queue was full or the endpoint failed.")
+)
+
+// Exporter receives finished spans. Export is called synchronously by the
+// code ending a span, so it must be quick.
+type Exporter interface {
+	Export(span SpanData)
+}
+
+// Config configures where spans are exported.
+type Config struct {
+	Endpoint    string // OTLP/HTTP endpoint, e.g. "http://localhost:4318"; "" logs spans at debug level
+	ServiceName string // service.name resource attribute (DefaultServiceName if empty)
+}
+
+var (
+	exporterMu sync.RWMutex
+	exporter   Exporter = logExporter{}
+)
+
+// currentExporter returns the exporter spans are handed to.
+func currentExporter() Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+	return exporter
+}
+
+// Init sets up span export. Spans still queued for a previous OTLP endpoint
+// are flushed first.
+func Init(config Config) error {
+	var next Exporter = logExporter{}
+	if config.Endpoint != "" {
+		otlp, err := NewOTLPExporter(config.Endpoint, config.ServiceName)
+		if err != nil {
+			return err
+		}
+		next = otlp
+	}
+
+	exporterMu.Lock()
+	previous := exporter
+	exporter = next
+	exporterMu.Unlock()
+
+	if otlp, ok := previous.(*OTLPExporter); ok {
+		otlp.Shutdown()
+	}
+	return nil
+}
+
+// Shutdown sends the spans still queued for export. It is called on exit.
+func Shutdown() {
+	if otlp, ok := currentExporter().(*OTLPExporter); ok {
+		otlp.Shutdown()
+	}
+}
+
+// logExporter logs finished spans at debug level.
+type logExporter struct{}
+
+// Export logs a span.
+func (logExporter) Export(span SpanData) {
+	args := []any{
+		logging.KeyTraceID, span.Context.TraceID.String(),
+		logging.KeySpanID, span.Context.SpanID.String(),
+		"span", span.Name,
+		"duration", span.End.Sub(span.Start),
+	}
+	if span.Parent != (SpanID{}) {
+		args = append(args, "parent_id", span.Parent.String())
+	}
+	if span.Error != "" {
+		args = append(args, "error", span.Error)
+	}
+	for _, attr := range span.Attrs {
+		args = append(args, attr)
+	}
+	logging.With(args...).Debug("Span finished")
+}
+
+// OTLPExporter sends spans in batches to an OTLP/HTTP endpoint, encoded as
+// JSON. Export never blocks: when the queue is full, spans are dropped.
+type OTLPExporter struct {
+	url      string
+	resource otlpResource
+	client   *http.Client
+
+	queue    chan SpanData
+	done     chan struct{}
+	stopped  chan struct{}
+	shutdown sync.Once
+}
+
+// NewOTLPExporter creates an exporter for an OTLP/HTTP endpoint. If the
+// endpoint has no path, spans are sent to its /v1/traces.
+func NewOTLPExporter(endpoint, serviceName string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint '%s', use http(s)://host:port", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+
+	resource := otlpResource{Attributes: []otlpAttribute{otlpAttr(slog.String("service.name", serviceName))}}
+	if hostname, err := os.Hostname(); err == nil {
+		resource.Attributes = append(resource.Attributes, otlpAttr(slog.String("host.name", hostname)))
+	}
+
+	e := &OTLPExporter{
+		url:      u.String(),
+		resource: resource,
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan SpanData, exportQueueSize),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// Export queues a span for sending.
+func (e *OTLPExporter) Export(span SpanData) {
+	select {
+	case e.queue <- span:
+	default:
+		spansDropped.Inc()
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter.
+func (e *OTLPExporter) Shutdown() {
+	e.shutdown.Do(func() {
+		close(e.done)
+		<-e.stopped
+	})
+}
+
+// run sends batches until Shutdown, flushing what is left on the way out.
+func (e *OTLPExporter) run() {
+	defer close(e.stopped)
+
+	flush := time.NewTicker(exportFlushInterval)
+	defer flush.Stop()
+
+	var batch []SpanData
+	failing := false
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			spansDropped.Add(float64(len(batch)))
+			// Only the first failure of an outage is worth a warning
+			if !failing {
+				logging.Warn("Failed to export %d spans to %s: %v", len(batch), e.url, err)
+				failing = true
+			}
+		} else {
+			spansExported.Add(float64(len(batch)))
+			failing = false
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= exportBatchSize {
+				send()
+			}
+		case <-flush.C:
+			send()
+		case <-e.done:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+					if len(batch) >= exportBatchSize {
+						send()
+					}
+				default:
+					send()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send posts a batch of spans.
+func (e *OTLPExporter) send(batch []SpanData) error {
+	spans := make([]otlpSpan, len(batch))
+	for i, span := range batch {
+		spans[i] = newOTLPSpan(span)
+	}
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   e.resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "cares"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of ExportTraceServiceRequest. IDs are hex
+// strings and 64-bit integers are decimal strings, as the encoding requires.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"` // 0 unset, 2 error
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+// newOTLPSpan converts a finished span.
+func newOTLPSpan(span SpanData) otlpSpan {
+	converted := otlpSpan{
+		TraceID:           span.Context.TraceID.String(),
+		SpanID:            span.Context.SpanID.String(),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+	}
+	if span.Parent != (SpanID{}) {
+		converted.ParentSpanID = span.Parent.String()
+	}
+	if span.Error != "" {
+		converted.Status = otlpStatus{Code: 2, Message: span.Error}
+	}
+	for _, attr := range span.Attrs {
+		converted.Attributes = append(converted.Attributes, otlpAttr(attr))
+	}
+	return converted
+}
+
+// otlpAttr converts an attribute, keeping booleans and numbers typed.
+func otlpAttr(attr slog.Attr) otlpAttribute {
+	value := attr.Value.Resolve()
+	converted := otlpAttribute{Key: attr.Key}
+	switch value.Kind() {
+	case slog.KindBool:
+		b := value.Bool()
+		converted.Value.BoolValue = &b
+	case slog.KindInt64:
+		i := strconv.FormatInt(value.Int64(), 10)
+		converted.Value.IntValue = &i
+	case slog.KindUint64:
+		i := strconv.FormatUint(value.Uint64(), 10)
+		converted.Value.IntValue = &i
+	case slog.KindFloat64:
+		f := value.Float64()
+		converted.Value.DoubleValue = &f
+	case slog.KindDuration:
+		i := strconv.FormatInt(value.Duration().Milliseconds(), 10)
+		converted.Value.IntValue = &i
+	default:
+		s := value.String()
+		converted.Value.StringValue = &s
+	}
+	return converted
+}
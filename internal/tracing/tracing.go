@@ -0,0 +1,265 @@
+// Package tracing records spans of the work done for a request, so a slow
+// invocation can be broken down into its HTTP handling, scheduling, gRPC call,
+// image pull and run. Spans travel in a context.Context; Start opens a child
+// of the span in the context, or a new trace if there is none. Traces cross
+// process boundaries as W3C trace context (the traceparent header), which the
+// API reads from HTTP requests and the cluster package sends in gRPC metadata.
+//
+// Finished spans are handed to the exporter set with Init: by default they are
+// logged at debug level, with an OTLP endpoint they are sent to a collector
+// such as Jaeger or the OpenTelemetry Collector. Every span is recorded; there
+// is no sampling.
+//
+// Records logged with a context carrying a span are tagged with its trace_id
+// and span_id (see logging.SetContextFields).
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"cares/internal/logging"
+)
+
+// TraceparentHeader is the W3C trace context header, also used as gRPC
+// metadata key.
+const TraceparentHeader = "traceparent"
+
+// Kind is the role of a span in a request, numbered as in OTLP.
+type Kind int
+
+// Span kinds
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// String returns the ID as 32 lowercase hex digits.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// String returns the ID as 16 lowercase hex digits.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext identifies a span, possibly one in another process.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+// ParseTraceparent parses a traceparent header value. It reports false for
+// malformed values and all-zero IDs, which the specification declares invalid.
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	return sc, sc.IsValid()
+}
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID // Zero for the root span of a trace
+	Start, End time.Time
+	Attrs      []slog.Attr
+	Error      string // Set if the span failed
+}
+
+// Span is an operation being timed. All methods may be called on a nil *Span,
+// doing nothing, and are safe for concurrent use.
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start opens an internal span named name as a child of the span in ctx,
+// returning a context carrying it. The span must be ended with End.
+func Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal, attrs...)
+}
+
+// StartKind opens a span of the given kind like Start.
+func StartKind(ctx context.Context, name string, kind Kind, attrs ...slog.Attr) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{data: SpanData{
+		Name:    name,
+		Kind:    kind,
+		Context: SpanContext{TraceID: parent.TraceID, SpanID: newSpanID()},
+		Parent:  parent.SpanID,
+		Start:   time.Now(),
+		Attrs:   attrs,
+	}}
+	if !parent.IsValid() {
+		span.data.Context.TraceID = newTraceID()
+		span.data.Parent = SpanID{}
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span in ctx, nil if there is none.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// WithRemote returns a context whose spans continue the trace of a span in
+// another process, e.g. one received in a traceparent header.
+func WithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the context of the span in ctx, or of the
+// remote parent set with WithRemote. It is invalid if there is neither.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := FromContext(ctx); span != nil {
+		return span.Context()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// TraceIDFromContext returns the ID of the trace in ctx, "" if there is none.
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID.String()
+	}
+	return ""
+}
+
+// Context returns the IDs of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context // Immutable after Start
+}
+
+// TraceID returns the ID of the span's trace, "" for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.data.Context.TraceID.String()
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attrs = append(s.data.Attrs, attrs...)
+}
+
+// RecordError marks the span as failed with err, if err is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+// SetError marks the span as failed with a message.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = message
+}
+
+// End finishes the span and exports it. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	data.Attrs = append([]slog.Attr(nil), s.data.Attrs...)
+	s.mu.Unlock()
+
+	currentExporter().Export(data)
+}
+
+// newTraceID returns a random, valid trace ID.
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		for i := 0; i < len(id); i += 8 {
+			binary.LittleEndian.PutUint64(id[i:], rand.Uint64())
+		}
+	}
+	return id
+}
+
+// newSpanID returns a random, valid span ID.
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		binary.LittleEndian.PutUint64(id[:], rand.Uint64())
+	}
+	return id
+}
+
+func init() {
+	logging.SetContextFields(func(ctx context.Context) []slog.Attr {
+		sc := SpanContextFromContext(ctx)
+		if !sc.IsValid() {
+			return nil
+		}
+		return []slog.Attr{
+			slog.String(logging.KeyTraceID, sc.TraceID.String()),
+			slog.String(logging.KeySpanID, sc.SpanID.String()),
+		}
+	})
+}
@@ -2,15 +2,79 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"cares/internal/api"
 	"cares/internal/cluster"
+	"cares/internal/credentials"
+	"cares/internal/distribution"
+	"cares/internal/events"
+	"cares/internal/executor"
 	"cares/internal/functions"
+	"cares/internal/invocations"
+	"cares/internal/logstore"
 	"cares/internal/logging"
+	"cares/internal/telemetry"
+	"cares/internal/webhooks"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// startWebhooks opens the webhook subscriptions and dead letters and starts
+// delivering the cluster's events to them
+func startWebhooks() (*webhooks.Dispatcher, error) {
+	store, err := webhooks.OpenDefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	deadLetters, err := webhooks.OpenDeadLetters(webhooks.DefaultDeadLetterPath, webhooks.DefaultDeadLetterMemory)
+	if err != nil {
+		return nil, err
+	}
+	dispatcher := webhooks.NewDispatcher(store, deadLetters, webhooks.DefaultRetryPolicy)
+	dispatcher.Start(events.Default())
+	return dispatcher, nil
+}
+
+// maxOutputBytesVariable caps the output of an invocation, in bytes; workers
+// use executor.DefaultMaxOutputBytes if it is unset
+const maxOutputBytesVariable = "CARES_MAX_OUTPUT_BYTES"
+
+// maxOutputBytes returns the output cap set with CARES_MAX_OUTPUT_BYTES, 0 to
+// leave it to the workers
+func maxOutputBytes() (int64, error) {
+	value := os.Getenv(maxOutputBytesVariable)
+	if value == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of bytes, got '%s'", maxOutputBytesVariable, value)
+	}
+	return limit, nil
+}
+
+// stopWebhooks stops delivering events, recording undelivered ones as dead letters
+func (m *Model) stopWebhooks() {
+	if m.Webhooks == nil {
+		return
+	}
+	m.Webhooks.Stop()
+	if err := m.Webhooks.DeadLetters().Close(); err != nil {
+		logging.Warn("Failed to close webhook dead letters: %v", err)
+	}
+}
+
+// tuiContext returns the context of changes made from the TUI, attributing
+// them to the TUI in the audit log
+func tuiContext() context.Context {
+	return events.WithActor(context.Background(), events.ActorTUI)
+}
+
 // handleSelectionKeys processes key input during mode selection screen
 func (m *Model) handleSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -111,17 +175,100 @@ func (m *Model) handleWorkerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // startOrchestratorMode initializes the gRPC server and switches to orchestrator mode
 func (m *Model) startOrchestratorMode() (tea.Model, tea.Cmd) {
+	// Open the function store and load the registry before starting any servers
+	store, err := functions.OpenDefaultStore()
+	if err != nil {
+		// TODO: Show error message in UI
+		logging.Error("Failed to open function store: %v", err)
+		return m, nil
+	}
+	functionRegistry, err := functions.NewRegistry(store)
+	if err != nil {
+		logging.Error("Failed to load function registry: %v", err)
+		store.Close()
+		return m, nil
+	}
+	history, err := invocations.OpenHistory(invocations.DefaultHistoryPath, invocations.DefaultRetention)
+	if err != nil {
+		logging.Error("Failed to open invocation history: %v", err)
+		functionRegistry.Close()
+		return m, nil
+	}
+	logStore, err := logstore.Open(logstore.DefaultPath, logstore.DefaultRetention)
+	if err != nil {
+		logging.Error("Failed to open log store: %v", err)
+		history.Close()
+		functionRegistry.Close()
+		return m, nil
+	}
+	auditLog, err := events.OpenAuditLog(events.DefaultAuditPath, events.DefaultAuditMemory)
+	if err != nil {
+		logging.Error("Failed to open audit log: %v", err)
+		logStore.Close()
+		history.Close()
+		functionRegistry.Close()
+		return m, nil
+	}
+	
+	// Record every cluster event from here on and serve them at GET /events
+	m.AuditLog = auditLog
+	events.Default().SetAuditLog(m.AuditLog)
+	
 	// Create gRPC server
 	m.GrpcServer = cluster.NewServer()
 	m.NodeRegistry = m.GrpcServer.GetRegistry()
 	
 	// Create function registry and API server
-	m.FunctionRegistry = functions.NewRegistry()
+	m.FunctionRegistry = functionRegistry
 	m.ApiServer = api.NewServer(m.FunctionRegistry)
 	
 	// Connect API server to node registry for function execution
 	m.ApiServer.SetNodeRegistry(m.NodeRegistry)
 	
+	// Record every invocation so it shows up in the History view
+	m.InvocationHistory = history
+	m.ApiServer.SetHistory(m.InvocationHistory)
+	
+	// Cap invocation output, which workers otherwise do at their default
+	if limit, err := maxOutputBytes(); err != nil {
+		logging.Error("Ignoring output limit: %v", err)
+	} else if limit > 0 {
+		m.ApiServer.SetMaxOutputBytes(limit)
+	}
+	
+	// Warm worker image caches as soon as container functions are registered
+	m.ApiServer.SetImagePuller(m.GrpcServer)
+	
+	// Let PUT /log-level reach workers over their heartbeat streams
+	m.ApiServer.SetLogLevelSetter(m.GrpcServer)
+	
+	// Keep the logs workers ship and serve them at GET /logs
+	m.LogStore = logStore
+	m.GrpcServer.SetLogStore(m.LogStore)
+	m.ApiServer.SetLogStore(m.LogStore)
+	
+	// Private registry credentials are optional; without the store only
+	// public images can be pulled
+	if credentialStore, err := credentials.OpenDefaultStore(); err != nil {
+		logging.Error("Failed to open credential store: %v", err)
+	} else {
+		m.ApiServer.SetCredentials(credentialStore)
+	}
+	
+	// Webhooks are optional too; without them events are only audited
+	if dispatcher, err := startWebhooks(); err != nil {
+		logging.Error("Failed to start webhooks: %v", err)
+	} else {
+		m.Webhooks = dispatcher
+		m.ApiServer.SetWebhooks(m.Webhooks)
+	}
+	
+	// Pin container versions to the digest their tag resolves to when published
+	m.ApiServer.SetDigestResolver(distribution.NewClient())
+	
+	// Serve cluster state next to the API's own metrics at /metrics
+	m.ApiServer.AddMetricsCollector(m.GrpcServer.CollectMetrics)
+	
 	// Switch to sidebar mode for Phase 3
 	m.Mode = ModeOrchestratorSidebar
 	m.SidebarSelected = 0  // Start with "Logs" selected
@@ -164,12 +311,23 @@ func (m *Model) startWorkerMode() (tea.Model, tea.Cmd) {
 	
 	// Create and start worker's own gRPC server for receiving function execution requests
 	m.WorkerGrpcServer = cluster.NewServer()
+	m.WorkerGrpcServer.SetNodeID(m.GrpcClient.GetNodeID())
 	go func() {
 		if err := m.WorkerGrpcServer.StartServer("50052"); err != nil {
 			logging.Error("Worker gRPC server error: %v", err)
 		}
 	}()
 	
+	// Probe the container runtime in the background; heartbeats report the result
+	executor.StartHealthMonitor(context.Background(), executor.DefaultHealthInterval)
+	
+	// Workers have no REST API, so their metrics get a listener of their own
+	go func() {
+		if err := telemetry.StartServer("9102", m.GrpcClient.CollectMetrics); err != nil {
+			logging.Error("Worker metrics server error: %v", err)
+		}
+	}()
+	
 	// Start heartbeat in background
 	go func() {
 		ctx := context.Background()
@@ -178,6 +336,13 @@ func (m *Model) startWorkerMode() (tea.Model, tea.Cmd) {
 		}
 	}()
 	
+	// Ship everything this worker logs to the orchestrator
+	go func() {
+		if err := m.GrpcClient.StartLogShipping(context.Background()); err != nil {
+			logging.Error("Log shipping error: %v", err)
+		}
+	}()
+	
 	// Start local metrics collection (same as Phase 01)
 	return m, m.tickCmd()
 }
@@ -194,9 +359,24 @@ func (m *Model) handleOrchestratorSidebarKeys(msg tea.KeyMsg) (tea.Model, tea.Cm
 		return m.handleFunctionFormKeys(msg)
 	}
 	
+	// Handle function edit form input if form is open
+	if m.ShowFunctionEditForm {
+		return m.handleFunctionEditFormKeys(msg)
+	}
+	
+	// Handle log search input while it is being typed
+	if m.LogSearchInput {
+		return m.handleLogSearchKeys(msg)
+	}
+	
 	switch msg.String() {
 	case "up", "k":
-		if m.FunctionTableFocused && m.SidebarSelected == 2 {
+		if m.NodeDetailOpen && m.SidebarSelected == 0 {
+			// The detail view shows a single node
+		} else if m.LogsFocused && m.SidebarSelected == 1 {
+			// Scroll back through the log feed
+			m.scrollLogs(1)
+		} else if m.FunctionTableFocused && m.SidebarSelected == 2 {
 			// Navigate functions table
 			if m.FunctionSelectedIndex > 0 {
 				m.FunctionSelectedIndex--
@@ -206,11 +386,21 @@ func (m *Model) handleOrchestratorSidebarKeys(msg tea.KeyMsg) (tea.Model, tea.Cm
 			if m.NodeSelectedIndex > 0 {
 				m.NodeSelectedIndex--
 			}
+		} else if m.HistoryTableFocused && m.SidebarSelected == 4 {
+			// Navigate invocation history (towards newer entries)
+			if m.HistorySelectedIndex > 0 {
+				m.HistorySelectedIndex--
+			}
 		} else if m.SidebarSelected > 0 {
 			m.SidebarSelected--
 		}
 	case "down", "j":
-		if m.FunctionTableFocused && m.SidebarSelected == 2 {
+		if m.NodeDetailOpen && m.SidebarSelected == 0 {
+			// The detail view shows a single node
+		} else if m.LogsFocused && m.SidebarSelected == 1 {
+			// Scroll towards the newest log entries
+			m.scrollLogs(-1)
+		} else if m.FunctionTableFocused && m.SidebarSelected == 2 {
 			// Navigate functions table
 			functions := m.FunctionRegistry.GetAllFunctions()
 			if m.FunctionSelectedIndex < len(functions)-1 {
@@ -224,8 +414,14 @@ func (m *Model) handleOrchestratorSidebarKeys(msg tea.KeyMsg) (tea.Model, tea.Cm
 					m.NodeSelectedIndex++
 				}
 			}
+		} else if m.HistoryTableFocused && m.SidebarSelected == 4 {
+			// Navigate invocation history (towards older entries)
+			if m.InvocationHistory != nil && m.HistorySelectedIndex < historyPanelRows-1 &&
+				m.HistorySelectedIndex < m.InvocationHistory.Count()-1 {
+				m.HistorySelectedIndex++
+			}
 		} else {
-			maxItems := 4 // logs, orchestrator, functions, add-function
+			maxItems := 5 // orchestrator, logs, functions, add-function, history
 			if m.SidebarSelected < maxItems-1 {
 				m.SidebarSelected++
 			}
@@ -233,10 +429,19 @@ func (m *Model) handleOrchestratorSidebarKeys(msg tea.KeyMsg) (tea.Model, tea.Cm
 	case "enter", " ":
 		switch m.SidebarSelected {
 		case 0: // Orchestrator - now first/default
-			// Enter key focuses into the nodes table for navigation
+			// Enter key focuses into the nodes table for navigation,
+			// and on a selected node opens its detail view
+			if m.NodeTableFocused && !m.NodeDetailOpen && m.NodeRegistry != nil {
+				nodes := m.NodeRegistry.GetAllNodes()
+				if m.NodeSelectedIndex < len(nodes) {
+					m.NodeDetailOpen = true
+					m.NodeDetailID = nodes[m.NodeSelectedIndex].ID
+				}
+			}
 			m.NodeTableFocused = true
 		case 1: // Logs
-			// Just selection change, content will update automatically
+			// Enter key focuses into the log feed for browsing
+			m.LogsFocused = true
 		case 2: // Functions
 			// Enter key focuses into the functions table for navigation
 			m.FunctionTableFocused = true
@@ -247,32 +452,132 @@ func (m *Model) handleOrchestratorSidebarKeys(msg tea.KeyMsg) (tea.Model, tea.Cm
 			m.FunctionFormImage = ""
 			m.FunctionFormDesc = ""
 			m.FunctionFormField = 0
+		case 4: // History
+			// Enter key focuses into the history table for navigation
+			m.HistoryTableFocused = true
+			m.HistorySelectedIndex = 0
+		}
+	case "e":
+		if m.FunctionTableFocused && m.SidebarSelected == 2 {
+			// Open edit form for the selected function
+			m.openFunctionEditForm()
+		}
+	case "t":
+		if m.FunctionTableFocused && m.SidebarSelected == 2 {
+			// Toggle enabled/disabled for the selected function
+			m.toggleSelectedFunctionStatus()
+		}
+	case "r":
+		if m.NodeDetailOpen && m.SidebarSelected == 0 {
+			// Cycle the range of the node's metric history
+			m.NodeDetailRange = (m.NodeDetailRange + 1) % len(nodeDetailRanges)
+		}
+	case "pgup", "pgdown":
+		if m.LogsFocused && m.SidebarSelected == 1 {
+			if msg.String() == "pgup" {
+				m.scrollLogs(logPageSize)
+			} else {
+				m.scrollLogs(-logPageSize)
+			}
+		}
+	case "l":
+		if m.LogsFocused && m.SidebarSelected == 1 {
+			// Cycle the minimum level shown
+			m.LogLevelFilter = (m.LogLevelFilter + 1) % len(logLevelFilters)
+			m.LogScroll = 0
+		}
+	case "/":
+		if m.LogsFocused && m.SidebarSelected == 1 {
+			// Start typing a search; an empty search shows everything
+			m.LogSearchInput = true
+			m.LogSearch = ""
+		}
+	case "p":
+		if m.LogsFocused && m.SidebarSelected == 1 {
+			// Toggle between holding the feed still and following new entries
+			if m.LogPaused {
+				m.followLogs()
+			} else {
+				m.pauseLogs()
+			}
+		}
+	case "f", "end":
+		if m.LogsFocused && m.SidebarSelected == 1 {
+			// Jump to the newest entry and follow new ones
+			m.followLogs()
 		}
 	case "esc":
-		if m.FunctionTableFocused {
+		if m.NodeDetailOpen {
+			// Close the node detail view, back to the table
+			m.NodeDetailOpen = false
+			m.NodeDetailID = ""
+		} else if m.FunctionTableFocused {
 			// Exit function table navigation
 			m.FunctionTableFocused = false
 		} else if m.NodeTableFocused {
 			// Exit node table navigation
 			m.NodeTableFocused = false
+		} else if m.HistoryTableFocused {
+			// Exit history table navigation
+			m.HistoryTableFocused = false
+		} else if m.LogsFocused {
+			// Stop browsing the log feed; the filters stay in place
+			m.LogsFocused = false
 		} else {
 			// Return to mode selection menu
 			// Cleanup orchestrator mode
 			if m.GrpcServer != nil {
 				// TODO: Properly stop the servers in Phase 03+
 			}
+			if m.FunctionRegistry != nil {
+				// Release the store so it can be reopened on the next start
+				if err := m.FunctionRegistry.Close(); err != nil {
+					logging.Warn("Failed to close function store: %v", err)
+				}
+			}
+			if m.InvocationHistory != nil {
+				if err := m.InvocationHistory.Close(); err != nil {
+					logging.Warn("Failed to close invocation history: %v", err)
+				}
+			}
+			if m.LogStore != nil {
+				if err := m.LogStore.Close(); err != nil {
+					logging.Warn("Failed to close log store: %v", err)
+				}
+			}
+			m.stopWebhooks()
+			if m.AuditLog != nil {
+				events.Default().SetAuditLog(nil)
+				if err := m.AuditLog.Close(); err != nil {
+					logging.Warn("Failed to close audit log: %v", err)
+				}
+			}
 			m.Mode = ModeSelection
 			m.GrpcServer = nil
 			m.NodeRegistry = nil
 			m.ApiServer = nil
 			m.FunctionRegistry = nil
+			m.InvocationHistory = nil
+			m.LogStore = nil
+			m.AuditLog = nil
+			m.Webhooks = nil
 			m.NodeScrollOffset = 0
 			m.SidebarSelected = 0
 			m.ShowFunctionForm = false
+			m.closeFunctionEditForm()
 			m.FunctionTableFocused = false
 			m.FunctionSelectedIndex = 0
 			m.NodeTableFocused = false
 			m.NodeSelectedIndex = 0
+			m.NodeDetailOpen = false
+			m.NodeDetailID = ""
+			m.NodeDetailRange = 0
+			m.LogsFocused = false
+			m.LogSearch = ""
+			m.LogScroll = 0
+			m.LogPaused = false
+			m.HistoryTableFocused = false
+			m.HistorySelectedIndex = 0
 		}
 	}
 	
@@ -343,6 +648,154 @@ func (m *Model) handleFunctionFormKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// openFunctionEditForm loads the selected function into the edit form
+func (m *Model) openFunctionEditForm() {
+	if m.FunctionRegistry == nil {
+		return
+	}
+	functions := m.FunctionRegistry.GetAllFunctions()
+	if m.FunctionSelectedIndex >= len(functions) {
+		return
+	}
+	fn := functions[m.FunctionSelectedIndex]
+	
+	m.ShowFunctionEditForm = true
+	m.EditFunctionID = fn.ID
+	m.EditFunctionRevision = fn.Revision
+	m.EditFormImage = fn.Image
+	m.EditFormDesc = fn.Description
+	m.EditFormTimeout = strconv.Itoa(fn.Settings.TimeoutSeconds)
+	m.EditFormMemory = strconv.Itoa(fn.Settings.MemoryMB)
+	m.EditFormField = 0
+	m.EditFormError = ""
+}
+
+// toggleSelectedFunctionStatus flips the manual status of the selected function
+func (m *Model) toggleSelectedFunctionStatus() {
+	if m.FunctionRegistry == nil {
+		return
+	}
+	functionList := m.FunctionRegistry.GetAllFunctions()
+	if m.FunctionSelectedIndex >= len(functionList) {
+		return
+	}
+	fn := functionList[m.FunctionSelectedIndex]
+	
+	status := functions.StatusInactive
+	if fn.Status != functions.StatusActive {
+		status = functions.StatusActive
+	}
+	if _, err := m.FunctionRegistry.UpdateFunctionStatus(tuiContext(), fn.ID, status); err != nil {
+		logging.Error("Failed to set function '%s' to %s: %v", fn.Name, status, err)
+		return
+	}
+	logging.Info("Function '%s' set to %s from TUI", fn.Name, status)
+}
+
+// closeFunctionEditForm closes the edit form and clears its fields
+func (m *Model) closeFunctionEditForm() {
+	m.ShowFunctionEditForm = false
+	m.EditFunctionID = ""
+	m.EditFunctionRevision = 0
+	m.EditFormImage = ""
+	m.EditFormDesc = ""
+	m.EditFormTimeout = ""
+	m.EditFormMemory = ""
+	m.EditFormField = 0
+	m.EditFormError = ""
+}
+
+// handleFunctionEditFormKeys processes key input in the function edit form
+func (m *Model) handleFunctionEditFormKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Pointers to the editable fields in form order, with their length limits
+	fields := []*string{&m.EditFormImage, &m.EditFormDesc, &m.EditFormTimeout, &m.EditFormMemory}
+	limits := []int{100, 200, 5, 6}
+	
+	switch msg.String() {
+	case "esc":
+		m.closeFunctionEditForm()
+	case "tab", "down":
+		if m.EditFormField < len(fields)-1 {
+			m.EditFormField++
+		}
+	case "shift+tab", "up":
+		if m.EditFormField > 0 {
+			m.EditFormField--
+		}
+	case "enter":
+		m.submitFunctionEditForm()
+	case "backspace":
+		field := fields[m.EditFormField]
+		if len(*field) > 0 {
+			*field = (*field)[:len(*field)-1]
+		}
+	default:
+		field := fields[m.EditFormField]
+		if len(msg.String()) == 1 && len(*field) < limits[m.EditFormField] {
+			*field += msg.String()
+		}
+	}
+	
+	return m, nil
+}
+
+// submitFunctionEditForm validates the edit form and applies it to the registry
+func (m *Model) submitFunctionEditForm() {
+	if m.FunctionRegistry == nil {
+		return
+	}
+	
+	timeout, err := strconv.Atoi(m.EditFormTimeout)
+	if err != nil {
+		m.EditFormError = "TIMEOUT MUST BE A NUMBER OF SECONDS"
+		return
+	}
+	memory, err := strconv.Atoi(m.EditFormMemory)
+	if err != nil {
+		m.EditFormError = "MEMORY MUST BE A NUMBER OF MEGABYTES"
+		return
+	}
+	
+	update := functions.FunctionUpdate{
+		Image:          &m.EditFormImage,
+		Description:    &m.EditFormDesc,
+		TimeoutSeconds: &timeout,
+		MemoryMB:       &memory,
+	}
+	
+	previous, _ := m.FunctionRegistry.GetFunction(m.EditFunctionID)
+	fn, err := m.FunctionRegistry.UpdateFunction(tuiContext(), m.EditFunctionID, m.EditFunctionRevision, update)
+	if err != nil {
+		var validationErr *functions.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			field := validationErr.Fields[0]
+			m.EditFormError = strings.ToUpper(fmt.Sprintf("%s %s", field.Field, field.Message))
+		case errors.Is(err, functions.ErrRevisionConflict):
+			m.EditFormError = "FUNCTION CHANGED ELSEWHERE - ESC AND REOPEN TO RELOAD"
+		default:
+			m.EditFormError = strings.ToUpper(err.Error())
+		}
+		return
+	}
+	
+	logging.Info("Updated function '%s' to revision %d from TUI", fn.Name, fn.Revision)
+	if previous == nil || previous.Image != fn.Image {
+		m.prepareLatestVersion(fn)
+	}
+	m.closeFunctionEditForm()
+}
+
+// prepareLatestVersion pins and pre-pulls the latest version of a function
+// like the API does for the versions it publishes. Pinning asks the registry,
+// which must not block the UI.
+func (m *Model) prepareLatestVersion(function *functions.Function) {
+	if m.ApiServer == nil {
+		return
+	}
+	go m.ApiServer.PrepareLatestVersion(tuiContext(), function)
+}
+
 // validateAndShowConfirmModal validates the function form before showing confirmation
 func (m *Model) validateAndShowConfirmModal() (tea.Model, tea.Cmd) {
 	// First set the confirm fields so they can be displayed in the modal
@@ -369,11 +822,12 @@ func (m *Model) handleFunctionConfirmModalKeys(msg tea.KeyMsg) (tea.Model, tea.C
 		
 		// Add function directly to registry
 		if m.FunctionRegistry != nil {
-			_, err := m.FunctionRegistry.AddFunction(m.FunctionConfirmName, m.FunctionFormImage, m.FunctionFormDesc)
+			function, err := m.FunctionRegistry.AddFunction(tuiContext(), m.FunctionConfirmName, m.FunctionFormImage, m.FunctionFormDesc)
 			if err != nil {
 				// TODO: Show error message in UI
 				logging.Error("Failed to add function: %v", err)
 			} else {
+				m.prepareLatestVersion(function)
 				// Success - close form and reset fields
 				m.ShowFunctionForm = false
 				m.FunctionFormName = ""
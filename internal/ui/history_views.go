@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"cares/internal/invocations"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// historyPanelRows is the number of invocations shown in the history table
+const historyPanelRows = 10
+
+// getHistoryContent returns the invocation history panel for the right panel
+func (m Model) getHistoryContent(contentWidth int) string {
+	if m.InvocationHistory == nil {
+		return "Invocation history not initialized"
+	}
+
+	records := m.InvocationHistory.Recent(historyPanelRows)
+
+	// Styling
+	titleStyle := lipgloss.NewStyle().Bold(true).Underline(true)
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	tooltipStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+	selectedRowStyle := lipgloss.NewStyle().Reverse(true)
+	failedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	if len(records) == 0 {
+		return strings.Join([]string{
+			titleStyle.Render("INVOCATION HISTORY"),
+			"",
+			"NO INVOCATIONS RECORDED YET",
+			"",
+			tooltipStyle.Render("→ Invoke a function with POST /invoke/{name}"),
+			tooltipStyle.Render("→ Query the full history with GET /invocations"),
+		}, "\n")
+	}
+
+	selectedIndex := m.HistorySelectedIndex
+	if selectedIndex >= len(records) {
+		selectedIndex = 0
+	}
+	selected := records[selectedIndex]
+
+	// Selected invocation details
+	lines := []string{
+		titleStyle.Render("INVOCATION HISTORY"),
+		"",
+		fmt.Sprintf("%s %s v%d", labelStyle.Render("FUNCTION:"), selected.FunctionName, selected.Version),
+		fmt.Sprintf("%s %s", labelStyle.Render("NODE:"), getOrDefault(selected.NodeID, "not scheduled")),
+		fmt.Sprintf("%s %s → %s (%dms)", labelStyle.Render("TIME:"),
+			selected.StartedAt.Format("2006-01-02 15:04:05"), selected.FinishedAt.Format("15:04:05"), selected.DurationMs),
+		fmt.Sprintf("%s %s", labelStyle.Render("STATUS:"), historyStatusLabel(selected)),
+	}
+	if selected.Error != "" {
+		lines = append(lines, failedStyle.Render(fmt.Sprintf("→ Error: %s", truncateLine(selected.Error, contentWidth-12))))
+	}
+	output := firstLine(selected.Output)
+	if selected.OutputTruncated || strings.Contains(strings.TrimSpace(selected.Output), "\n") {
+		output += " ..."
+	}
+	lines = append(lines,
+		tooltipStyle.Render(fmt.Sprintf("→ Output: %s", truncateLine(getOrDefault(output, "(none)"), contentWidth-12))),
+		"",
+		labelStyle.Render(fmt.Sprintf("RECENT INVOCATIONS (%d RETAINED) - PRESS ENTER TO NAVIGATE", m.InvocationHistory.Count())),
+		"",
+	)
+
+	// Calculate column widths (5 columns: Time, Function, Node, Duration, Status)
+	tableWidth := contentWidth - 4
+	if tableWidth < 50 {
+		tableWidth = 50
+	}
+	timeWidth := 21
+	durationWidth := 10
+	statusWidth := 9
+	functionWidth := (tableWidth - timeWidth - durationWidth - statusWidth - 6) / 2
+	nodeWidth := tableWidth - timeWidth - durationWidth - statusWidth - functionWidth - 6
+	if functionWidth < 8 {
+		functionWidth = 8
+	}
+	if nodeWidth < 8 {
+		nodeWidth = 8
+	}
+
+	widths := []int{timeWidth, functionWidth, nodeWidth, durationWidth, statusWidth}
+	border := func(left, mid, right string) string {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat("─", width)
+		}
+		return left + strings.Join(parts, mid) + right
+	}
+	row := func(cells ...string) string {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = fmt.Sprintf(" %-*s ", width-2, truncateLine(cells[i], width-2))
+		}
+		return "│" + strings.Join(parts, "│") + "│"
+	}
+
+	lines = append(lines,
+		border("┌", "┬", "┐"),
+		row("STARTED", "FUNCTION", "NODE", "DURATION", "STATUS"),
+		border("├", "┼", "┤"),
+	)
+	for i := 0; i < historyPanelRows; i++ {
+		if i >= len(records) {
+			lines = append(lines, row("", "", "", "", ""))
+			continue
+		}
+
+		record := records[i]
+		line := row(
+			record.StartedAt.Format("01-02 15:04:05"),
+			fmt.Sprintf("%s v%d", record.FunctionName, record.Version),
+			getOrDefault(record.NodeID, "-"),
+			fmt.Sprintf("%dms", record.DurationMs),
+			strings.ToUpper(record.Status),
+		)
+		if i == selectedIndex && m.HistoryTableFocused {
+			line = selectedRowStyle.Render(line)
+		} else if record.Status == invocations.StatusFailed {
+			line = failedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, border("└", "┴", "┘"),
+		"",
+		tooltipStyle.Render(func() string {
+			if m.HistoryTableFocused {
+				return fmt.Sprintf("→ Invocation %d of %d | ↑↓: Navigate | ESC: Exit table", selectedIndex+1, len(records))
+			}
+			return fmt.Sprintf("→ Showing %d most recent | ENTER: Navigate table", len(records))
+		}()),
+	)
+
+	return strings.Join(lines, "\n")
+}
+
+// historyStatusLabel returns the outcome of an invocation, with its exit code when known
+func historyStatusLabel(record invocations.Record) string {
+	label := strings.ToUpper(record.Status)
+	if record.ExitCode != nil {
+		label += fmt.Sprintf(" (exit %d)", *record.ExitCode)
+	}
+	return label
+}
+
+// truncateLine shortens s to at most width characters, marking the cut with "..."
+func truncateLine(s string, width int) string {
+	if width < 4 {
+		width = 4
+	}
+	if len(s) <= width {
+		return s
+	}
+	return s[:width-3] + "..."
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
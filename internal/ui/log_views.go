@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"cares/internal/logging"
+)
+
+// logLevelFilters are the minimum levels the Logs view cycles through.
+var logLevelFilters = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// logPageSize is how many lines page up and page down scroll the Logs view.
+const logPageSize = 10
+
+// filteredLogs returns the entries the Logs view shows: those at or above the
+// level filter that match the search, up to the pause point if paused.
+func (m Model) filteredLogs() []logging.Entry {
+	minLevel := logLevelFilters[m.LogLevelFilter%len(logLevelFilters)]
+	search := strings.ToLower(m.LogSearch)
+
+	var entries []logging.Entry
+	for _, entry := range logging.Recent().Since(0) {
+		if m.LogPaused && entry.Seq > m.LogPausedSeq {
+			break
+		}
+		if entry.Level < minLevel {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(entry.NodeID+" "+entry.String()), search) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// pauseLogs freezes the Logs view at the latest entry, so scrolling back is
+// not disturbed by new entries.
+func (m *Model) pauseLogs() {
+	if !m.LogPaused {
+		m.LogPaused = true
+		m.LogPausedSeq = logging.Recent().LastSeq()
+	}
+}
+
+// followLogs resumes showing new entries as they are logged.
+func (m *Model) followLogs() {
+	m.LogPaused = false
+	m.LogScroll = 0
+}
+
+// scrollLogs scrolls the Logs view back (positive lines) or forward, pausing
+// it while scrolled back.
+func (m *Model) scrollLogs(lines int) {
+	if lines > 0 {
+		m.pauseLogs()
+	}
+	m.LogScroll += lines
+	if limit := len(m.filteredLogs()) - 1; m.LogScroll > limit {
+		m.LogScroll = max(limit, 0)
+	}
+	if m.LogScroll < 0 {
+		m.LogScroll = 0
+	}
+}
+
+// getLogsContent returns the live log feed for the right panel
+func (m Model) getLogsContent(contentWidth int, availableHeight int) string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	tooltipStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+	timestampStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	nodeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("141"))
+	levelStyles := map[slog.Level]lipgloss.Style{
+		slog.LevelDebug: lipgloss.NewStyle().Foreground(lipgloss.Color("245")),
+		slog.LevelInfo:  lipgloss.NewStyle().Foreground(lipgloss.Color("51")),  // Neon cyan
+		slog.LevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("226")), // Neon yellow
+		slog.LevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("196")), // Red
+	}
+
+	// Title, status, blank line, container borders, blank line and help
+	maxRows := availableHeight - 13
+	if maxRows < 5 {
+		maxRows = 5
+	}
+	lineWidth := contentWidth - 8 // Container border and padding
+	if lineWidth < 20 {
+		lineWidth = 20
+	}
+
+	entries := m.filteredLogs()
+	scroll := min(m.LogScroll, max(len(entries)-1, 0))
+	end := len(entries) - scroll
+	start := max(end-maxRows, 0)
+
+	state := labelStyle.Render("FOLLOWING")
+	if m.LogPaused {
+		state = labelStyle.Render("PAUSED")
+	}
+	status := fmt.Sprintf("%s   LEVEL ≥ %s   %d ENTRIES",
+		state, strings.ToUpper(logging.LevelName(logLevelFilters[m.LogLevelFilter%len(logLevelFilters)])), len(entries))
+	if m.LogSearchInput {
+		status += "   SEARCH: " + m.LogSearch + "█"
+	} else if m.LogSearch != "" {
+		status += "   SEARCH: " + m.LogSearch
+	}
+	if scroll > 0 {
+		status += fmt.Sprintf("   ↑ %d NEWER", scroll)
+	}
+
+	logContent := make([]string, 0, maxRows)
+	for _, entry := range entries[start:end] {
+		level := strings.ToUpper(logging.LevelName(entry.Level))
+		node := ""
+		if entry.NodeID != "" {
+			node = entry.NodeID
+			if len(node) > 8 {
+				node = node[:8]
+			}
+			node = "[" + node + "] "
+		}
+
+		// Truncate before styling so escape codes are never cut
+		text := []rune(entry.String())
+		room := lineWidth - 15 - len(node) // Timestamp and level
+		if room < 1 {
+			room = 1
+		}
+		if len(text) > room {
+			text = append(text[:max(room-1, 0)], '…')
+		}
+
+		style, ok := levelStyles[entry.Level]
+		if !ok {
+			style = levelStyles[slog.LevelInfo]
+		}
+		logContent = append(logContent,
+			timestampStyle.Render(entry.Time.Format("15:04:05"))+" "+
+				style.Render(fmt.Sprintf("%-5s", level))+" "+
+				nodeStyle.Render(node)+style.Render(string(text)))
+	}
+	if len(entries) == 0 {
+		logContent = append(logContent, tooltipStyle.Render("No log entries match"))
+	}
+	for len(logContent) < maxRows {
+		logContent = append(logContent, "")
+	}
+
+	logContainer := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Width(contentWidth-4).
+		Height(maxRows).
+		Padding(0, 1).
+		Render(strings.Join(logContent, "\n"))
+
+	help := "→ ENTER: Browse logs"
+	switch {
+	case m.LogSearchInput:
+		help = "→ Type to search | ENTER: Apply | ESC: Clear search"
+	case m.LogsFocused:
+		help = "→ ↑↓ PGUP PGDN: Scroll | L: Level | /: Search | P: Pause | F: Follow | ESC: Exit"
+	}
+
+	return strings.Join([]string{
+		titleStyle.Render("  SYSTEM ACTIVITY LOGS  "),
+		status,
+		"",
+		logContainer,
+		"",
+		tooltipStyle.Render(help),
+	}, "\n")
+}
+
+// handleLogSearchKeys processes key input while typing a Logs view search
+func (m *Model) handleLogSearchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		m.LogSearchInput = false
+	case "esc":
+		m.LogSearchInput = false
+		m.LogSearch = ""
+	case "backspace":
+		if search := []rune(m.LogSearch); len(search) > 0 {
+			m.LogSearch = string(search[:len(search)-1])
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			if len(m.LogSearch) < 100 {
+				m.LogSearch += string(msg.Runes)
+			}
+		}
+	}
+	m.LogScroll = 0
+	return m, nil
+}
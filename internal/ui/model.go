@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"time"
 
-	"cares/internal/functions"
+	"cares/internal/events"
 	"cares/internal/logging"
 	"cares/internal/metrics"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// maxMetricsHistory is the number of samples shown in the worker graphs.
+const maxMetricsHistory = 20
+
 // NewModel returns an initialized model starting in mode selection.
 func NewModel() *Model {
 	return &Model{
@@ -42,25 +45,32 @@ func NewModelWithInterval(d time.Duration) *Model {
 	return m
 }
 
-// Init is called when the program starts. Kick off the first metric sampling tick.
+// Init is called when the program starts. Start the shared metrics sampler
+// and kick off the first tick reading it.
 func (m *Model) Init() tea.Cmd {
+	metrics.Default()
 	return m.tickCmd()
 }
 
-// tickCmd returns a tea.Cmd that samples metrics after the configured interval
-// and sends a MetricsMsg to the Update loop.
+// tickCmd returns a tea.Cmd that reads the shared metrics sampler after the
+// configured interval and sends a MetricsMsg to the Update loop.
 func (m *Model) tickCmd() tea.Cmd {
 	interval := m.interval
 	return tea.Tick(interval, func(t time.Time) tea.Msg {
-		cpuVal, err1 := metrics.GetCPUUsage()
-		memVal, err2 := metrics.GetMemoryUsage()
-		var err error
-		if err1 != nil {
-			err = err1
-		} else if err2 != nil {
-			err = err2
+		sampler := metrics.Default()
+		sample, ok := sampler.Latest()
+		if !ok {
+			return MetricsMsg{Err: fmt.Errorf("no metrics sampled yet")}
+		}
+
+		msg := MetricsMsg{CPU: sample.CPUUsage, Mem: sample.MemoryUsage, Err: sample.Err}
+		for _, past := range sampler.History(maxMetricsHistory) {
+			if past.Err == nil {
+				msg.CPUHistory = append(msg.CPUHistory, past.CPUUsage)
+				msg.MemHistory = append(msg.MemHistory, past.MemoryUsage)
+			}
 		}
-		return MetricsMsg{CPU: cpuVal, Mem: memVal, Err: err}
+		return msg
 	})
 }
 
@@ -72,14 +82,29 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.ShowConfirm {
 			switch msg.String() {
 			case "y", "Y":
-				// Save function registry before quitting if it exists
+				// Close the function store before quitting; every change is
+				// already persisted, this only releases the backend
 				if m.FunctionRegistry != nil {
-					// Save synchronously to ensure it completes before exiting
-					if err := m.FunctionRegistry.SaveToFile(functions.DefaultStoragePath); err != nil {
+					if err := m.FunctionRegistry.Close(); err != nil {
 						// Log error but still quit
-						logging.Warn("Failed to save function registry: %v", err)
-					} else {
-						logging.Info("Function registry saved to %s", functions.DefaultStoragePath)
+						logging.Warn("Failed to close function store: %v", err)
+					}
+				}
+				if m.InvocationHistory != nil {
+					if err := m.InvocationHistory.Close(); err != nil {
+						logging.Warn("Failed to close invocation history: %v", err)
+					}
+				}
+				if m.LogStore != nil {
+					if err := m.LogStore.Close(); err != nil {
+						logging.Warn("Failed to close log store: %v", err)
+					}
+				}
+				m.stopWebhooks()
+				if m.AuditLog != nil {
+					events.Default().SetAuditLog(nil)
+					if err := m.AuditLog.Close(); err != nil {
+						logging.Warn("Failed to close audit log: %v", err)
 					}
 				}
 				return m, tea.Quit
@@ -90,7 +115,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		
 		// Global quit trigger (works in all modes) - including when screen is too small
-		if msg.String() == "ctrl+c" || msg.String() == "q" {
+		// ("q" is text while searching the logs)
+		if msg.String() == "ctrl+c" || (msg.String() == "q" && !m.LogSearchInput) {
 			// If screen is too small, quit directly without confirmation
 			if m.WinW < DesiredBoxW || m.WinH < DesiredBoxH {
 				return m, tea.Quit
@@ -125,20 +151,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.CPU = fmt.Sprintf("%.2f%%", msg.CPU)
 			m.Mem = fmt.Sprintf("%.2f%%", msg.Mem)
 			
-			// Update graph history for worker mode
+			// Update graph history for worker mode; the sampler keeps it
 			if m.Mode == ModeWorker {
-				// Add new data points
-				m.CPUHistory = append(m.CPUHistory, msg.CPU)
-				m.MemoryHistory = append(m.MemoryHistory, msg.Mem)
-				
-				// Keep only last 20 data points to prevent memory growth
-				maxHistory := 20
-				if len(m.CPUHistory) > maxHistory {
-					m.CPUHistory = m.CPUHistory[len(m.CPUHistory)-maxHistory:]
-				}
-				if len(m.MemoryHistory) > maxHistory {
-					m.MemoryHistory = m.MemoryHistory[len(m.MemoryHistory)-maxHistory:]
-				}
+				m.CPUHistory = msg.CPUHistory
+				m.MemoryHistory = msg.MemHistory
 			}
 		}
 		
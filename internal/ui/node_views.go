@@ -0,0 +1,279 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guptarohit/asciigraph"
+
+	"cares/internal/registry"
+)
+
+// nodeIDWidth fits a full node ID, a UUID, with padding.
+const nodeIDWidth = 38
+
+// nodeColumn is a column of the worker node table on the orchestrator dashboard.
+type nodeColumn struct {
+	title string
+	width int // Minimum width, including one space of padding on each side
+	value func(node *registry.Node) string
+}
+
+// nodeColumns lists the node table's columns by priority. Columns are dropped
+// from the end when the table is too narrow to show them all.
+var nodeColumns = []nodeColumn{
+	{"NODE ID", 12, func(node *registry.Node) string { return node.ID }},
+	{"STATUS", 10, nodeStatusLabel},
+	{"CPU", 14, func(node *registry.Node) string {
+		if node.Resources.CPUCores == 0 {
+			return fmt.Sprintf("%.1f%%", node.CPUUsage)
+		}
+		return fmt.Sprintf("%.1f%% /%dc", node.CPUUsage, node.Resources.CPUCores)
+	}},
+	{"MEM AVAIL", 14, func(node *registry.Node) string {
+		if node.Resources.MemoryTotal == 0 {
+			return fmt.Sprintf("%.1f%%", node.MemoryUsage)
+		}
+		return fmt.Sprintf("%s/%s", formatBytes(node.Resources.MemoryAvailable), formatBytes(node.Resources.MemoryTotal))
+	}},
+	{"DISK FREE", 11, func(node *registry.Node) string {
+		if node.Resources.DiskTotal == 0 {
+			return "-"
+		}
+		return formatBytes(node.Resources.DiskFree)
+	}},
+	{"LOAD", 7, func(node *registry.Node) string { return fmt.Sprintf("%.2f", node.Resources.Load1) }},
+	{"NET ↓/↑", 15, func(node *registry.Node) string {
+		return formatRate(node.Resources.NetworkRxRate) + "/" + formatRate(node.Resources.NetworkTxRate)
+	}},
+	{"CTRS", 6, func(node *registry.Node) string { return fmt.Sprintf("%d", node.Resources.RunningContainers) }},
+	{"UPTIME", 9, func(node *registry.Node) string { return formatUptime(node.Resources.UptimeSeconds) }},
+}
+
+// nodeTableColumns returns the columns that fit into width, with their widths.
+// Space left over widens the node ID column until full IDs fit, and is then
+// shared by all columns.
+func nodeTableColumns(width int) ([]nodeColumn, []int) {
+	used := 1 // Left border
+	var columns []nodeColumn
+	var widths []int
+	for _, column := range nodeColumns {
+		if len(columns) > 0 && used+column.width+1 > width {
+			break
+		}
+		columns = append(columns, column)
+		widths = append(widths, column.width)
+		used += column.width + 1 // Column and its right border
+	}
+	spare := width - used
+	if grow := min(spare, nodeIDWidth-widths[0]); grow > 0 {
+		widths[0] += grow
+		spare -= grow
+	}
+	for i := range widths {
+		if spare <= 0 {
+			break
+		}
+		extra := spare / (len(widths) - i)
+		widths[i] += extra
+		spare -= extra
+	}
+	return columns, widths
+}
+
+// nodeTableBorder renders a horizontal table border with the given corner and
+// junction characters.
+func nodeTableBorder(widths []int, left, junction, right string) string {
+	segments := make([]string, len(widths))
+	for i, width := range widths {
+		segments[i] = strings.Repeat("─", width)
+	}
+	return left + strings.Join(segments, junction) + right
+}
+
+// nodeTableRow renders a table row, truncating cells to their column width.
+func nodeTableRow(cells []string, widths []int) string {
+	var row strings.Builder
+	row.WriteString("│")
+	for i, width := range widths {
+		cell := cells[i]
+		if len([]rune(cell)) > width-2 {
+			cell = string([]rune(cell)[:width-5]) + "..."
+		}
+		row.WriteString(" " + cell + strings.Repeat(" ", width-2-len([]rune(cell))) + " │")
+	}
+	return row.String()
+}
+
+// nodeStatusLabel returns the status shown for a node in the node table.
+func nodeStatusLabel(node *registry.Node) string {
+	if node.Status != registry.NodeStatusActive {
+		return "OFFLINE"
+	}
+	if !node.Runtime.Available {
+		return "DEGRADED" // Only process and wasm functions can run
+	}
+	return "ONLINE"
+}
+
+// formatBytes formats a size with binary units, e.g. "15.6G".
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	value, suffix := float64(bytes)/unit, 0
+	for value >= unit && suffix < 4 {
+		value /= unit
+		suffix++
+	}
+	return fmt.Sprintf("%.1f%c", value, "KMGTP"[suffix])
+}
+
+// formatRate formats a throughput in bytes per second, e.g. "1.2M".
+func formatRate(rate float64) string {
+	if rate < 1 {
+		return "0"
+	}
+	return formatBytes(uint64(rate))
+}
+
+// formatUptime formats a duration in seconds coarsely, e.g. "3d4h" or "12m".
+func formatUptime(seconds int64) string {
+	switch {
+	case seconds <= 0:
+		return "-"
+	case seconds < 3600:
+		return fmt.Sprintf("%dm", seconds/60)
+	case seconds < 86400:
+		return fmt.Sprintf("%dh%dm", seconds/3600, seconds%3600/60)
+	default:
+		return fmt.Sprintf("%dd%dh", seconds/86400, seconds%86400/3600)
+	}
+}
+
+// nodeDetailRanges are the history ranges the node detail view cycles through.
+var nodeDetailRanges = []time.Duration{15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// getNodeDetailContent returns the detail view of the node opened from the
+// node table: its current resources and sparklines of its metric history.
+func (m Model) getNodeDetailContent(contentWidth, height int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Reverse(true).Padding(0, 1)
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	tooltipStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+
+	window := nodeDetailRanges[m.NodeDetailRange%len(nodeDetailRanges)]
+	help := tooltipStyle.Render(fmt.Sprintf("→ Range %s | R: Change range | ESC: Back to nodes", formatRange(window)))
+
+	node := m.NodeRegistry.GetNode(m.NodeDetailID)
+	if node == nil {
+		return strings.Join([]string{
+			headerStyle.Render("  NODE DETAIL  "),
+			"",
+			"NODE IS NO LONGER REGISTERED",
+			"",
+			help,
+		}, "\n")
+	}
+	points, resolution, _ := m.NodeRegistry.History(node.ID, window)
+
+	lines := []string{
+		headerStyle.Render("  NODE DETAIL - " + node.ID + "  "),
+		"",
+		fmt.Sprintf("%s %s   %s %s   %s %s",
+			labelStyle.Render("STATUS:"), nodeStatusLabel(node),
+			labelStyle.Render("HOST:"), node.Hostname,
+			labelStyle.Render("UPTIME:"), formatUptime(node.Resources.UptimeSeconds)),
+	}
+	var resources []string
+	for _, column := range nodeColumns[2:8] { // CPU through containers
+		resources = append(resources, fmt.Sprintf("%s %s", labelStyle.Render(column.title+":"), column.value(node)))
+	}
+	lines = append(lines, strings.Join(resources[:3], "   "), strings.Join(resources[3:], "   "), "")
+
+	if len(points) < 2 {
+		return strings.Join(append(lines,
+			"COLLECTING METRICS - HISTORY APPEARS AFTER A FEW HEARTBEATS",
+			"",
+			help,
+		), "\n")
+	}
+
+	cpu := make([]float64, len(points))
+	memory := make([]float64, len(points))
+	load := make([]float64, len(points))
+	rx := make([]float64, len(points))
+	tx := make([]float64, len(points))
+	for i, point := range points {
+		cpu[i], memory[i], load[i] = point.CPUUsage, point.MemoryUsage, point.Load1
+		rx[i], tx[i] = point.NetworkRxRate/1024, point.NetworkTxRate/1024
+	}
+
+	// Four graphs share the height left after the summary above and the help
+	// and network legend below. Besides its rows, a graph takes a title, an
+	// extra axis row, a caption and a blank line.
+	graphHeight := (height-len(lines)-4)/4 - 4
+	if graphHeight < 2 {
+		graphHeight = 2
+	}
+	graphWidth := contentWidth - 16 // Axis labels
+	if graphWidth < 10 {
+		graphWidth = 10
+	}
+	caption := fmt.Sprintf("last %s, %d points at %s", formatRange(window), len(points), resolution)
+
+	graphs := []struct {
+		title  string
+		series [][]float64
+		opts   []asciigraph.Option
+	}{
+		{"CPU %", [][]float64{cpu}, []asciigraph.Option{asciigraph.LowerBound(0), asciigraph.UpperBound(100)}},
+		{"MEMORY %", [][]float64{memory}, []asciigraph.Option{asciigraph.LowerBound(0), asciigraph.UpperBound(100)}},
+		{"LOAD (1m)", [][]float64{load}, []asciigraph.Option{asciigraph.LowerBound(0), asciigraph.Precision(2)}},
+		{"NETWORK KiB/s", [][]float64{rx, tx}, []asciigraph.Option{asciigraph.LowerBound(0),
+			asciigraph.SeriesColors(asciigraph.Green, asciigraph.Blue), asciigraph.SeriesLegends("↓ rx", "↑ tx")}},
+	}
+	for _, graph := range graphs {
+		series := make([][]float64, len(graph.series))
+		for i, values := range graph.series {
+			series[i] = downsample(values, graphWidth)
+		}
+		opts := append([]asciigraph.Option{
+			asciigraph.Height(graphHeight),
+			asciigraph.Width(graphWidth),
+			asciigraph.Precision(1),
+			asciigraph.Caption(caption),
+		}, graph.opts...)
+		lines = append(lines, labelStyle.Render(graph.title), asciigraph.PlotMany(series, opts...), "")
+	}
+
+	return strings.Join(append(lines, help), "\n")
+}
+
+// downsample averages values into at most n buckets so a long history fits
+// the graph width without dropping spikes between columns entirely.
+func downsample(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	out := make([]float64, n)
+	for i := range out {
+		from, to := i*len(values)/n, (i+1)*len(values)/n
+		var sum float64
+		for _, value := range values[from:to] {
+			sum += value
+		}
+		out[i] = sum / float64(to-from)
+	}
+	return out
+}
+
+// formatRange formats a history range compactly, e.g. "15m" or "24h".
+func formatRange(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
+
+	"cares/internal/functions"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -37,6 +40,7 @@ func (m Model) getTwoPanelLayout() []string {
 		"Logs",
 		"Functions",
 		"Add Function",
+		"History",
 	}
 	
 	var menuContent []string
@@ -76,13 +80,23 @@ func (m Model) getTwoPanelLayout() []string {
 	var contentText string
 	switch m.SidebarSelected {
 	case 0: // Orchestrator - now first/default
-		contentText = m.getOrchestratorContent(contentWidth)
+		if m.NodeDetailOpen {
+			contentText = m.getNodeDetailContent(contentWidth, availableHeight-6)
+		} else {
+			contentText = m.getOrchestratorContent(contentWidth)
+		}
 	case 1: // Logs
 		contentText = m.getLogsContent(contentWidth, availableHeight)
 	case 2: // Functions
-		contentText = m.getFunctionsContent(contentWidth)
+		if m.ShowFunctionEditForm {
+			contentText = m.getEditFunctionContent()
+		} else {
+			contentText = m.getFunctionsContent(contentWidth)
+		}
 	case 3: // Add Function
 		contentText = m.getAddFunctionContent()
+	case 4: // History
+		contentText = m.getHistoryContent(contentWidth)
 	default:
 		contentText = "Select a menu item to view content"
 	}
@@ -355,114 +369,6 @@ func (m Model) createSimpleGraph(data []float64, width, height int, label string
 	return lines
 }
 
-// getLogsContent returns logs content for the right panel
-func (m Model) getLogsContent(contentWidth int, availableHeight int) string {
-	// Inverted heading style
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Reverse(true).
-		Padding(0, 1)
-	
-	// Updated neon colors
-	timestampStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("245")) // Light grey for timestamps
-	
-	successStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("46")) // Bright neon green for success
-	
-	infoStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("51")) // Bright neon cyan for info
-	
-	warningStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("226")) // Bright neon yellow for warnings
-	
-	var lines []string
-	
-	lines = append(lines,
-		titleStyle.Render("  SYSTEM ACTIVITY LOGS  "),
-		"",
-	)
-	
-	// Collect log entries
-	var logEntries []string
-	
-	logEntries = append(logEntries,
-		timestampStyle.Render("[14:32:07]") + successStyle.Render(" ORCHESTRATOR INITIALIZED SUCCESSFULLY"),
-		timestampStyle.Render("[14:32:08]") + infoStyle.Render(" GRPC SERVER LISTENING ON PORT :50051"),
-		timestampStyle.Render("[14:32:09]") + infoStyle.Render(" REST API SERVER RUNNING ON PORT :8080"),
-		timestampStyle.Render("[14:32:10]") + successStyle.Render(" FUNCTION REGISTRY INITIALIZED"),
-	)
-	
-	if m.NodeRegistry != nil {
-		nodes := m.NodeRegistry.GetAllNodes()
-		if len(nodes) > 0 {
-			logEntries = append(logEntries, 
-				timestampStyle.Render("[14:32:11]") + 
-				successStyle.Render(fmt.Sprintf(" %d WORKER NODE(S) CONNECTED TO CLUSTER", len(nodes))))
-			
-			for i, node := range nodes {
-				if i >= 2 { // Limit to 2 nodes to fit in 10 rows
-					break
-				}
-				
-				nodeID := node.ID
-				if len(nodeID) > 12 {
-					nodeID = nodeID[:9] + "..."
-				}
-				
-				logEntries = append(logEntries, 
-					timestampStyle.Render(fmt.Sprintf("[14:32:1%d]", 2+i)) + 
-					infoStyle.Render(fmt.Sprintf(" %s: CPU %.1f%% | MEM %.1f%% | STATUS: ACTIVE", 
-						nodeID, node.CPUUsage, node.MemoryUsage)))
-			}
-			
-			logEntries = append(logEntries, 
-				timestampStyle.Render("[14:32:15]") + successStyle.Render(" CLUSTER LOAD BALANCING ACTIVE"))
-		} else {
-			logEntries = append(logEntries,
-				timestampStyle.Render("[14:32:11]") + warningStyle.Render(" WAITING FOR WORKER NODES TO JOIN..."))
-		}
-	}
-	
-	logEntries = append(logEntries, 
-		timestampStyle.Render("[14:32:16]") + successStyle.Render(" SYSTEM OPERATIONAL - MONITORING ACTIVE"))
-	
-	// Calculate maximum rows based on available height
-	maxRows := availableHeight - 6 // Account for title, borders, and padding
-	if maxRows < 5 {
-		maxRows = 5 // Minimum rows
-	}
-	
-	// Show only the latest logs (reverse order if needed)
-	startIndex := 0
-	if len(logEntries) > maxRows {
-		startIndex = len(logEntries) - maxRows
-	}
-	
-	// Build log content for the bordered container
-	var logContent []string
-	for i := 0; i < maxRows; i++ {
-		entryIndex := startIndex + i
-		if entryIndex < len(logEntries) {
-			logContent = append(logContent, logEntries[entryIndex])
-		} else {
-			logContent = append(logContent, "") // Empty line
-		}
-	}
-	
-	// Create the terminal-style bordered log container using lipgloss
-	logContainer := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		Width(contentWidth - 4). // Use full available width minus outer padding
-		Height(maxRows).
-		Padding(0, 1).
-		Render(strings.Join(logContent, "\n"))
-	
-	lines = append(lines, logContainer)
-	
-	return strings.Join(lines, "\n")
-}
-
 // getOrchestratorContent returns orchestrator info for the right panel
 func (m Model) getOrchestratorContent(contentWidth int) string {
 	if m.NodeRegistry == nil {
@@ -532,31 +438,17 @@ func (m Model) getOrchestratorContent(contentWidth int) string {
 		tableWidth = 40 // Minimum width
 	}
 	
-	// Calculate column widths dynamically (4 columns: Node ID, CPU, Memory, Status)
-	// Node ID: 30%, CPU: 25%, Memory: 25%, Status: 20%
-	nodeIdWidth := tableWidth * 30 / 100
-	cpuWidth := tableWidth * 25 / 100  
-	memoryWidth := tableWidth * 25 / 100
-	statusWidth := tableWidth - nodeIdWidth - cpuWidth - memoryWidth - 6 // Account for separators
-	
-	// Ensure minimum widths
-	if nodeIdWidth < 10 {
-		nodeIdWidth = 10
-	}
-	if cpuWidth < 8 {
-		cpuWidth = 8
-	}
-	if memoryWidth < 8 {
-		memoryWidth = 8
-	}
-	if statusWidth < 8 {
-		statusWidth = 8
+	// Columns that do not fit are dropped, least important first
+	columns, widths := nodeTableColumns(tableWidth)
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = column.title
 	}
 	
 	// Build dynamic table header
-	topBorder := "┌" + strings.Repeat("─", nodeIdWidth) + "┬" + strings.Repeat("─", cpuWidth) + "┬" + strings.Repeat("─", memoryWidth) + "┬" + strings.Repeat("─", statusWidth) + "┐"
-	headerRow := fmt.Sprintf("│ %-*s │ %-*s │ %-*s │ %-*s │", nodeIdWidth-2, "NODE ID", cpuWidth-2, "CPU", memoryWidth-2, "MEMORY", statusWidth-2, "STATUS")
-	midBorder := "├" + strings.Repeat("─", nodeIdWidth) + "┼" + strings.Repeat("─", cpuWidth) + "┼" + strings.Repeat("─", memoryWidth) + "┼" + strings.Repeat("─", statusWidth) + "┤"
+	topBorder := nodeTableBorder(widths, "┌", "┬", "┐")
+	headerRow := nodeTableRow(titles, widths)
+	midBorder := nodeTableBorder(widths, "├", "┼", "┤")
 	
 	lines = append(lines,
 		labelStyle.Render("WORKER NODES - PRESS ENTER TO NAVIGATE"),
@@ -574,50 +466,31 @@ func (m Model) getOrchestratorContent(contentWidth int) string {
 	}
 	
 	for i := 0; i < maxRows; i++ {
-		var row string
-		
+		cells := make([]string, len(columns))
 		if i < len(nodes) {
 			// Display actual node data
-			node := nodes[i]
-			
-			// Truncate node ID to fit column width
-			nodeID := node.ID
-			if len(nodeID) > nodeIdWidth-3 {
-				nodeID = nodeID[:nodeIdWidth-6] + "..."
-			}
-			
-			status := "OFFLINE"
-			if string(node.Status) == "Active" {
-				status = "ONLINE"
+			for j, column := range columns {
+				cells[j] = column.value(nodes[i])
 			}
-			
-			row = fmt.Sprintf("│ %-*s │ %-*s │ %-*s │ %-*s │",
-				nodeIdWidth-2, nodeID, 
-				cpuWidth-2, fmt.Sprintf("%.1f%%", node.CPUUsage), 
-				memoryWidth-2, fmt.Sprintf("%.1f%%", node.MemoryUsage), 
-				statusWidth-2, status)
-			
-			// Highlight selected row only when table is focused
-			if i == selectedIndex && m.NodeTableFocused {
-				row = selectedRowStyle.Render(row)
-			}
-		} else {
-			// Empty row with dynamic spacing
-			row = fmt.Sprintf("│%*s│%*s│%*s│%*s│", 
-				nodeIdWidth, "", cpuWidth, "", memoryWidth, "", statusWidth, "")
+		}
+		row := nodeTableRow(cells, widths)
+		
+		// Highlight selected row only when table is focused
+		if i < len(nodes) && i == selectedIndex && m.NodeTableFocused {
+			row = selectedRowStyle.Render(row)
 		}
 		
 		lines = append(lines, row)
 	}
 	
 	// Table footer with dynamic width
-	bottomBorder := "└" + strings.Repeat("─", nodeIdWidth) + "┴" + strings.Repeat("─", cpuWidth) + "┴" + strings.Repeat("─", memoryWidth) + "┴" + strings.Repeat("─", statusWidth) + "┘"
+	bottomBorder := nodeTableBorder(widths, "└", "┴", "┘")
 	lines = append(lines, 
 		bottomBorder,
 		"",
 		tooltipStyle.Render(func() string {
 			if m.NodeTableFocused {
-				return fmt.Sprintf("→ Node %d of %d | ↑↓: Navigate | ESC: Exit table", selectedIndex+1, len(nodes))
+				return fmt.Sprintf("→ Node %d of %d | ↑↓: Navigate | ENTER: Details | ESC: Exit table", selectedIndex+1, len(nodes))
 			}
 			return fmt.Sprintf("→ %d of %d nodes | ENTER: Navigate table", len(nodes), maxRows)
 		}()),
@@ -667,10 +540,15 @@ func (m Model) getFunctionsContent(contentWidth int) string {
 		titleStyle.Render("FUNCTION REGISTRY"),
 		"",
 		fmt.Sprintf("%s %s", labelStyle.Render("SELECTED:"), selectedFunction.Name),
-		fmt.Sprintf("%s %s", labelStyle.Render("IMAGE:"), selectedFunction.Image),
-		fmt.Sprintf("%s %s", labelStyle.Render("STATUS:"), highlightStyle.Render(strings.ToUpper(selectedFunction.Status))),
+		fmt.Sprintf("%s %s (%s)", labelStyle.Render(targetLabel(selectedFunction)), selectedFunction.Image, selectedFunction.Kind),
+		fmt.Sprintf("%s %s", labelStyle.Render("STATUS:"), highlightStyle.Render(functionStatusLabel(selectedFunction, time.Now()))),
 		fmt.Sprintf("%s %s", labelStyle.Render("ENDPOINT:"), fmt.Sprintf("POST /invoke/%s", strings.ToLower(selectedFunction.Name))),
 		tooltipStyle.Render(fmt.Sprintf("→ Description: %s", getOrDefault(selectedFunction.Description, "No description provided"))),
+		"",
+		fmt.Sprintf("%s %s", labelStyle.Render("TRAFFIC:"), formatTrafficSplit(selectedFunction)),
+	)
+	lines = append(lines, formatVersionStats(selectedFunction, 3)...)
+	lines = append(lines,
 		"",
 		"",
 	)
@@ -735,10 +613,7 @@ func (m Model) getFunctionsContent(contentWidth int) string {
 				image = image[:imageWidth-6] + "..."
 			}
 			
-			status := "READY"
-			if fn.Status == "active" {
-				status = "ACTIVE"
-			}
+			status := functionStatusLabel(fn, time.Now())
 			
 			// Generate endpoint
 			endpoint := fmt.Sprintf("/invoke/%s", strings.ToLower(fn.Name))
@@ -768,7 +643,7 @@ func (m Model) getFunctionsContent(contentWidth int) string {
 		"",
 		tooltipStyle.Render(func() string {
 			if m.FunctionTableFocused {
-				return fmt.Sprintf("→ Function %d of %d | ↑↓: Navigate | ESC: Exit table", selectedIndex+1, len(functions))
+				return fmt.Sprintf("→ Function %d of %d | ↑↓: Navigate | E: Edit | T: Enable/Disable | ESC: Exit table", selectedIndex+1, len(functions))
 			}
 			return fmt.Sprintf("→ %d of %d functions | ENTER: Navigate table", len(functions), maxRows)
 		}()),
@@ -777,6 +652,58 @@ func (m Model) getFunctionsContent(contentWidth int) string {
 	return strings.Join(lines, "\n")
 }
 
+// functionStatusLabel returns the table label for a function's effective status,
+// flagging statuses that come from a scheduled window rather than a manual toggle
+func functionStatusLabel(fn *functions.Function, now time.Time) string {
+	label := "DISABLED"
+	if fn.IsInvocable(now) {
+		label = "ACTIVE"
+	}
+	if fn.ActiveWindow(now) != nil {
+		label = "SCHED " + label
+	}
+	return label
+}
+
+// targetLabel names what a function runs: a container image, an executable or a wasm module
+func targetLabel(fn *functions.Function) string {
+	switch fn.Kind {
+	case functions.KindProcess:
+		return "EXECUTABLE:"
+	case functions.KindWasm:
+		return "MODULE:"
+	default:
+		return "IMAGE:"
+	}
+}
+
+// formatTrafficSplit renders the weighted routing of a function, e.g. "v1 90% | v2 10%"
+func formatTrafficSplit(fn *functions.Function) string {
+	var parts []string
+	for _, version := range fn.Versions {
+		if weight := fn.TrafficWeight(version.Version); weight > 0 {
+			parts = append(parts, fmt.Sprintf("v%d %d%%", version.Version, weight))
+		}
+	}
+	if len(parts) == 0 {
+		return "NO ROUTABLE VERSION"
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatVersionStats renders one stats line per version, newest first, limited to maxVersions
+func formatVersionStats(fn *functions.Function, maxVersions int) []string {
+	var lines []string
+	for i := len(fn.Versions) - 1; i >= 0 && len(lines) < maxVersions; i-- {
+		version := fn.Versions[i]
+		stats := version.Stats
+		lines = append(lines, fmt.Sprintf("  v%-3d %3d%%  %5d CALLS  %5.1f%% OK  AVG %6.0fms  MAX %6dms  %s",
+			version.Version, fn.TrafficWeight(version.Version), stats.Invocations,
+			stats.SuccessRate(), stats.AvgLatencyMs(), stats.MaxLatencyMs, version.Image))
+	}
+	return lines
+}
+
 // Helper function to get value or default
 func getOrDefault(value, defaultValue string) string {
 	if value == "" {
@@ -785,6 +712,96 @@ func getOrDefault(value, defaultValue string) string {
 	return value
 }
 
+// getEditFunctionContent returns the edit form for the selected function
+func (m Model) getEditFunctionContent() string {
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Underline(true).
+		MarginBottom(2)
+	
+	labelStyle := lipgloss.NewStyle().Bold(true)
+	activeFieldStyle := lipgloss.NewStyle().Bold(true).Reverse(true)
+	
+	inputActiveStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		Padding(0, 1).
+		Bold(true)
+	
+	inputInactiveStyle := lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder()).
+		Padding(0, 1)
+	
+	watermarkStyle := lipgloss.NewStyle().
+		Faint(true).
+		Italic(true)
+	
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196")).
+		Bold(true)
+	
+	tooltipStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("240")).
+		Italic(true)
+	
+	name := m.EditFunctionID
+	if m.FunctionRegistry != nil {
+		if fn, exists := m.FunctionRegistry.GetFunction(m.EditFunctionID); exists {
+			name = fn.Name
+		}
+	}
+	
+	var lines []string
+	
+	lines = append(lines,
+		titleStyle.Render("EDIT FUNCTION"),
+		"",
+		watermarkStyle.Render(fmt.Sprintf("EDITING '%s' AT REVISION %d", strings.ToUpper(name), m.EditFunctionRevision)),
+		"",
+	)
+	
+	fields := []struct {
+		label string
+		value string
+		hint  string
+	}{
+		{"DOCKER IMAGE", m.EditFormImage, "A NEW IMAGE PUBLISHES A NEW VERSION"},
+		{"DESCRIPTION", m.EditFormDesc, ""},
+		{"TIMEOUT (SECONDS)", m.EditFormTimeout, "0 USES THE WORKER DEFAULT"},
+		{"MEMORY LIMIT (MB)", m.EditFormMemory, "0 MEANS NO LIMIT"},
+	}
+	
+	for i, field := range fields {
+		active := i == m.EditFormField
+		
+		labelText := labelStyle.Render(field.label)
+		if active {
+			labelText = activeFieldStyle.Render(fmt.Sprintf(" %s ", field.label))
+		}
+		if field.hint != "" {
+			labelText += " " + watermarkStyle.Render(field.hint)
+		}
+		lines = append(lines, labelText)
+		
+		if active {
+			lines = append(lines, inputActiveStyle.Width(50).Render(field.value+"|"), "")
+		} else {
+			lines = append(lines, inputInactiveStyle.Width(50).Render(field.value), "")
+		}
+	}
+	
+	if m.EditFormError != "" {
+		lines = append(lines, errorStyle.Render("ERROR: "+m.EditFormError), "")
+	}
+	
+	lines = append(lines,
+		tooltipStyle.Render("→ TAB/UP/DOWN: Navigate fields"),
+		tooltipStyle.Render("→ ENTER: Save changes"),
+		tooltipStyle.Render("→ ESC: Cancel and return"),
+	)
+	
+	return strings.Join(lines, "\n")
+}
+
 // getAddFunctionContent returns the add function form for the right panel
 func (m Model) getAddFunctionContent() string {
 	titleStyle := lipgloss.NewStyle().
@@ -5,8 +5,12 @@ import (
 
 	"cares/internal/api"
 	"cares/internal/cluster"
+	"cares/internal/events"
 	"cares/internal/functions"
+	"cares/internal/invocations"
+	"cares/internal/logstore"
 	"cares/internal/registry"
+	"cares/internal/webhooks"
 )
 
 // AppMode represents the current mode of the application
@@ -68,6 +72,10 @@ type Model struct {
 	// Phase 3 - Function management
 	FunctionRegistry *functions.Registry
 	ApiServer        *api.Server
+	InvocationHistory *invocations.History
+	LogStore         *logstore.Store // Logs shipped by workers
+	AuditLog         *events.AuditLog // Cluster events, served at GET /events
+	Webhooks         *webhooks.Dispatcher // Delivers cluster events to webhook subscriptions
 	
 	// Sidebar navigation state
 	SidebarSelected  int
@@ -84,9 +92,36 @@ type Model struct {
 	FunctionTableFocused bool // True when user is navigating functions table
 	FunctionSelectedIndex int // Currently selected function in table
 	
+	// Function edit form state (opened with "e" on the functions table)
+	ShowFunctionEditForm bool
+	EditFunctionID       string
+	EditFunctionRevision int64 // Revision the form was loaded from, for optimistic concurrency
+	EditFormImage        string
+	EditFormDesc         string
+	EditFormTimeout      string
+	EditFormMemory       string
+	EditFormField        int // 0=image, 1=desc, 2=timeout, 3=memory
+	EditFormError        string
+	
+	// Invocation history navigation state
+	HistoryTableFocused  bool // True when user is navigating the history table
+	HistorySelectedIndex int  // Currently selected invocation (0 = newest)
+	
 	// Node navigation state
 	NodeTableFocused bool // True when user is navigating nodes table
 	NodeSelectedIndex int // Currently selected node in table
+	NodeDetailOpen    bool   // True when the selected node's detail view is shown
+	NodeDetailID      string // Node shown in the detail view
+	NodeDetailRange   int    // Index into nodeDetailRanges
+	
+	// Logs view state
+	LogsFocused    bool   // True when user is browsing the log feed
+	LogLevelFilter int    // Index into logLevelFilters
+	LogSearch      string // Case-insensitive text entries must contain
+	LogSearchInput bool   // True while typing the search
+	LogScroll      int    // Lines scrolled back from the newest entry
+	LogPaused      bool   // True when new entries are held back
+	LogPausedSeq   uint64 // Last entry shown while paused
 	
 	// Function confirmation modal state
 	ShowFunctionConfirmModal bool
@@ -104,4 +139,8 @@ type MetricsMsg struct {
 	CPU float64
 	Mem float64
 	Err error
+
+	// Recent samples for the worker graphs, oldest first
+	CPUHistory []float64
+	MemHistory []float64
 }
@@ -0,0 +1,160 @@
+package webhooks
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cares/internal/events"
+)
+
+// DefaultDeadLetterPath is the default location of the dead letter log.
+const DefaultDeadLetterPath = "data/webhook_dead_letters.jsonl"
+
+// DefaultDeadLetterMemory is how many of the latest dead letters are kept in
+// memory for queries.
+const DefaultDeadLetterMemory = 1000
+
+// DeadLetter is a delivery that was given up on, with the event it carried so
+// it can be inspected or replayed by hand.
+type DeadLetter struct {
+	DeliveryID string       `json:"delivery_id"`
+	WebhookID  string       `json:"webhook_id"`
+	URL        string       `json:"url"`
+	Event      events.Event `json:"event"`
+	Attempts   int          `json:"attempts"`
+	StatusCode int          `json:"status_code,omitempty"` // Of the last attempt, unset if there was no response
+	Error      string       `json:"error"`                 // Why the last attempt failed
+	FailedAt   time.Time    `json:"failed_at"`
+}
+
+// DeadLetters is an append-only, persistent record of failed deliveries.
+// Like the audit log, the file is never rewritten and only the latest dead
+// letters are kept in memory for queries.
+type DeadLetters struct {
+	mu      sync.RWMutex
+	path    string
+	memory  int
+	letters []DeadLetter // Latest dead letters, oldest first
+	file    *os.File
+}
+
+// OpenDeadLetters loads the latest memory dead letters stored at path (if
+// any) and opens it for appending. memory <= 0 uses DefaultDeadLetterMemory.
+func OpenDeadLetters(path string, memory int) (*DeadLetters, error) {
+	if memory <= 0 {
+		memory = DefaultDeadLetterMemory
+	}
+	d := &DeadLetters{path: path, memory: memory}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter log: %v", err)
+	}
+	d.file = file
+
+	return d, nil
+}
+
+// Add appends a dead letter to the log.
+func (d *DeadLetters) Add(letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %v", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return fmt.Errorf("dead letter log is closed")
+	}
+	if _, err := d.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append dead letter: %v", err)
+	}
+	d.remember(letter)
+	return nil
+}
+
+// Query returns the dead letters of a subscription (all of them if webhookID
+// is empty) held in memory newest first, skipping offset and returning at
+// most limit (0 = no limit), together with the total number of matches.
+func (d *DeadLetters) Query(webhookID string, limit, offset int) ([]DeadLetter, int) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var matches []DeadLetter
+	for i := len(d.letters) - 1; i >= 0; i-- {
+		if webhookID == "" || d.letters[i].WebhookID == webhookID {
+			matches = append(matches, d.letters[i])
+		}
+	}
+
+	total := len(matches)
+	if offset >= total {
+		return []DeadLetter{}, total
+	}
+	matches = matches[offset:]
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, total
+}
+
+// Close closes the log file.
+func (d *DeadLetters) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	return err
+}
+
+// remember keeps a dead letter in memory, forgetting the oldest beyond the
+// limit. The caller must hold d.mu (or own d exclusively).
+func (d *DeadLetters) remember(letter DeadLetter) {
+	d.letters = append(d.letters, letter)
+	if len(d.letters) > d.memory {
+		d.letters = append([]DeadLetter(nil), d.letters[len(d.letters)-d.memory:]...)
+	}
+}
+
+// load reads the dead letters stored in the log file. A missing file is an
+// empty log.
+func (d *DeadLetters) load() error {
+	file, err := os.Open(d.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read dead letter log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			// A torn final line after a crash is expected; skip it
+			continue
+		}
+		d.remember(letter)
+	}
+	return scanner.Err()
+}
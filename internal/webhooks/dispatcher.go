@@ -0,0 +1,353 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"cares/internal/events"
+	"cares/internal/logging"
+	"cares/internal/telemetry"
+)
+
+// Headers of a delivery. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed with the
+// subscription's secret; receivers check it with Verify and should reject
+// stale timestamps to stop replays.
+const (
+	EventHeader     = "X-Cares-Event"     // Event type, e.g. "node.disconnected"
+	DeliveryHeader  = "X-Cares-Delivery"  // Delivery ID, the same on every attempt
+	TimestampHeader = "X-Cares-Timestamp" // Unix time of the attempt, in seconds
+	SignatureHeader = "X-Cares-Signature"
+)
+
+// PingEvent is the type of the event sent by Ping to test a subscription.
+const PingEvent events.Type = "webhook.ping"
+
+// Delivery limits
+const (
+	deliveryWorkers   = 4
+	deliveryQueueSize = 1000
+	deliveryTimeout   = 10 * time.Second
+	maxResponseBytes  = 64 * 1024 // Response body read, and ignored, to reuse the connection
+)
+
+var webhookDeliveries = telemetry.NewCounter("cares_webhook_deliveries_total",
+	"Webhook delivery attempts, by result: delivered, retried or dead_lettered.", "result")
+
+// RetryPolicy bounds how long a failing delivery is retried.
+type RetryPolicy struct {
+	MaxAttempts    int           // Attempts before the delivery becomes a dead letter
+	InitialBackoff time.Duration // Wait before the first retry, doubled after each further failure
+	MaxBackoff     time.Duration // Longest wait between attempts
+}
+
+// DefaultRetryPolicy retries for about a minute: after 1s, 2s, 4s, 8s, 16s and 32s.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    7,
+	InitialBackoff: time.Second,
+	MaxBackoff:     5 * time.Minute,
+}
+
+// Backoff returns the wait before the next attempt after the given number of
+// failed attempts.
+func (p RetryPolicy) Backoff(failed int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < failed && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
+
+// Sign returns the signature of a delivery body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at timestamp,
+// both as received in the SignatureHeader and TimestampHeader of a delivery.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, t, body)), []byte(signature))
+}
+
+// delivery is an event on its way to one subscription.
+type delivery struct {
+	id       string
+	webhook  Subscription
+	event    events.Event
+	body     []byte
+	attempts int
+}
+
+// Dispatcher delivers the events published on a bus to the subscriptions in
+// its store. Deliveries run concurrently, so a subscription may receive
+// events out of order; each carries its seq for receivers that care.
+type Dispatcher struct {
+	store       *Store
+	deadLetters *DeadLetters
+	retry       RetryPolicy
+	client      *http.Client
+
+	queue        chan *delivery
+	subscription *events.Subscription
+	workers      sync.WaitGroup
+	ctx          context.Context // Cancelled by Stop, aborting attempts in flight
+	cancel       context.CancelFunc
+
+	mu       sync.Mutex
+	stopped  bool
+	retrying map[*delivery]*time.Timer // Deliveries waiting for their next attempt
+}
+
+// NewDispatcher creates a dispatcher for the subscriptions in store, keeping
+// failed deliveries in deadLetters.
+func NewDispatcher(store *Store, deadLetters *DeadLetters, retry RetryPolicy) *Dispatcher {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		store:       store,
+		deadLetters: deadLetters,
+		retry:       retry,
+		client:      &http.Client{Timeout: deliveryTimeout},
+		queue:       make(chan *delivery, deliveryQueueSize),
+		retrying:    make(map[*delivery]*time.Timer),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Store returns the subscriptions the dispatcher delivers to.
+func (d *Dispatcher) Store() *Store {
+	return d.store
+}
+
+// DeadLetters returns the log of deliveries that were given up on.
+func (d *Dispatcher) DeadLetters() *DeadLetters {
+	return d.deadLetters
+}
+
+// Start delivers the events published on bus from now on until Stop.
+func (d *Dispatcher) Start(bus *events.Bus) {
+	d.subscription = bus.Subscribe(events.Filter{})
+	for i := 0; i < deliveryWorkers; i++ {
+		d.workers.Add(1)
+		go d.work()
+	}
+	go d.dispatch(d.subscription)
+}
+
+// Stop stops delivering without waiting for slow endpoints: attempts in
+// flight are cancelled, and they and the deliveries still queued or waiting
+// for a retry are recorded as dead letters.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if d.stopped {
+		d.mu.Unlock()
+		return
+	}
+	d.stopped = true
+	for pending, timer := range d.retrying {
+		if timer.Stop() {
+			d.giveUp(pending, 0, "dispatcher stopped")
+		}
+	}
+	d.retrying = nil
+	for drained := false; !drained; {
+		select {
+		case pending := <-d.queue:
+			d.giveUp(pending, 0, "dispatcher stopped")
+		default:
+			drained = true
+		}
+	}
+	close(d.queue)
+	d.mu.Unlock()
+
+	d.cancel()
+	if d.subscription != nil {
+		d.subscription.Close()
+	}
+	d.workers.Wait()
+}
+
+// Ping sends a PingEvent to a subscription once, without retries, and
+// returns the status code it answered with.
+func (d *Dispatcher) Ping(ctx context.Context, webhook Subscription) (int, error) {
+	event := events.Event{
+		ID:      uuid.New().String(),
+		Time:    time.Now(),
+		Type:    PingEvent,
+		Actor:   events.ActorFromContext(ctx),
+		Subject: webhook.ID,
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	return d.send(ctx, &delivery{id: uuid.New().String(), webhook: webhook, event: event, body: body})
+}
+
+// dispatch queues a delivery of every event for each subscription wanting it.
+func (d *Dispatcher) dispatch(subscription *events.Subscription) {
+	for event := range subscription.C {
+		webhooks := d.store.Matching(event)
+		if len(webhooks) == 0 {
+			continue
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			logging.Warn("Failed to encode %s event for webhooks: %v", event.Type, err)
+			continue
+		}
+		for _, webhook := range webhooks {
+			d.enqueue(&delivery{id: uuid.New().String(), webhook: webhook, event: event, body: body})
+		}
+	}
+}
+
+// enqueue hands a delivery to the workers, or records it as a dead letter if
+// the queue is full or the dispatcher has stopped.
+func (d *Dispatcher) enqueue(pending *delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		d.giveUp(pending, 0, "dispatcher stopped")
+		return
+	}
+	select {
+	case d.queue <- pending:
+	default:
+		d.giveUp(pending, 0, "delivery queue full")
+	}
+}
+
+// work makes the attempts of queued deliveries until the queue is closed.
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+	for pending := range d.queue {
+		d.attempt(pending)
+	}
+}
+
+// attempt sends a delivery, scheduling a retry or recording a dead letter if
+// it fails.
+func (d *Dispatcher) attempt(pending *delivery) {
+	pending.attempts++
+	ctx, cancel := context.WithTimeout(d.ctx, deliveryTimeout)
+	status, err := d.send(ctx, pending)
+	cancel()
+	if err == nil {
+		webhookDeliveries.Inc("delivered")
+		return
+	}
+
+	if !retryable(status) || pending.attempts >= d.retry.MaxAttempts {
+		d.mu.Lock()
+		d.giveUp(pending, status, err.Error())
+		d.mu.Unlock()
+		return
+	}
+
+	webhookDeliveries.Inc("retried")
+	backoff := d.retry.Backoff(pending.attempts)
+	logging.Debug("Webhook delivery %s to %s failed (attempt %d), retrying in %s: %v",
+		pending.id, pending.webhook.URL, pending.attempts, backoff, err)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		d.giveUp(pending, status, err.Error())
+		return
+	}
+	d.retrying[pending] = time.AfterFunc(backoff, func() {
+		d.mu.Lock()
+		if d.retrying != nil {
+			delete(d.retrying, pending)
+		}
+		d.mu.Unlock()
+		d.enqueue(pending)
+	})
+}
+
+// giveUp records a delivery as a dead letter. The caller must hold d.mu.
+func (d *Dispatcher) giveUp(pending *delivery, status int, reason string) {
+	webhookDeliveries.Inc("dead_lettered")
+	logging.Warn("Giving up on webhook delivery of %s event to %s after %d attempt(s): %s",
+		pending.event.Type, pending.webhook.URL, pending.attempts, reason)
+
+	letter := DeadLetter{
+		DeliveryID: pending.id,
+		WebhookID:  pending.webhook.ID,
+		URL:        pending.webhook.URL,
+		Event:      pending.event,
+		Attempts:   pending.attempts,
+		StatusCode: status,
+		Error:      reason,
+		FailedAt:   time.Now(),
+	}
+	if d.deadLetters == nil {
+		return
+	}
+	if err := d.deadLetters.Add(letter); err != nil {
+		logging.Warn("Failed to record dead letter of webhook delivery %s: %v", pending.id, err)
+	}
+}
+
+// send makes one attempt at a delivery. It returns the response status code,
+// 0 if there was no response, and an error unless the code is 2xx.
+func (d *Dispatcher) send(ctx context.Context, pending *delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pending.webhook.URL, bytes.NewReader(pending.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cares-webhooks")
+	req.Header.Set(EventHeader, string(pending.event.Type))
+	req.Header.Set(DeliveryHeader, pending.id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(pending.webhook.Secret, timestamp, pending.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt may succeed when repeated: the
+// endpoint was unreachable, overloaded or failing, rather than rejecting the
+// delivery.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"cares/internal/events"
+)
+
+// testRetryPolicy retries quickly so tests do not wait for real backoffs.
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     20 * time.Millisecond,
+}
+
+// endpoint is a webhook receiver answering each attempt with the next of its
+// status codes, and the last one once they run out.
+type endpoint struct {
+	t        *testing.T
+	secret   string
+	statuses []int
+
+	mu       sync.Mutex
+	attempts int
+	verified int
+	delivery string // Delivery ID, which must be the same on every attempt
+	done     chan struct{}
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if Verify(e.secret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+		e.verified++
+	}
+	if e.delivery == "" {
+		e.delivery = r.Header.Get(DeliveryHeader)
+	} else if id := r.Header.Get(DeliveryHeader); id != e.delivery {
+		e.t.Errorf("delivery ID changed between attempts: %s, then %s", e.delivery, id)
+	}
+	status := e.statuses[len(e.statuses)-1]
+	if e.attempts < len(e.statuses) {
+		status = e.statuses[e.attempts]
+	}
+	e.attempts++
+	w.WriteHeader(status)
+	if status/100 == 2 {
+		close(e.done)
+	}
+}
+
+// startDispatcher starts a dispatcher delivering the events of a new bus to a
+// subscription at endpoint, and returns the bus and its dead letters.
+func startDispatcher(t *testing.T, e *endpoint) (*events.Bus, *DeadLetters) {
+	t.Helper()
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	store, err := OpenStore(filepath.Join(dir, "webhooks.json"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if _, err := store.Add(Subscription{URL: server.URL, Types: []events.Type{"node"}, Secret: e.secret}); err != nil {
+		t.Fatalf("add subscription: %v", err)
+	}
+	deadLetters, err := OpenDeadLetters(filepath.Join(dir, "dead_letters.jsonl"), 0)
+	if err != nil {
+		t.Fatalf("open dead letters: %v", err)
+	}
+	t.Cleanup(func() { deadLetters.Close() })
+
+	bus := events.NewBus()
+	dispatcher := NewDispatcher(store, deadLetters, testRetryPolicy)
+	dispatcher.Start(bus)
+	t.Cleanup(dispatcher.Stop)
+	return bus, deadLetters
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"node.joined"}`)
+	signature := Sign("0123456789abcdef", 1700000000, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		want      bool
+	}{
+		{"valid", "0123456789abcdef", "1700000000", body, true},
+		{"wrong secret", "fedcba9876543210", "1700000000", body, false},
+		{"other timestamp", "0123456789abcdef", "1700000001", body, false},
+		{"tampered body", "0123456789abcdef", "1700000000", []byte(`{"type":"node.removed"}`), false},
+		{"invalid timestamp", "0123456789abcdef", "soon", body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.body, signature); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliveryRetriesServerErrors(t *testing.T) {
+	e := &endpoint{t: t, secret: "0123456789abcdef", statuses: []int{503, 500, 204}, done: make(chan struct{})}
+	bus, deadLetters := startDispatcher(t, e)
+
+	bus.Publish(context.Background(), events.Event{Type: events.NodeJoined, Subject: "worker-1"})
+	bus.Publish(context.Background(), events.Event{Type: events.FunctionRegistered, Subject: "fn-1"}) // Not subscribed to
+
+	select {
+	case <-e.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", e.attempts)
+	}
+	if e.verified != e.attempts {
+		t.Errorf("%d of %d attempts had a valid signature", e.verified, e.attempts)
+	}
+	if letters, _ := deadLetters.Query("", 0, 0); len(letters) != 0 {
+		t.Errorf("dead letters = %v, want none", letters)
+	}
+}
+
+func TestDeliveryBecomesDeadLetter(t *testing.T) {
+	e := &endpoint{t: t, secret: "0123456789abcdef", statuses: []int{500}, done: make(chan struct{})}
+	bus, deadLetters := startDispatcher(t, e)
+
+	bus.Publish(context.Background(), events.Event{Type: events.NodeDisconnected, Subject: "worker-1"})
+
+	var letters []DeadLetter
+	for deadline := time.Now().Add(5 * time.Second); len(letters) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("no dead letter recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+		letters, _ = deadLetters.Query("", 0, 0)
+	}
+
+	letter := letters[0]
+	if letter.Attempts != testRetryPolicy.MaxAttempts || letter.StatusCode != 500 {
+		t.Errorf("dead letter after %d attempt(s) with status %d, want %d with 500",
+			letter.Attempts, letter.StatusCode, testRetryPolicy.MaxAttempts)
+	}
+	if letter.Event.Type != events.NodeDisconnected || letter.Event.Subject != "worker-1" {
+		t.Errorf("dead letter event = %s %s, want node.disconnected worker-1", letter.Event.Type, letter.Event.Subject)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.attempts != testRetryPolicy.MaxAttempts {
+		t.Errorf("endpoint saw %d attempts, want %d", e.attempts, testRetryPolicy.MaxAttempts)
+	}
+}
@@ -0,0 +1,249 @@
+// Package webhooks notifies external systems, such as chat or incident
+// tooling, of cluster events. A subscription names a URL and the event types
+// it wants; every matching event is POSTed to it as JSON, signed with the
+// subscription's secret (see Sign). Failed deliveries are retried with
+// exponential backoff, and those that never succeed are kept as dead letters.
+//
+// To be alerted when a function breaks, subscribe to "function.failing" and
+// "function.recovered", which are published once per outage, rather than to
+// "invocation.failed", which is published for every failed invocation.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"cares/internal/events"
+	"cares/internal/fsutil"
+)
+
+// DefaultStorePath is the default location of the subscription store.
+const DefaultStorePath = "data/webhooks.json"
+
+var (
+	// ErrNotFound is returned when no subscription has the requested ID.
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalid is returned when adding a subscription that fails validation.
+	ErrInvalid = errors.New("invalid webhook")
+)
+
+// Limits of a subscription
+const (
+	maxDescriptionLength = 200
+	minSecretLength      = 16
+	generatedSecretBytes = 32
+)
+
+// Subscription asks for the events of the given types to be delivered to URL.
+// Secret is never serialized to JSON by the API; the store keeps it in a file
+// only the orchestrator's user can read.
+type Subscription struct {
+	ID          string        `json:"id"`
+	URL         string        `json:"url"`
+	Types       []events.Type `json:"types"` // Event types or categories, e.g. "node.disconnected" or "invocation"
+	Description string        `json:"description,omitempty"`
+	Secret      string        `json:"-"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// storedSubscription is the on-disk form of a Subscription.
+type storedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+// Validate checks that the subscription is complete and well formed.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL")
+	}
+	if len(s.Types) == 0 {
+		return fmt.Errorf("at least one event type is required")
+	}
+	for _, t := range s.Types {
+		if !events.Known(t) {
+			return fmt.Errorf("unknown event type '%s'", t)
+		}
+	}
+	if len(s.Description) > maxDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", maxDescriptionLength)
+	}
+	if len(s.Secret) < minSecretLength {
+		return fmt.Errorf("secret must be at least %d characters", minSecretLength)
+	}
+	return nil
+}
+
+// Wants reports whether the subscription asks for an event.
+func (s Subscription) Wants(event events.Event) bool {
+	return events.Filter{Types: s.Types}.Matches(event)
+}
+
+// Store is a thread-safe, file-backed set of subscriptions.
+type Store struct {
+	mu            sync.RWMutex
+	path          string
+	subscriptions map[string]*Subscription
+}
+
+// OpenDefaultStore opens the store at DefaultStorePath.
+func OpenDefaultStore() (*Store, error) {
+	return OpenStore(DefaultStorePath)
+}
+
+// OpenStore opens or creates a subscription store at path.
+func OpenStore(path string) (*Store, error) {
+	store := &Store{
+		path:          path,
+		subscriptions: make(map[string]*Subscription),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook store: %w", err)
+	}
+
+	var stored []storedSubscription
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse webhook store: %w", err)
+	}
+	for _, entry := range stored {
+		subscription := entry.Subscription
+		subscription.Secret = entry.Secret
+		store.subscriptions[subscription.ID] = &subscription
+	}
+
+	return store, nil
+}
+
+// Add stores a new subscription. If it has no secret, a random one is
+// generated; the returned subscription carries it so it can be shown once.
+func (s *Store) Add(subscription Subscription) (*Subscription, error) {
+	if subscription.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, err
+		}
+		subscription.Secret = secret
+	}
+	if err := subscription.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	subscription.ID = uuid.New().String()
+	subscription.Types = append([]events.Type{}, subscription.Types...)
+	subscription.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptions[subscription.ID] = &subscription
+	if err := s.saveLocked(); err != nil {
+		delete(s.subscriptions, subscription.ID)
+		return nil, err
+	}
+
+	stored := subscription
+	return &stored, nil
+}
+
+// Get returns the subscription with the given ID, including its secret.
+func (s *Store) Get(id string) (*Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscription, exists := s.subscriptions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	stored := *subscription
+	return &stored, nil
+}
+
+// List returns every subscription oldest first, with secrets removed.
+func (s *Store) List() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Subscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		redacted := *subscription
+		redacted.Secret = ""
+		list = append(list, redacted)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Matching returns the subscriptions that want an event, including secrets.
+func (s *Store) Matching(event events.Event) []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Subscription
+	for _, subscription := range s.subscriptions {
+		if subscription.Wants(event) {
+			matches = append(matches, *subscription)
+		}
+	}
+	return matches
+}
+
+// Remove deletes a subscription.
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, exists := s.subscriptions[id]
+	if !exists {
+		return ErrNotFound
+	}
+
+	delete(s.subscriptions, id)
+	if err := s.saveLocked(); err != nil {
+		s.subscriptions[id] = subscription
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes the store. The caller must hold s.mu.
+func (s *Store) saveLocked() error {
+	stored := make([]storedSubscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		stored = append(stored, storedSubscription{Subscription: *subscription, Secret: subscription.Secret})
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].CreatedAt.Before(stored[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook store: %w", err)
+	}
+	// Secrets are stored in the clear, so only the owner may read the file
+	if err := fsutil.WriteFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write webhook store: %w", err)
+	}
+	return nil
+}
+
+// generateSecret returns a random hex-encoded signing secret.
+func generateSecret() (string, error) {
+	secret := make([]byte, generatedSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}